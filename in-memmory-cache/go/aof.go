@@ -0,0 +1,479 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// Интервал сброса буфера журнала на диск. При сбое теряются изменения не более чем за этот интервал
+	logSyncInterval = time.Second
+
+	// Журнал сжимается, когда вырастает вдвое относительно размера после последнего сжатия,
+	// но не раньше, чем достигнет минимального размера
+	logCompactMinSize = 1 << 20
+	logCompactGrowth  = 2
+
+	// Наибольший размер записи журнала. Длина записи читается из файла, поэтому запись с большей длиной
+	// считается оборванной, а не выделяет память по поврежденному заголовку
+	logMaxRecordSize = 64 << 20
+)
+
+// Тип операции журнала
+type logOp uint8
+
+const (
+	logSet logOp = iota + 1
+	logDelete
+	logClear
+
+	// Новое время жизни и время истечения значения (`Touch`, `Expire`, `SetDefaultTTL`)
+	logExpire
+
+	// Новое время жизни значений по умолчанию (`SetDefaultTTL`)
+	logDefaultTTL
+)
+
+/*
+ * Запись журнала. Каждая запись кодируется отдельно и предваряется длиной и контрольной суммой,
+ * поэтому оборванная при сбое последняя запись обнаруживается и отбрасывается при воспроизведении
+ */
+type logRecord[K comparable, V any] struct {
+	Op       logOp
+	Key      K
+	Value    V
+	TTL      time.Duration
+	ExpireAt time.Time
+
+	// Значение записано с временем жизни по умолчанию (`SetDefaultTTL` изменяет его время жизни)
+	DefaultTTL bool
+}
+
+/*
+ * Журнал изменений кэш-хранилища (`WithPersistenceLog`). Записи добавляются под блокировкой сегмента,
+ * поэтому порядок записей по одному ключу совпадает с порядком изменений в памяти. Буфер журнала
+ * сбрасывается на диск раз в `logSyncInterval`
+ */
+type persistenceLog[K comparable, V any] struct {
+	path string
+
+	mutex  sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	size   int64
+
+	// Размер журнала после последнего сжатия
+	compactedSize int64
+
+	// Во время сжатия новые записи дополнительно накапливаются в буфере,
+	// который дописывается в сжатый журнал перед подменой файла
+	compacting bool
+	pending    bytes.Buffer
+
+	// Первая ошибка записи в журнал, возвращается из `SyncLog`
+	err error
+
+	closed bool
+}
+
+// Функция кодирования записи журнала с заголовком из длины и контрольной суммы
+func encodeLogRecord[K comparable, V any](record logRecord[K, V]) ([]byte, error) {
+	var payload bytes.Buffer
+
+	if err := gob.NewEncoder(&payload).Encode(record); err != nil {
+		return nil, fmt.Errorf("cache: encode log record: %w", err)
+	}
+
+	if payload.Len() > logMaxRecordSize {
+		return nil, fmt.Errorf("cache: log record of %d bytes exceeds %d bytes", payload.Len(), logMaxRecordSize)
+	}
+
+	frame := make([]byte, 8, 8+payload.Len())
+
+	binary.LittleEndian.PutUint32(frame[0:4], uint32(payload.Len()))
+	binary.LittleEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload.Bytes()))
+
+	return append(frame, payload.Bytes()...), nil
+}
+
+/*
+ * Функция чтения очередной записи журнала. Возвращает `io.EOF` в конце журнала
+ * и `io.ErrUnexpectedEOF` для оборванной или поврежденной записи, в том числе с длиной больше `logMaxRecordSize`
+ */
+func decodeLogRecord[K comparable, V any](r io.Reader) (logRecord[K, V], int64, error) {
+	var (
+		record logRecord[K, V]
+		header [8]byte
+	)
+
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return record, 0, err
+	}
+
+	size := binary.LittleEndian.Uint32(header[0:4])
+
+	if size > logMaxRecordSize {
+		return record, 0, io.ErrUnexpectedEOF
+	}
+
+	payload := make([]byte, size)
+
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return record, 0, io.ErrUnexpectedEOF
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+		return record, 0, io.ErrUnexpectedEOF
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&record); err != nil {
+		return record, 0, fmt.Errorf("cache: decode log record: %w", err)
+	}
+
+	return record, int64(len(header) + len(payload)), nil
+}
+
+// Функция добавления записи в журнал. Вызывается под блокировкой сегмента
+func (log *persistenceLog[K, V]) append(record logRecord[K, V]) {
+	frame, err := encodeLogRecord(record)
+
+	log.mutex.Lock()
+
+	defer log.mutex.Unlock()
+
+	if err == nil {
+		_, err = log.writer.Write(frame)
+	}
+
+	if err != nil {
+		if log.err == nil {
+			log.err = err
+		}
+
+		return
+	}
+
+	log.size += int64(len(frame))
+
+	if log.compacting {
+		log.pending.Write(frame)
+	}
+}
+
+// Функция сброса буфера журнала на диск
+func (log *persistenceLog[K, V]) sync() error {
+	log.mutex.Lock()
+
+	defer log.mutex.Unlock()
+
+	if err := log.writer.Flush(); err != nil && log.err == nil {
+		log.err = err
+	}
+
+	if err := log.file.Sync(); err != nil && log.err == nil {
+		log.err = err
+	}
+
+	return log.err
+}
+
+/*
+ * Функция воспроизведения журнала при создании кэша. Оборванная при сбое последняя запись отбрасывается,
+ * а файл усекается до последней целой записи, после чего журнал открывается на дозапись
+ */
+func (cache *Cache[K, V]) openLog(path string) (*persistenceLog[K, V], error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+
+	if err != nil {
+		return nil, fmt.Errorf("cache: open log: %w", err)
+	}
+
+	reader := bufio.NewReader(file)
+
+	var size int64
+
+	for {
+		record, n, err := decodeLogRecord[K, V](reader)
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+
+		if err != nil {
+			file.Close()
+
+			return nil, err
+		}
+
+		size += n
+
+		cache.replay(record)
+	}
+
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+
+		return nil, fmt.Errorf("cache: truncate log: %w", err)
+	}
+
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+
+		return nil, fmt.Errorf("cache: seek log: %w", err)
+	}
+
+	return &persistenceLog[K, V]{
+		path:          path,
+		file:          file,
+		writer:        bufio.NewWriter(file),
+		size:          size,
+		compactedSize: size,
+	}, nil
+}
+
+// Функция применения записи журнала к кэшу. Вызывается до подключения журнала к сегментам
+func (cache *Cache[K, V]) replay(record logRecord[K, V]) {
+	switch record.Op {
+	case logSet:
+		var evicted []evictedItem[K, V]
+
+		shard := cache.shardFor(record.Key)
+
+		shard.locked(func() {
+			evicted = shard.setUntil(record.Key, record.Value, record.TTL, record.ExpireAt, record.DefaultTTL)
+		})
+
+		cache.notifyEvicted(evicted)
+	case logDelete:
+		shard := cache.shardFor(record.Key)
+
+		shard.locked(func() {
+			shard.remove(record.Key, EvictedDeleted)
+		})
+	case logClear:
+		for _, shard := range cache.shards {
+			shard.mutex.Lock()
+			shard.reset()
+			shard.mutex.Unlock()
+		}
+	case logExpire:
+		shard := cache.shardFor(record.Key)
+
+		shard.locked(func() {
+			if item, ok := shard.data[record.Key]; ok {
+				item.ttl = record.TTL
+
+				shard.expire(record.Key, item, record.ExpireAt)
+			}
+		})
+	case logDefaultTTL:
+		cache.ttl.Store(int64(record.TTL))
+	}
+}
+
+/*
+ * Функция сжатия журнала: журнал заменяется записями актуальных значений кэша. Новые записи во время
+ * сжатия продолжают дописываться в прежний журнал и накапливаются в буфере, который дописывается в сжатый
+ * журнал перед атомарной подменой файла. Без `WithPersistenceLog` ничего не делает
+ */
+func (cache *Cache[K, V]) CompactLog() error {
+	log := cache.journal
+
+	if log == nil {
+		return nil
+	}
+
+	// Сжатия выполняются по очереди и не пересекаются с закрытием кэша, иначе
+	// сжатие после удаления значений при закрытии оставило бы пустой журнал
+	cache.compacting.Lock()
+
+	defer cache.compacting.Unlock()
+
+	log.mutex.Lock()
+	closed := log.closed
+	log.mutex.Unlock()
+
+	if closed {
+		return ErrClosed
+	}
+
+	dir, name := filepath.Split(log.path)
+
+	file, err := os.CreateTemp(dir, name+".tmp-*")
+
+	if err != nil {
+		return fmt.Errorf("cache: create log: %w", err)
+	}
+
+	// При любой ошибке удаляем временный файл, после переименования удаление ничего не делает
+	defer os.Remove(file.Name())
+
+	log.mutex.Lock()
+	log.compacting = true
+	log.pending.Reset()
+	log.mutex.Unlock()
+
+	size, err := cache.writeCompactedLog(file)
+
+	log.mutex.Lock()
+
+	defer log.mutex.Unlock()
+
+	log.compacting = false
+
+	if err == nil {
+		var n int
+
+		n, err = file.Write(log.pending.Bytes())
+		size += int64(n)
+	}
+
+	log.pending.Reset()
+
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if err != nil {
+		file.Close()
+
+		return fmt.Errorf("cache: write log: %w", err)
+	}
+
+	// Сбрасываем буфер прежнего журнала: его записи уже вошли в сжатый журнал,
+	// но при ошибке подмены прежний журнал должен остаться полным
+	if err := log.writer.Flush(); err != nil {
+		file.Close()
+
+		return fmt.Errorf("cache: write log: %w", err)
+	}
+
+	if err := os.Rename(file.Name(), log.path); err != nil {
+		file.Close()
+
+		return fmt.Errorf("cache: rename log: %w", err)
+	}
+
+	log.file.Close()
+
+	log.file = file
+	log.writer = bufio.NewWriter(file)
+	log.size = size
+	log.compactedSize = size
+
+	return nil
+}
+
+// Функция записи актуальных значений кэша в сжатый журнал
+func (cache *Cache[K, V]) writeCompactedLog(w io.Writer) (int64, error) {
+	writer := bufio.NewWriter(w)
+	now := cache.clock.Now()
+
+	var size int64
+
+	// Время жизни по умолчанию, измененное `SetDefaultTTL`, предшествует значениям, записанным после изменения
+	if ttl := cache.DefaultTTL(); ttl != cache.configuredTTL {
+		frame, err := encodeLogRecord(logRecord[K, V]{Op: logDefaultTTL, TTL: ttl})
+
+		if err != nil {
+			return size, err
+		}
+
+		if _, err := writer.Write(frame); err != nil {
+			return size, err
+		}
+
+		size += int64(len(frame))
+	}
+
+	for _, shard := range cache.shards {
+		var records []logRecord[K, V]
+
+		shard.mutex.RLock()
+
+		for key, item := range shard.data {
+			if !now.After(item.expireAt) {
+				records = append(records, logRecord[K, V]{Op: logSet, Key: key, Value: item.value, TTL: item.ttl, ExpireAt: item.deadline, DefaultTTL: item.defaultTTL})
+			}
+		}
+
+		shard.mutex.RUnlock()
+
+		for _, record := range records {
+			frame, err := encodeLogRecord(record)
+
+			if err != nil {
+				return size, err
+			}
+
+			if _, err := writer.Write(frame); err != nil {
+				return size, err
+			}
+
+			size += int64(len(frame))
+		}
+	}
+
+	return size, writer.Flush()
+}
+
+/*
+ * Функция немедленного сброса журнала на диск. Возвращает первую ошибку записи в журнал
+ * с момента создания кэша. Без `WithPersistenceLog` ничего не делает
+ */
+func (cache *Cache[K, V]) SyncLog() error {
+	if cache.journal == nil {
+		return nil
+	}
+
+	return cache.journal.sync()
+}
+
+// Функция периодического сброса журнала на диск и его сжатия при росте
+func (cache *Cache[K, V]) logger(ticker Ticker) {
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			_ = cache.journal.sync()
+
+			cache.journal.mutex.Lock()
+			grown := cache.journal.size >= logCompactMinSize && cache.journal.size >= logCompactGrowth*cache.journal.compactedSize
+			cache.journal.mutex.Unlock()
+
+			if grown {
+				_ = cache.CompactLog()
+			}
+		case <-cache.stop:
+			return
+		}
+	}
+}
+
+// Функция закрытия журнала со сбросом буфера на диск
+func (log *persistenceLog[K, V]) close() error {
+	err := log.sync()
+
+	log.mutex.Lock()
+
+	defer log.mutex.Unlock()
+
+	log.closed = true
+
+	if closeErr := log.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
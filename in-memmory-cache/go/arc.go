@@ -0,0 +1,170 @@
+package cache
+
+import "container/list"
+
+// Списки политики ARC
+const (
+	// Значения, к которым обращались один раз с момента записи
+	arcRecent = iota
+
+	// Значения, к которым обращались повторно
+	arcFrequent
+
+	// Ключи, недавно вытесненные из `arcRecent` и `arcFrequent` (призраки без значений)
+	arcRecentGhost
+	arcFrequentGhost
+)
+
+// Положение ключа в списках политики ARC
+type arcEntry struct {
+	element *list.Element
+	segment int
+}
+
+/*
+ * Политика адаптивного замещения (ARC, Adaptive Replacement Cache). Значения делятся на список недавно
+ * записанных (T1) и список повторно использованных (T2), а для каждого хранится список недавно вытесненных
+ * ключей без значений (B1, B2). Повторная запись вытесненного ключа показывает, какой список был слишком
+ * мал, и смещает целевой размер T1 в его пользу, поэтому политика сама подстраивается между LRU
+ * и LFU: сканирование вытесняет только T1, а часто используемые значения остаются в T2.
+ *
+ * Политика не знает ограничения емкости сегмента, поэтому емкостью считается наибольшее количество
+ * значений, которое она отслеживала. В заполненном сегменте это и есть его ограничение
+ */
+type arcPolicy[K comparable] struct {
+	lists   [4]*list.List
+	entries map[K]*arcEntry
+
+	// Целевой размер списка T1 и емкость политики
+	target   int
+	capacity int
+
+	// Кандидат, выбранный последним вызовом `Victim`. Его удаление означает вытеснение,
+	// и ключ переходит в список призраков, а остальные удаления забывают ключ
+	victim  K
+	pending bool
+}
+
+func newARCPolicy[K comparable]() *arcPolicy[K] {
+	policy := &arcPolicy[K]{entries: make(map[K]*arcEntry)}
+
+	for i := range policy.lists {
+		policy.lists[i] = list.New()
+	}
+
+	return policy
+}
+
+func (policy *arcPolicy[K]) OnGet(key K) {
+	policy.pending = false
+
+	if entry, ok := policy.entries[key]; ok && entry.segment <= arcFrequent {
+		policy.move(key, entry, arcFrequent)
+	}
+}
+
+func (policy *arcPolicy[K]) OnSet(key K) {
+	policy.pending = false
+
+	entry, ok := policy.entries[key]
+
+	if !ok {
+		policy.entries[key] = &arcEntry{element: policy.lists[arcRecent].PushFront(key), segment: arcRecent}
+		policy.resize()
+
+		return
+	}
+
+	recent, frequent := policy.lists[arcRecentGhost].Len(), policy.lists[arcFrequentGhost].Len()
+
+	// Запись недавно вытесненного ключа увеличивает целевой размер списка, из которого он был вытеснен
+	switch entry.segment {
+	case arcRecentGhost:
+		policy.target = min(policy.target+max(frequent/recent, 1), policy.capacity)
+	case arcFrequentGhost:
+		policy.target = max(policy.target-max(recent/frequent, 1), 0)
+	}
+
+	policy.move(key, entry, arcFrequent)
+	policy.resize()
+}
+
+func (policy *arcPolicy[K]) OnDelete(key K) {
+	entry, ok := policy.entries[key]
+
+	if !ok {
+		return
+	}
+
+	evicted := policy.pending && policy.victim == key
+
+	policy.pending = false
+
+	if evicted && entry.segment <= arcFrequent {
+		policy.move(key, entry, entry.segment+arcRecentGhost)
+		policy.resize()
+
+		return
+	}
+
+	policy.lists[entry.segment].Remove(entry.element)
+	delete(policy.entries, key)
+}
+
+// Кандидат запоминается, поэтому его последующее удаление переводит ключ в список призраков
+func (policy *arcPolicy[K]) Victim() (K, bool) {
+	victim, ok := policy.peekVictim()
+
+	if ok {
+		policy.victim, policy.pending = victim, true
+	}
+
+	return victim, ok
+}
+
+// Кандидат выбирается из T1, пока он больше целевого размера, иначе из T2
+func (policy *arcPolicy[K]) peekVictim() (K, bool) {
+	recent, frequent := policy.lists[arcRecent], policy.lists[arcFrequent]
+
+	element := recent.Back()
+
+	if element == nil || (recent.Len() <= policy.target && frequent.Len() > 0) {
+		element = frequent.Back()
+	}
+
+	if element == nil {
+		var zero K
+
+		return zero, false
+	}
+
+	return element.Value.(K), true
+}
+
+// Функция переноса ключа в начало списка `segment`
+func (policy *arcPolicy[K]) move(key K, entry *arcEntry, segment int) {
+	policy.lists[entry.segment].Remove(entry.element)
+
+	entry.element = policy.lists[segment].PushFront(key)
+	entry.segment = segment
+}
+
+/*
+ * Функция учета емкости политики и ограничения списков призраков: T1 вместе с B1 и все призраки
+ * вместе не превышают емкости, поэтому политика хранит не больше удвоенной емкости ключей
+ */
+func (policy *arcPolicy[K]) resize() {
+	policy.capacity = max(policy.capacity, policy.lists[arcRecent].Len()+policy.lists[arcFrequent].Len())
+
+	for policy.lists[arcRecentGhost].Len()+policy.lists[arcFrequentGhost].Len() > policy.capacity {
+		segment := arcFrequentGhost
+
+		if policy.lists[arcRecentGhost].Len() > 0 &&
+			(policy.lists[arcRecent].Len()+policy.lists[arcRecentGhost].Len() > policy.capacity || policy.lists[arcFrequentGhost].Len() == 0) {
+			segment = arcRecentGhost
+		}
+
+		key := policy.lists[segment].Remove(policy.lists[segment].Back()).(K)
+		delete(policy.entries, key)
+	}
+}
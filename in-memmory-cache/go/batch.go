@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"context"
+	"maps"
+	"slices"
+)
+
+// Функция группировки ключей по сегментам, в которых они хранятся
+func (cache *Cache[K, V]) groupByShard(keys []K) map[*shard[K, V]][]K {
+	groups := make(map[*shard[K, V]][]K)
+
+	for _, key := range keys {
+		shard := cache.shardFor(key)
+		groups[shard] = append(groups[shard], key)
+	}
+
+	return groups
+}
+
+/*
+ * Функция получения нескольких значений за одну блокировку каждого затронутого сегмента. Возвращает
+ * только актуальные значения, отсутствующие и просроченные ключи в результат не попадают. Чтение
+ * продлевает время жизни и учитывается политикой вытеснения так же, как `Get`, но загрузчик
+ * (`WithLoader`) для промахов не вызывается
+ */
+func (cache *Cache[K, V]) GetMany(keys []K) map[K]V {
+	values := make(map[K]V, len(keys))
+
+	for shard, keys := range cache.groupByShard(keys) {
+		shard.getMany(keys, values)
+	}
+
+	for _, key := range keys {
+		value, ok := values[key]
+
+		cache.recordRead(key, ok)
+
+		if ok {
+			values[key] = cache.copyOut(value)
+		}
+	}
+
+	return values
+}
+
+/*
+ * Функция чтения значений сегмента по ключам `keys` в `values` за одну блокировку. Блокировка на запись
+ * берется, только если чтение изменяет состояние сегмента (`mutatesOnGet`)
+ */
+func (shard *shard[K, V]) getMany(keys []K, values map[K]V) {
+	mutates := shard.mutatesOnGet()
+
+	if mutates {
+		shard.mutex.Lock()
+
+		defer shard.mutex.Unlock()
+	} else {
+		shard.mutex.RLock()
+
+		defer shard.mutex.RUnlock()
+	}
+
+	for _, key := range keys {
+		var (
+			value V
+			ok    bool
+		)
+
+		if mutates {
+			value, _, ok = shard.getLocked(key)
+		} else {
+			value, _, ok = shard.readLocked(key)
+		}
+
+		if ok {
+			values[key] = value
+		}
+	}
+}
+
+/*
+ * Функция записи нескольких значений за одну блокировку каждого затронутого сегмента. Время жизни
+ * значений равно TTL кэша. При заданном хранилище (`WithStore`) каждое значение сначала сохраняется
+ * в хранилище, и значения, которые не удалось сохранить, в кэш не записываются
+ */
+func (cache *Cache[K, V]) SetMany(items map[K]V) {
+	cache.awaitWrites()
+
+	// Сохранение и запись в кэш выполняются под блокировками записи ключей, как и в `writeThrough`
+	unlock := cache.lockWrites(slices.Collect(maps.Keys(items)))
+
+	defer unlock()
+
+	keys := make([]K, 0, len(items))
+	values := make(map[K]V, len(items))
+
+	for key, value := range items {
+		if cache.store != nil && cache.save(context.Background(), key, value) != nil {
+			continue
+		}
+
+		keys = append(keys, key)
+		values[key] = cache.copyIn(value)
+	}
+
+	var evicted []evictedItem[K, V]
+
+	for shard, keys := range cache.groupByShard(keys) {
+		shard.locked(func() {
+			// Закрытый кэш больше не принимает новые значения
+			if shard.closed {
+				return
+			}
+
+			for _, key := range keys {
+				evicted = append(evicted, shard.set(key, values[key], useDefaultTTL)...)
+			}
+		})
+	}
+
+	cache.notifyEvicted(evicted)
+
+	for _, key := range keys {
+		cache.broadcast(key)
+	}
+}
+
+/*
+ * Функция удаления нескольких значений за одну блокировку каждого затронутого сегмента. При заданном
+ * хранилище (`WithStore`) значения удаляются и из него. Возвращает количество значений, присутствовавших в кэше
+ */
+func (cache *Cache[K, V]) DeleteMany(keys []K) int {
+	cache.awaitWrites()
+
+	unlock := cache.lockWrites(keys)
+
+	defer unlock()
+
+	var evicted []evictedItem[K, V]
+
+	for shard, keys := range cache.groupByShard(keys) {
+		shard.locked(func() {
+			for _, key := range keys {
+				if item, ok := shard.data[key]; ok {
+					shard.remove(key, EvictedDeleted)
+
+					evicted = append(evicted, evictedItem[K, V]{key: key, value: item.value, reason: EvictedDeleted})
+				}
+			}
+		})
+	}
+
+	cache.notifyEvicted(evicted)
+
+	for _, key := range keys {
+		cache.broadcast(key)
+	}
+
+	if cache.store != nil {
+		for _, key := range keys {
+			_ = cache.drop(context.Background(), key)
+		}
+	}
+
+	return len(evicted)
+}
@@ -0,0 +1,246 @@
+package cache_test
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachetest"
+)
+
+/*
+ * Тесты производительности кэша профилей. Каждая операция измеряется для количеств значений из флага
+ * `-sizes`, а варианты с опциями кэша оформлены вложенными тестами, поэтому результаты разных версий
+ * сравниваются через benchstat:
+ *
+ *	go test -run '^$' -bench 'Set|GC' -count 10 -args -sizes 1000,100000 > new.txt
+ */
+
+// Количества значений в кэше через запятую
+var sizesFlag = flag.String("sizes", "1000,10000,100000,1000000", "comma-separated cache sizes")
+
+// Количество различных профилей, которыми заполняется кэш. Профили разделяются между ключами,
+// чтобы заполнение большого кэша не измеряло выделение памяти под сами профили
+const profilePool = 1024
+
+// Доля записей в смешанной нагрузке: одна запись на `mixedWriteEvery` операций
+const mixedWriteEvery = 10
+
+// Размер буфера записи для тестов с `WithBufferedWrites`
+const writeBuffer = 4096
+
+// Шаг грубых часов для тестов с `WithCoarseClock`
+const coarseResolution = time.Millisecond
+
+/*
+ * Чтение значения: последовательно и параллельно из `GOMAXPROCS` потоков с одним сегментом (`Parallel`),
+ * с сегментами по числу потоков (`Sharded`), с чтением без блокировки (`ReadOptimized`) и с грубыми
+ * часами (`CoarseClock`)
+ */
+func BenchmarkGet(b *testing.B) {
+	forSizes(b, func(b *testing.B, size int) {
+		b.Run("Serial", func(b *testing.B) { benchmarkSerial(b, size, 0) })
+		b.Run("Parallel", func(b *testing.B) { benchmarkParallel(b, size, 0) })
+		b.Run("Sharded", func(b *testing.B) { benchmarkParallel(b, size, 0, cache.WithShards(shards())) })
+		b.Run("ReadOptimized", func(b *testing.B) { benchmarkParallel(b, size, 0, cache.WithReadOptimized()) })
+		b.Run("CoarseClock", func(b *testing.B) { benchmarkParallel(b, size, 0, cache.WithCoarseClock(coarseResolution)) })
+	})
+}
+
+// Запись значения с теми же вариантами, что и чтение, и с буфером записи (`Buffered`) вместо чтения без блокировки
+func BenchmarkSet(b *testing.B) {
+	forSizes(b, func(b *testing.B, size int) {
+		b.Run("Serial", func(b *testing.B) { benchmarkSerial(b, size, 1) })
+		b.Run("Parallel", func(b *testing.B) { benchmarkParallel(b, size, 1) })
+		b.Run("Sharded", func(b *testing.B) { benchmarkParallel(b, size, 1, cache.WithShards(shards())) })
+		b.Run("Buffered", func(b *testing.B) { benchmarkParallel(b, size, 1, cache.WithBufferedWrites(writeBuffer)) })
+		b.Run("CoarseClock", func(b *testing.B) { benchmarkParallel(b, size, 1, cache.WithCoarseClock(coarseResolution)) })
+	})
+}
+
+// Смешанная нагрузка с 90% чтений с теми же вариантами, что и чтение
+func BenchmarkMixed(b *testing.B) {
+	forSizes(b, func(b *testing.B, size int) {
+		b.Run("Serial", func(b *testing.B) { benchmarkSerial(b, size, mixedWriteEvery) })
+		b.Run("Parallel", func(b *testing.B) { benchmarkParallel(b, size, mixedWriteEvery) })
+		b.Run("Sharded", func(b *testing.B) {
+			benchmarkParallel(b, size, mixedWriteEvery, cache.WithShards(shards()))
+		})
+		b.Run("ReadOptimized", func(b *testing.B) {
+			benchmarkParallel(b, size, mixedWriteEvery, cache.WithShards(shards()), cache.WithReadOptimized())
+		})
+		b.Run("CoarseClock", func(b *testing.B) {
+			benchmarkParallel(b, size, mixedWriteEvery, cache.WithCoarseClock(coarseResolution))
+		})
+	})
+}
+
+// Проход сборщика мусора по истекшим значениям: полный (`Full`) и с выборочной проверкой истечения (`Sampling`)
+func BenchmarkGC(b *testing.B) {
+	forSizes(b, func(b *testing.B, size int) {
+		b.Run("Full", func(b *testing.B) { benchmarkGC(b, size) })
+		b.Run("Sampling", func(b *testing.B) { benchmarkGC(b, size, cache.WithExpirationEngine(cache.Sampling)) })
+	})
+}
+
+// Функция запуска вложенных тестов `size=N` для каждого количества значений из флага `-sizes`
+func forSizes(b *testing.B, f func(b *testing.B, size int)) {
+	for _, part := range strings.Split(*sizesFlag, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(part))
+
+		if err != nil || size <= 0 {
+			b.Fatalf("invalid -sizes value %q", part)
+		}
+
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) { f(b, size) })
+	}
+}
+
+// Количество сегментов для параллельных тестов: степень двойки не меньше количества потоков
+func shards() int {
+	n := 1
+
+	for n < runtime.GOMAXPROCS(0) {
+		n *= 2
+	}
+
+	return n
+}
+
+// Функция создания ключей и профилей для заполнения кэша
+func fixtures(size int) ([]string, []*cache.Profile) {
+	keys := make([]string, size)
+
+	for i := range keys {
+		keys[i] = "profile-" + strconv.Itoa(i)
+	}
+
+	profiles := make([]*cache.Profile, min(size, profilePool))
+
+	for i := range profiles {
+		profiles[i] = &cache.Profile{UUID: keys[i], Name: "benchmark"}
+	}
+
+	return keys, profiles
+}
+
+// Функция создания кэша, заполненного `size` значениями
+func filled(b *testing.B, size int, opts ...cache.Option) (*cache.ProfileCache, []string, []*cache.Profile) {
+	b.Helper()
+
+	profiles, err := cache.New(append(opts, cache.WithTTL(time.Hour))...)
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Cleanup(profiles.Close)
+
+	keys, values := fixtures(size)
+
+	for i, key := range keys {
+		profiles.Cache.Set(key, values[i%len(values)])
+	}
+
+	return profiles, keys, values
+}
+
+// Индекс ключа для операции `i`. Шаг взаимно прост с размером кэша, поэтому обход затрагивает все ключи
+func index(i, size int) int {
+	return (i * 7919) % size
+}
+
+// Функция операции `i`: запись каждой `writeEvery`-й операцией, иначе чтение. При `writeEvery == 0` только чтение
+func operate(profiles *cache.ProfileCache, keys []string, values []*cache.Profile, i, writeEvery int) {
+	key := keys[index(i, len(keys))]
+
+	if writeEvery > 0 && i%writeEvery == 0 {
+		profiles.Cache.Set(key, values[i%len(values)])
+	} else {
+		profiles.Get(key)
+	}
+}
+
+// Функция последовательного теста из одного потока
+func benchmarkSerial(b *testing.B, size, writeEvery int) {
+	profiles, keys, values := filled(b, size)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := range b.N {
+		operate(profiles, keys, values, i, writeEvery)
+	}
+}
+
+// Функция параллельного теста из `GOMAXPROCS` потоков с кэшем, созданным с опциями `opts`
+func benchmarkParallel(b *testing.B, size, writeEvery int, opts ...cache.Option) {
+	profiles, keys, values := filled(b, size, opts...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		// Потоки начинают обход с разных ключей, чтобы не читать одни и те же значения одновременно
+		i := int(time.Now().UnixNano() % int64(size))
+
+		for pb.Next() {
+			i++
+
+			operate(profiles, keys, values, i, writeEvery)
+		}
+	})
+}
+
+/*
+ * Функция теста прохода сборщика мусора: каждая операция - удаление `size` истекших значений. Время
+ * управляется `cachetest.FakeClock`, поэтому проход запускается сдвигом часов, а не ожиданием интервала.
+ * Дополнительно сообщается длительность прохода по статистике кэша в пересчете на одно значение
+ */
+func benchmarkGC(b *testing.B, size int, opts ...cache.Option) {
+	clock := cachetest.NewFakeClock(time.Now())
+
+	profiles, err := cache.New(append(opts, cache.WithClock(clock), cache.WithTTL(time.Minute), cache.WithCleanupInterval(time.Hour))...)
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Cleanup(profiles.Close)
+
+	keys, values := fixtures(size)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		b.StopTimer()
+
+		for i, key := range keys {
+			profiles.Cache.Set(key, values[i%len(values)])
+		}
+
+		sweeps := profiles.Stats().Sweeps
+
+		b.StartTimer()
+
+		clock.Advance(time.Hour)
+
+		for profiles.Stats().Sweeps == sweeps {
+			runtime.Gosched()
+		}
+	}
+
+	b.StopTimer()
+
+	stats := profiles.Stats()
+
+	if stats.Sweeps > 0 {
+		b.ReportMetric(float64(stats.SweepDuration.Nanoseconds())/float64(stats.Sweeps)/float64(size), "ns/entry")
+	}
+}
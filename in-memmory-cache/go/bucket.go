@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/*
+ * Пространство имен внутри кэша со строковыми ключами. Ключи пространства хранятся в общем кэше
+ * с префиксом `name:`, поэтому пространства разделяют сборщик мусора, ограничения количества
+ * значений и памяти, но не пересекаются по ключам. Время жизни значений по умолчанию задается
+ * для каждого пространства отдельно (`WithTTL`)
+ */
+type Bucket[V any] struct {
+	cache  *Cache[string, V]
+	name   string
+	prefix string
+	// Собственное время жизни пространства, ноль - TTL кэша
+	ttl time.Duration
+}
+
+/*
+ * Функция получения пространства имен с префиксом ключей `name:`. Пространство наследует TTL кэша,
+ * в том числе измененный `SetDefaultTTL`.
+ * Пространства имен доступны только для кэша со строковыми ключами, для остальных типов ключей
+ * функция завершается паникой
+ */
+func (cache *Cache[K, V]) Namespace(name string) *Bucket[V] {
+	strCache, ok := any(cache).(*Cache[string, V])
+
+	if !ok {
+		panic(fmt.Sprintf("cache: namespaces require string keys, got %T", *new(K)))
+	}
+
+	return &Bucket[V]{cache: strCache, name: name, prefix: name + ":"}
+}
+
+/*
+ * Функция получения пространства имен с собственным временем жизни значений по умолчанию.
+ * Исходное пространство не изменяется. При неположительном `ttl` используется TTL кэша
+ */
+func (bucket *Bucket[V]) WithTTL(ttl time.Duration) *Bucket[V] {
+	copied := *bucket
+	copied.ttl = max(ttl, 0)
+
+	return &copied
+}
+
+// Функция получения имени пространства
+func (bucket *Bucket[V]) Name() string {
+	return bucket.name
+}
+
+// Функция получения значения пространства по ключу. Работает как `Get` кэша
+func (bucket *Bucket[V]) Get(key string) (V, bool) {
+	return bucket.cache.Get(bucket.prefix + key)
+}
+
+// Функция записи значения в пространство с временем жизни пространства
+func (bucket *Bucket[V]) Set(key string, value V) {
+	bucket.cache.SetWithTTL(bucket.prefix+key, value, bucket.defaultTTL())
+}
+
+// Функция получения времени жизни пространства. Без собственного TTL используется текущий TTL кэша
+func (bucket *Bucket[V]) defaultTTL() time.Duration {
+	if bucket.ttl > 0 {
+		return bucket.ttl
+	}
+
+	return bucket.cache.DefaultTTL()
+}
+
+// Функция записи значения в пространство с индивидуальным временем жизни
+func (bucket *Bucket[V]) SetWithTTL(key string, value V, ttl time.Duration) {
+	bucket.cache.SetWithTTL(bucket.prefix+key, value, ttl)
+}
+
+// Функция удаления значения пространства по ключу. Возвращает `true`, если значение присутствовало
+func (bucket *Bucket[V]) Delete(key string) bool {
+	return bucket.cache.Delete(bucket.prefix + key)
+}
+
+/*
+ * Функция получения ключей всех актуальных значений пространства без префикса.
+ * Порядок ключей не гарантируется
+ */
+func (bucket *Bucket[V]) Keys() []string {
+	var keys []string
+
+	for _, key := range bucket.cache.Keys() {
+		if rest, ok := strings.CutPrefix(key, bucket.prefix); ok {
+			keys = append(keys, rest)
+		}
+	}
+
+	return keys
+}
+
+// Функция получения количества актуальных значений пространства
+func (bucket *Bucket[V]) Len() int {
+	return len(bucket.Keys())
+}
+
+/*
+ * Функция удаления всех значений пространства. Значения остальных пространств и ключи без префикса
+ * не затрагиваются. Возвращает количество удаленных значений
+ */
+func (bucket *Bucket[V]) Clear() int {
+	keys := bucket.Keys()
+
+	for i, key := range keys {
+		keys[i] = bucket.prefix + key
+	}
+
+	return bucket.cache.DeleteMany(keys)
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+/*
+ * Сообщение об изменении значения на одном из экземпляров сервиса. Получив сообщение от другого
+ * экземпляра, кэш удаляет свою копию значения, и следующее чтение обращается к источнику данных
+ */
+type Invalidation struct {
+	// Идентификатор экземпляра кэша, изменившего значение. Кэш пропускает собственные сообщения
+	Source string
+
+	// Ключ измененного значения
+	Key string
+}
+
+/*
+ * Шина сообщений об изменении значений между экземплярами сервиса (`WithInvalidationBus`).
+ * Реализации поверх Redis pub/sub и NATS находятся в пакетах `cacheredis` и `cachenats`
+ */
+type InvalidationBus interface {
+	// Отправка сообщения всем подписчикам шины, включая отправителя
+	Publish(ctx context.Context, message Invalidation) error
+
+	// Подписка на сообщения шины. Функция `fn` вызывается для каждого сообщения,
+	// а возвращаемая функция отменяет подписку
+	Subscribe(fn func(Invalidation)) (unsubscribe func(), err error)
+}
+
+// Функция создания случайного идентификатора экземпляра кэша для сообщений шины
+func newInstanceID() string {
+	var id [16]byte
+
+	_, _ = rand.Read(id[:])
+
+	return hex.EncodeToString(id[:])
+}
+
+/*
+ * Функция отправки сообщения об изменении значения другим экземплярам. Вызывается после изменения
+ * кэша без удержания блокировки. Неотправленное сообщение учитывается в `Stats.DeadLetters`,
+ * а ошибка отправки записывается в журнал событий (`WithLogger`)
+ */
+func (cache *Cache[K, V]) broadcast(key K) {
+	if cache.bus == nil {
+		return
+	}
+
+	// Шина подключается только к кэшу со строковыми ключами, что проверяется в конструкторе
+	err := cache.bus.Publish(context.Background(), Invalidation{Source: cache.instanceID, Key: any(key).(string)})
+
+	if err == nil {
+		return
+	}
+
+	cache.stats.deadLetters.Add(1)
+
+	if cache.log != nil {
+		cache.log.Warn("cache: invalidation publish failed", "key", key, "error", err)
+	}
+}
+
+// Функция обработки сообщения шины: удаление локальной копии значения, измененного другим экземпляром
+func (cache *Cache[K, V]) receive(message Invalidation) {
+	if message.Source == cache.instanceID {
+		return
+	}
+
+	cache.invalidate(any(message.Key).(K))
+}
@@ -0,0 +1,1755 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"hash/maphash"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * Обобщенное кэш-хранилище с TTL. Ключом может выступать любой сравнимый тип `K`,
+ * значением - любой тип `V`. Кэш профилей пользователей (`ProfileCache`) построен поверх
+ * данной структуры, поэтому весь механизм TTL и сборки мусора общий для всех типов значений.
+ *
+ * Значения хранятся в одном или нескольких независимо блокируемых сегментах (`WithShards`)
+ */
+type Cache[K comparable, V any] struct {
+	// Время жизни значений по умолчанию, изменяемое во время работы (`SetDefaultTTL`), и заданное `WithTTL`
+	ttl           atomic.Int64
+	configuredTTL time.Duration
+
+	cleanupInterval time.Duration
+	clock           Clock
+
+	// Границы адаптивного интервала сборщика мусора (`WithAdaptiveCleanup`)
+	minCleanupInterval time.Duration
+	maxCleanupInterval time.Duration
+
+	// Количество незавершенных приостановок сборщика мусора (`PauseGC`) и блокировка, которую
+	// удерживает проход сборщика, чтобы `PauseGC` мог дождаться его завершения
+	gcPauses atomic.Int64
+	sweeping sync.Mutex
+
+	onEvicted func(K, V, EvictionReason)
+
+	// Загрузчик значения при промахе (`WithLoader`) и хранилище, в которое
+	// синхронно записываются изменения кэша (`WithStore`)
+	loader func(context.Context, K) (V, error)
+	store  Store[K, V]
+
+	// Очередь отложенной записи в хранилище (`WithWriteBehind`)
+	writeBehind *writeBehind[K, V]
+
+	// Буфер записи в кэш (`WithBufferedWrites`)
+	pipeline *writePipeline[K, V]
+
+	// Копирование значений на границе API (`WithCopyOnRead`, `WithCopyOnWrite`)
+	copyOnRead  bool
+	copyOnWrite bool
+	clone       func(V) V
+
+	// Файл периодических снимков кэша (`WithSnapshot`)
+	snapshotPath string
+
+	// Журнал изменений (`WithPersistenceLog`) и блокировка, упорядочивающая его сжатия
+	journal    *persistenceLog[K, V]
+	compacting sync.Mutex
+
+	// Рассылка изменений репликам, подключается при первом вызове `Replicate`
+	hub     atomic.Pointer[replicationHub[K, V]]
+	hubOnce sync.Once
+
+	// Рассылка событий изменения значений подписчикам `Watch`
+	events *eventHub[K, V]
+
+	// Окно устаревания (`WithStaleWhileRevalidate`) и ключи, обновляемые в фоне
+	grace      time.Duration
+	refreshing sync.Map
+
+	// Сегменты хранилища и зерно хеш-функции для распределения ключей по ним
+	shards []*shard[K, V]
+	seed   maphash.Seed
+
+	// Блокировки ключей для прикладного кода (`Lock`) и для записи в хранилище (`WithStore`). Наборы
+	// разделены, чтобы запись в кэш из-под `Lock` не ожидала саму себя
+	locks  [lockStripes]sync.Mutex
+	writes [lockStripes]sync.Mutex
+
+	// Журнал событий (`WithLogger`)
+	log *slog.Logger
+
+	// Шина сообщений об изменении значений (`WithInvalidationBus`), идентификатор
+	// данного экземпляра в сообщениях и функция отмены подписки на шину
+	bus         InvalidationBus
+	instanceID  string
+	unsubscribe func()
+
+	// Инструменты телеметрии (`WithTelemetry`)
+	telemetry *telemetry
+
+	// Максимальное количество заказов профиля (`WithMaxOrdersPerProfile`), применяется кэшем профилей
+	maxOrders int
+
+	// Учет статистики за период в сегментах (`WithStatsWindow`)
+	windowed bool
+
+	loads     singleflight[K, V]
+	stats     counters
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+type CacheItem[V any] struct {
+	value    V
+	ttl      time.Duration
+	size     int64
+	expireAt time.Time
+
+	// Время последнего чтения в наносекундах Unix и количество чтений (`WithAccessTracking`). Чтение без
+	// продления времени жизни выполняется под блокировкой на чтение, поэтому счетчики изменяются атомарно.
+	// Поля расположены рядом с `expireAt`, чтобы чтение затрагивало одну строку кэша процессора
+	accessed atomic.Int64
+	accesses atomic.Uint32
+
+	// Количество чтений значения с момента записи (`WithRefreshAhead`)
+	hits uint32
+
+	// Время истечения без учета простоя (`WithMaxIdle`). Без ограничения простоя совпадает с `expireAt`
+	deadline time.Time
+
+	// Версия записи значения для `CompareAndSwap`
+	version uint64
+
+	// Время записи ключа и последней перезаписи значения в наносекундах Unix (`Entry`)
+	created int64
+	updated int64
+
+	// Приоритет и стоимость значения при вытеснении (`SetWithOptions`)
+	cost     int64
+	priority Priority
+
+	// Значение закреплено (`Pin`) и не вытесняется при ограничении емкости
+	pinned bool
+
+	// Значение записано с временем жизни по умолчанию, и `SetDefaultTTL` изменяет его время жизни
+	defaultTTL bool
+
+	// Порядок полей подобран так, чтобы значение занимало 128 байт (класс размера аллокатора Go)
+}
+
+// Удаленная из хранилища пара ключ-значение, о которой необходимо
+// уведомить функцию обратного вызова после снятия блокировки
+type evictedItem[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictionReason
+}
+
+// Функция-конструктор для создания обобщенного кэш-хранилища. Параметры кэша задаются функциональными
+// опциями, при некорректных значениях возвращается ошибка. Параллельно с созданием кэша запускаем
+// сборщик мусора, который с заданным интервалом очищает хранилище от протухших значений.
+func NewCache[K comparable, V any](opts ...Option) (*Cache[K, V], error) {
+	o, err := newOptions(opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if o.minCleanupInterval > 0 && o.withoutBackgroundGC {
+		return nil, fmt.Errorf("cache: adaptive cleanup requires background gc")
+	}
+
+	if o.minCleanupInterval > 0 {
+		o.cleanupInterval = min(max(o.cleanupInterval, o.minCleanupInterval), o.maxCleanupInterval)
+	}
+
+	cache := &Cache[K, V]{
+		cleanupInterval:    o.cleanupInterval,
+		clock:              o.clock,
+		minCleanupInterval: o.minCleanupInterval,
+		maxCleanupInterval: o.maxCleanupInterval,
+		log:                o.logger,
+		seed:               maphash.MakeSeed(),
+		events:             &eventHub[K, V]{watchers: make(map[K]map[chan Event[K, V]]struct{})},
+		stop:               make(chan struct{}),
+		windowed:           o.statsWindow,
+	}
+
+	cache.ttl.Store(int64(o.ttl))
+	cache.configuredTTL = o.ttl
+
+	if o.coarseClock > 0 {
+		cache.clock = newCoarseClock(o.clock)
+	}
+
+	if o.copyOnRead || o.copyOnWrite {
+		cloner, ok := o.cloner.(func(V) V)
+
+		if !ok {
+			return nil, fmt.Errorf("cache: copy on read or write requires a cloner for %T, got %T", cloner, o.cloner)
+		}
+
+		cache.copyOnRead = o.copyOnRead
+		cache.copyOnWrite = o.copyOnWrite
+		cache.clone = cloner
+
+		if o.copyOnRead {
+			cache.events.clone = cloner
+		}
+	}
+
+	// Функция обратного вызова передается без типа, поэтому проверяем,
+	// что ее сигнатура совпадает с типами ключа и значения кэша
+	if o.onEvicted != nil {
+		onEvicted, ok := o.onEvicted.(func(K, V, EvictionReason))
+
+		if !ok {
+			return nil, fmt.Errorf("cache: on evicted callback has type %T, want %T", o.onEvicted, onEvicted)
+		}
+
+		cache.onEvicted = onEvicted
+	}
+
+	if o.loader != nil {
+		loader, ok := o.loader.(func(context.Context, K) (V, error))
+
+		if !ok {
+			return nil, fmt.Errorf("cache: loader has type %T, want %T", o.loader, loader)
+		}
+
+		cache.loader = loader
+
+		if cache.log != nil {
+			cache.loader = cache.logLoader(loader)
+		}
+	}
+
+	if o.tracerProvider != nil || o.meterProvider != nil {
+		telemetry, err := newTelemetry(o.tracerProvider, o.meterProvider)
+
+		if err != nil {
+			return nil, err
+		}
+
+		cache.telemetry = telemetry
+		cache.stats.telemetry = telemetry
+
+		if cache.loader != nil {
+			cache.loader = instrumentLoader(telemetry, cache.loader)
+		}
+	}
+
+	if o.store != nil {
+		store, ok := o.store.(Store[K, V])
+
+		if !ok {
+			var (
+				key   K
+				value V
+			)
+
+			return nil, fmt.Errorf("cache: store has type %T, want Store[%T, %T]", o.store, key, value)
+		}
+
+		cache.store = store
+	}
+
+	if o.staleWindow > 0 {
+		if cache.loader == nil {
+			return nil, fmt.Errorf("cache: stale-while-revalidate requires a loader to be set")
+		}
+
+		cache.grace = o.staleWindow
+	}
+
+	if o.refreshWindow > 0 && cache.loader == nil {
+		return nil, fmt.Errorf("cache: refresh-ahead requires a loader to be set")
+	}
+
+	if o.writeBehind {
+		if cache.store == nil {
+			return nil, fmt.Errorf("cache: write-behind requires a store to be set")
+		}
+
+		cache.writeBehind = newWriteBehind[K, V](o.flushSize)
+	}
+
+	if o.writeBuffer > 0 {
+		if cache.store != nil {
+			return nil, fmt.Errorf("cache: buffered writes are incompatible with a store")
+		}
+
+		cache.pipeline = &writePipeline[K, V]{ops: make(chan writeOp[K, V], o.writeBuffer)}
+	}
+
+	if o.bus != nil {
+		if _, ok := any(cache).(*Cache[string, V]); !ok {
+			var key K
+
+			return nil, fmt.Errorf("cache: invalidation bus requires string keys, got %T", key)
+		}
+
+		cache.bus = o.bus
+		cache.instanceID = newInstanceID()
+	}
+
+	var indexKeys func(V) []string
+
+	if o.indexKeys != nil {
+		var ok bool
+
+		indexKeys, ok = o.indexKeys.(func(V) []string)
+
+		if !ok {
+			return nil, fmt.Errorf("cache: index keys function has type %T, want %T", o.indexKeys, indexKeys)
+		}
+	}
+
+	var prune func(V, time.Time) (V, bool)
+
+	if o.orderTTL > 0 {
+		pruneBefore, ok := o.prune.(func(V, time.Time) (V, bool))
+
+		if !ok {
+			var value V
+
+			return nil, fmt.Errorf("cache: order ttl requires a profile cache, got values of type %T", value)
+		}
+
+		prune = func(value V, now time.Time) (V, bool) {
+			return pruneBefore(value, now.Add(-o.orderTTL))
+		}
+	}
+
+	// Функция удаления устаревших заказов задается только кэшем профилей
+	if o.maxOrders > 0 {
+		if o.prune == nil {
+			var value V
+
+			return nil, fmt.Errorf("cache: max orders per profile requires a profile cache, got values of type %T", value)
+		}
+
+		cache.maxOrders = o.maxOrders
+	}
+
+	var sizer func(K, V) int64
+
+	if o.maxBytes > 0 {
+		sizer, err = sizerFunc[K, V](o)
+
+		if err != nil {
+			return nil, err
+		}
+	} else if o.sizer != nil {
+		return nil, fmt.Errorf("cache: sizer requires max bytes to be set")
+	}
+
+	var newPolicy func() EvictionPolicy[K]
+
+	if o.capacity > 0 || o.maxBytes > 0 {
+		newPolicy, err = policyFactory[K](o)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if o.tinyLFU && o.capacity == 0 {
+			return nil, fmt.Errorf("cache: tinylfu admission requires max entries to be set")
+		}
+	} else if o.policy != nil || o.customPolicy != nil {
+		return nil, fmt.Errorf("cache: eviction policy requires max entries or max bytes to be set")
+	} else if o.tinyLFU {
+		return nil, fmt.Errorf("cache: tinylfu admission requires max entries to be set")
+	} else if o.lowWatermark < 1 {
+		return nil, fmt.Errorf("cache: low watermark requires max entries or max bytes to be set")
+	}
+
+	if o.readOptimized && (o.sliding || o.maxIdle > 0 || o.capacity > 0 || o.maxBytes > 0 || o.refreshWindow > 0 || o.accessTracking) {
+		return nil, fmt.Errorf("cache: read optimized mode is incompatible with options that modify the cache on read")
+	}
+
+	if o.capacity > 0 && o.capacity < o.shards {
+		return nil, fmt.Errorf("cache: max entries %d is less than shard count %d", o.capacity, o.shards)
+	}
+
+	// Общие ограничения емкости и памяти делим между сегментами поровну с округлением вверх
+	capacity := ceilDiv(o.capacity, o.shards)
+	maxBytes := (o.maxBytes + int64(o.shards) - 1) / int64(o.shards)
+	initialCapacity := ceilDiv(o.initialCapacity, o.shards)
+
+	cache.shards = make([]*shard[K, V], o.shards)
+
+	items := &sync.Pool{New: func() any { return new(CacheItem[V]) }}
+	keys := &sync.Pool{New: func() any { return new([]K) }}
+
+	for i := range cache.shards {
+		shard := &shard[K, V]{
+			data:            make(map[K]*CacheItem[V], initialCapacity),
+			initialCapacity: initialCapacity,
+			capacity:        capacity,
+			maxBytes:        maxBytes,
+			lowCapacity:     min(capacity-1, int(float64(capacity)*o.lowWatermark)),
+			lowBytes:        int64(float64(maxBytes) * o.lowWatermark),
+			sizer:           sizer,
+			sliding:         o.sliding,
+			tracking:        o.accessTracking,
+			trackingRate:    uint32(max(o.accessSampling, 1)),
+			newPolicy:       newPolicy,
+			events:          cache.events,
+			items:           items,
+			keys:            keys,
+		}
+
+		// С фильтром допуска политика сегмента работает за окном W-TinyLFU, в том числе после `Clear`
+		if o.tinyLFU {
+			admission := newTinyLFU[K](capacity)
+
+			shard.admission = admission
+			shard.newPolicy = func() EvictionPolicy[K] {
+				return newWindowPolicy(newPolicy(), admission, capacity, &cache.stats.rejected)
+			}
+		}
+
+		if shard.newPolicy != nil {
+			shard.policy = shard.newPolicy()
+		}
+
+		shard.sampling = o.expiration == Sampling
+		shard.jitter = o.ttlJitter
+		shard.defaultTTL = &cache.ttl
+		shard.maxIdle = o.maxIdle
+
+		if o.statsWindow {
+			shard.window = new(statsWindow)
+		}
+
+		if o.withoutBackgroundGC {
+			shard.lazy = true
+			shard.notify = cache.notifyEvicted
+		}
+
+		if o.expiration == TimingWheel {
+			shard.wheel = newTimingWheel[K](o.cleanupInterval, cache.clock.Now())
+		}
+
+		shard.grace = cache.grace
+		shard.clock = cache.clock
+
+		if o.refreshWindow > 0 {
+			shard.refreshWindow = o.refreshWindow
+			shard.refreshHits = uint32(o.refreshHits)
+			shard.refresh = cache.revalidate
+		}
+
+		if indexKeys != nil {
+			shard.indexKeys = indexKeys
+			shard.index = make(map[string]K)
+		}
+
+		shard.prune = prune
+
+		cache.shards[i] = shard
+	}
+
+	// Восстанавливаем значения из снимка до запуска фоновых горутин,
+	// чтобы при ошибке восстановления не оставлять их работающими
+	if o.snapshotPath != "" {
+		if err := cache.restoreSnapshot(o.snapshotPath); err != nil {
+			return nil, err
+		}
+
+		if cache.log != nil {
+			cache.log.Debug("cache: snapshot restored", "path", o.snapshotPath, "entries", cache.Len())
+		}
+
+		cache.snapshotPath = o.snapshotPath
+	}
+
+	// Журнал воспроизводится после снимка, поскольку содержит более поздние изменения.
+	// Журнал подключается к сегментам после воспроизведения, чтобы не дублировать записи
+	if o.logPath != "" {
+		journal, err := cache.openLog(o.logPath)
+
+		if err != nil {
+			return nil, err
+		}
+
+		cache.journal = journal
+
+		for _, shard := range cache.shards {
+			shard.journal = journal
+		}
+	}
+
+	// Копии сегментов для чтения строятся после восстановления значений из снимка и журнала
+	if o.readOptimized {
+		for _, shard := range cache.shards {
+			shard.view = &readView[K, V]{}
+			shard.publishView()
+		}
+	}
+
+	// Подписка оформляется последней, поскольку при ошибке последующих шагов подписку пришлось бы отменять
+	if cache.bus != nil {
+		unsubscribe, err := cache.bus.Subscribe(cache.receive)
+
+		if err != nil {
+			return nil, fmt.Errorf("cache: subscribe to invalidation bus: %w", err)
+		}
+
+		cache.unsubscribe = unsubscribe
+	}
+
+	// Тикеры фоновых горутин создаются до их запуска, поэтому сдвиг управляемых
+	// часов (`WithClock`) сразу после создания кэша не может их опередить
+	if coarse, ok := cache.clock.(*coarseClock); ok {
+		go coarse.run(o.clock.Ticker(o.coarseClock), cache.stop)
+	}
+
+	if !o.withoutBackgroundGC {
+		go cache.collectGarbage(cache.clock.Ticker(cache.cleanupInterval))
+	}
+
+	if cache.writeBehind != nil {
+		go cache.flusher(cache.clock.Ticker(o.flushInterval))
+	}
+
+	if cache.snapshotPath != "" {
+		go cache.snapshotter(cache.clock.Ticker(o.snapshotInterval))
+	}
+
+	if cache.journal != nil {
+		go cache.logger(cache.clock.Ticker(logSyncInterval))
+	}
+
+	if o.readOptimized {
+		go cache.publisher(cache.clock.Ticker(readViewInterval))
+	}
+
+	if cache.pipeline != nil {
+		go cache.writer()
+	}
+
+	if cache.windowed {
+		go cache.rotateStats(cache.clock.Ticker(statsInterval))
+	}
+
+	return cache, nil
+}
+
+// Функция выбора сегмента, в котором хранится значение по ключу
+func (cache *Cache[K, V]) shardFor(key K) *shard[K, V] {
+	if len(cache.shards) == 1 {
+		return cache.shards[0]
+	}
+
+	return cache.shards[hashKey(cache.seed, key)%uint64(len(cache.shards))]
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
+
+/*
+ * Функция получения значения кэша по ключу. При включенном скользящем времени жизни
+ * (`WithSlidingExpiration`) каждое успешное чтение заново отсчитывает TTL значения,
+ * а при ограничении количества значений чтение учитывается политикой вытеснения.
+ * Если задан загрузчик (`WithLoader`), при промахе значение загружается и записывается
+ * в кэш, а ошибка загрузки возвращается как отсутствие значения
+ */
+func (cache *Cache[K, V]) Get(key K) (V, bool) {
+	if cache.loader != nil {
+		value, err := cache.GetContext(context.Background(), key)
+
+		return value, err == nil
+	}
+
+	value, ok := cache.get(key)
+
+	cache.recordRead(key, ok)
+
+	if ok {
+		value = cache.copyOut(value)
+	}
+
+	return value, ok
+}
+
+/*
+ * Функция получения значения кэша по ключу с ошибкой вместо признака наличия. Позволяет отличить
+ * отсутствующее значение (`ErrNotFound`) от просроченного, но еще не удаленного сборщиком мусора
+ * (`ErrExpired`), и закрытый кэш (`ErrClosed`). Ошибки можно оборачивать через `%w` и проверять
+ * через `errors.Is`. Если задан загрузчик (`WithLoader`), при промахе работает как `GetContext`
+ */
+func (cache *Cache[K, V]) GetE(key K) (V, error) {
+	if cache.loader != nil {
+		return cache.GetContext(context.Background(), key)
+	}
+
+	value, ok := cache.Get(key)
+
+	if ok {
+		return value, nil
+	}
+
+	return value, cache.shardFor(key).missReason(key)
+}
+
+// Функция чтения значения без загрузки при промахе и без учета в статистике
+func (cache *Cache[K, V]) get(key K) (V, bool) {
+	return cache.shardFor(key).get(key)
+}
+
+/*
+ * Функция получения значения кэша по ключу вместе со временем его истечения. Позволяет вызывающему
+ * коду заранее обновить значение, срок которого подходит к концу. Чтение продлевает время жизни
+ * и учитывается политикой вытеснения так же, как `Get`, и возвращается уже продленное время истечения
+ */
+func (cache *Cache[K, V]) GetWithExpiration(key K) (V, time.Time, bool) {
+	value, expireAt, ok := cache.shardFor(key).getWithExpiration(key)
+
+	cache.recordRead(key, ok)
+
+	if ok {
+		value = cache.copyOut(value)
+	}
+
+	return value, expireAt, ok
+}
+
+/*
+ * Функция поиска значения по вторичному ключу индекса. Вторичный ключ не определяет сегмент,
+ * поэтому сегменты просматриваются по очереди
+ */
+func (cache *Cache[K, V]) lookup(secondary string) (K, V, bool) {
+	for _, shard := range cache.shards {
+		if key, value, ok := shard.lookup(secondary); ok {
+			return key, value, true
+		}
+	}
+
+	var (
+		zeroKey   K
+		zeroValue V
+	)
+
+	return zeroKey, zeroValue, false
+}
+
+/*
+ * Функция получения значения кэша по ключу без продления времени жизни значения
+ * и без изменения порядка вытеснения
+ */
+func (cache *Cache[K, V]) Peek(key K) (V, bool) {
+	value, ok := cache.peek(key)
+
+	cache.recordRead(key, ok)
+
+	if ok {
+		value = cache.copyOut(value)
+	}
+
+	return value, ok
+}
+
+// Функция чтения значения без продления времени жизни и без учета в статистике
+func (cache *Cache[K, V]) peek(key K) (V, bool) {
+	return cache.shardFor(key).peek(key)
+}
+
+/*
+ * Функция продления времени жизни значения без его чтения. Время жизни отсчитывается заново с текущего
+ * момента на TTL значения. Возвращает `false`, если значение отсутствует или уже просрочено
+ */
+func (cache *Cache[K, V]) Touch(key K) bool {
+	cache.awaitWrites()
+
+	shard := cache.shardFor(key)
+
+	shard.mutex.Lock()
+
+	defer shard.mutex.Unlock()
+
+	item, ok := shard.data[key]
+
+	if !ok {
+		return false
+	}
+
+	now := cache.clock.Now()
+
+	if now.After(item.expireAt) {
+		return false
+	}
+
+	shard.reschedule(key, item, shard.expireAfter(now, item.ttl))
+
+	return true
+}
+
+/*
+ * Функция установки нового времени жизни значения без его чтения. Время жизни отсчитывается с текущего
+ * момента и становится TTL значения для последующих `Touch`. Как и `Touch`, записывается в журнал
+ * (`WithPersistenceLog`) и передается репликам. Возвращает `false`, если значение отсутствует или уже просрочено
+ */
+func (cache *Cache[K, V]) Expire(key K, ttl time.Duration) bool {
+	cache.awaitWrites()
+
+	shard := cache.shardFor(key)
+
+	shard.mutex.Lock()
+
+	defer shard.mutex.Unlock()
+
+	item, ok := shard.data[key]
+
+	if !ok {
+		return false
+	}
+
+	now := cache.clock.Now()
+
+	if now.After(item.expireAt) {
+		return false
+	}
+
+	item.ttl = ttl
+
+	shard.reschedule(key, item, shard.expireAfter(now, ttl))
+
+	return true
+}
+
+/*
+ * Функция закрепления значения, например профиля служебной учетной записи. Закрепленное значение
+ * не вытесняется при ограничении емкости (`WithMaxEntries`, `WithMaxBytes`), но по-прежнему истекает
+ * по времени жизни (если не записано с `NoExpiration`) и удаляется `Delete`. Закрепление сохраняется
+ * при перезаписи значения и снимается вместе с удалением значения. Если закреплены все значения
+ * сегмента, новое значение записывается сверх ограничения емкости. Возвращает `false`, если значение
+ * отсутствует или уже просрочено
+ */
+func (cache *Cache[K, V]) Pin(key K) bool {
+	return cache.pin(key, true)
+}
+
+/*
+ * Функция снятия закрепления (`Pin`). Значение снова вытесняется политикой, как только что записанное.
+ * Возвращает `false`, если значение отсутствует или уже просрочено
+ */
+func (cache *Cache[K, V]) Unpin(key K) bool {
+	return cache.pin(key, false)
+}
+
+func (cache *Cache[K, V]) pin(key K, pinned bool) bool {
+	cache.awaitWrites()
+
+	shard := cache.shardFor(key)
+
+	shard.mutex.Lock()
+
+	defer shard.mutex.Unlock()
+
+	item, ok := shard.data[key]
+
+	if !ok || cache.clock.Now().After(item.expireAt) {
+		return false
+	}
+
+	if item.pinned == pinned {
+		return true
+	}
+
+	item.pinned = pinned
+
+	// Закрепленное значение снимается с учета политики, поэтому она не может выбрать его для вытеснения
+	if shard.policy != nil {
+		if pinned {
+			shard.policy.OnDelete(key)
+		} else {
+			shard.track(key, item)
+		}
+	}
+
+	return true
+}
+
+/*
+ * Функция получения оставшегося времени жизни значения. Позволяет заранее обновить значение,
+ * срок которого подходит к концу. Для значения без истечения возвращает `NoExpiration`.
+ * Возвращает `false`, если значение отсутствует или уже просрочено
+ */
+func (cache *Cache[K, V]) TTL(key K) (time.Duration, bool) {
+	shard := cache.shardFor(key)
+
+	shard.mutex.RLock()
+
+	defer shard.mutex.RUnlock()
+
+	item, ok := shard.data[key]
+
+	if !ok {
+		return 0, false
+	}
+
+	if item.expireAt.Equal(neverExpires) {
+		return NoExpiration, true
+	}
+
+	remaining := item.expireAt.Sub(cache.clock.Now())
+
+	if remaining < 0 {
+		return 0, false
+	}
+
+	return remaining, true
+}
+
+/*
+ * Функция получения источника времени кэша (`WithClock`). Позволяет коду поверх кэша, например серверам
+ * протоколов, вычислять сроки истечения по тем же часам, что и кэш
+ */
+func (cache *Cache[K, V]) Clock() Clock {
+	return cache.clock
+}
+
+// Функция получения времени жизни значений по умолчанию
+func (cache *Cache[K, V]) DefaultTTL() time.Duration {
+	return time.Duration(cache.ttl.Load())
+}
+
+/*
+ * Функция изменения времени жизни значений по умолчанию во время работы, например из административного
+ * обработчика (`PUT /ttl` в `Handler`) без перезапуска сервиса. Новое время жизни получают значения,
+ * записанные после вызова. При `existing` время истечения значений, записанных с прежним TTL по умолчанию,
+ * сдвигается на разницу между новым и прежним TTL, как если бы они были записаны с новым TTL. Значения
+ * с индивидуальным временем жизни (`SetWithTTL`, `SetWithExpireAt`) не изменяются. Значения, новое время
+ * истечения которых уже прошло, удаляются сборщиком мусора. Возвращает ошибку, если `ttl` не положителен
+ */
+func (cache *Cache[K, V]) SetDefaultTTL(ttl time.Duration, existing bool) error {
+	if ttl <= 0 {
+		return fmt.Errorf("cache: ttl must be positive, got %s", ttl)
+	}
+
+	if !existing {
+		cache.ttl.Store(int64(ttl))
+		cache.recordDefaultTTL(ttl)
+
+		return nil
+	}
+
+	cache.awaitWrites()
+
+	previous := time.Duration(cache.ttl.Swap(int64(ttl)))
+
+	cache.recordDefaultTTL(ttl)
+
+	for _, shard := range cache.shards {
+		shard.mutex.Lock()
+
+		for key, item := range shard.data {
+			// Значение, записанное после замены времени жизни по умолчанию, уже получило новое время жизни
+			if !item.defaultTTL || item.ttl != previous {
+				continue
+			}
+
+			item.ttl = ttl
+
+			shard.reschedule(key, item, item.deadline.Add(ttl-previous))
+		}
+
+		shard.mutex.Unlock()
+	}
+
+	return nil
+}
+
+/*
+ * Функция записи нового времени жизни по умолчанию в журнал (`WithPersistenceLog`) и передачи его репликам.
+ * Значения, время истечения которых сдвинуто, записываются отдельно под блокировкой своих сегментов
+ */
+func (cache *Cache[K, V]) recordDefaultTTL(ttl time.Duration) {
+	record := logRecord[K, V]{Op: logDefaultTTL, TTL: ttl}
+
+	if cache.journal != nil {
+		cache.journal.append(record)
+	}
+
+	if hub := cache.hub.Load(); hub != nil {
+		hub.publish(record)
+	}
+}
+
+/*
+ * Функция записи значения в кэш-хранилище по ключу. Время жизни значения равно TTL кэша
+ */
+func (cache *Cache[K, V]) Set(key K, value V) {
+	cache.SetWithTTL(key, value, useDefaultTTL)
+}
+
+/*
+ * Функция записи значения в кэш-хранилище по ключу с индивидуальным временем жизни. Позволяет
+ * хранить часто запрашиваемые значения дольше остальных
+ */
+func (cache *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	_ = cache.setWithTTL(context.Background(), key, value, ttl)
+}
+
+/*
+ * Функция записи значения с индивидуальным временем жизни. При заданном хранилище (`WithStore`)
+ * значение сначала сохраняется в хранилище, и при ошибке сохранения кэш не изменяется
+ */
+func (cache *Cache[K, V]) setWithTTL(ctx context.Context, key K, value V, ttl time.Duration) error {
+	// Буфер записи несовместим с хранилищем, что проверяется в конструкторе
+	if cache.pipeline != nil {
+		return cache.enqueue(key, cache.copyIn(value), ttl)
+	}
+
+	stored := cache.copyIn(value)
+
+	return cache.write(ctx, key, value, func(shard *shard[K, V]) []evictedItem[K, V] {
+		return shard.set(key, stored, ttl)
+	})
+}
+
+/*
+ * Функция записи значения с абсолютным временем истечения вместо относительного времени жизни, например
+ * когда значение должно истечь вместе с окончанием акции. Разброс `WithTTLJitter` не применяется.
+ * Время жизни значения для последующих `Touch` и `WithSlidingExpiration` равно времени, оставшемуся
+ * до `at` на момент записи. Значение с прошедшим `at` записывается уже просроченным
+ */
+func (cache *Cache[K, V]) SetWithExpireAt(key K, value V, at time.Time) {
+	_ = cache.setWithExpireAt(context.Background(), key, value, at)
+}
+
+/*
+ * Функция записи значения с абсолютным временем истечения. Запись выполняется в обход буфера записи
+ * (`WithBufferedWrites`), поскольку он хранит только относительное время жизни
+ */
+func (cache *Cache[K, V]) setWithExpireAt(ctx context.Context, key K, value V, at time.Time) error {
+	stored := cache.copyIn(value)
+
+	cache.awaitWrites()
+
+	return cache.write(ctx, key, value, func(shard *shard[K, V]) []evictedItem[K, V] {
+		return shard.setUntil(key, stored, at.Sub(shard.clock.Now()), at, false)
+	})
+}
+
+// Функция записи значения из буфера записи (`WithBufferedWrites`), несовместимого с хранилищем
+func (cache *Cache[K, V]) set(key K, value V, ttl time.Duration) error {
+	return cache.write(context.Background(), key, value, func(shard *shard[K, V]) []evictedItem[K, V] {
+		return shard.set(key, value, ttl)
+	})
+}
+
+/*
+ * Функция безусловной записи значения `value` по ключу функцией `fn`, вызываемой под блокировкой
+ * на запись. Значение сохраняется в хранилище (`WithStore`) до изменения кэша
+ */
+func (cache *Cache[K, V]) write(ctx context.Context, key K, value V, fn func(*shard[K, V]) []evictedItem[K, V]) error {
+	_, err := cache.writeThrough(ctx, key, &value, func(shard *shard[K, V]) (V, bool, []evictedItem[K, V]) {
+		return value, true, fn(shard)
+	})
+
+	return err
+}
+
+/*
+ * Функция записи значения, только если по ключу нет актуального значения. Проверка и запись выполняются
+ * под одной блокировкой. Возвращает `ErrExists`, если значение уже присутствует
+ */
+func (cache *Cache[K, V]) Add(key K, value V) error {
+	return cache.setIf(key, value, false)
+}
+
+/*
+ * Функция перезаписи значения, только если по ключу есть актуальное значение. Проверка и запись
+ * выполняются под одной блокировкой. Возвращает `ErrNotFound`, если значение отсутствует или просрочено
+ */
+func (cache *Cache[K, V]) Replace(key K, value V) error {
+	return cache.setIf(key, value, true)
+}
+
+/*
+ * Функция атомарного изменения значения. Функция `fn` получает текущее значение и возвращает новое,
+ * которое записывается в хранилище с заново отсчитанным временем жизни значения. Чтение, изменение
+ * и запись выполняются под одной блокировкой на запись, поэтому одновременные изменения одного
+ * значения не затирают друг друга. Функция `fn` вызывается под блокировкой и не должна обращаться
+ * к кэшу. Возвращает `false`, если значение отсутствует или просрочено
+ */
+func (cache *Cache[K, V]) Update(key K, fn func(V) V) bool {
+	return cache.update(key, func(value V) (V, bool) {
+		return fn(value), true
+	})
+}
+
+/*
+ * Функция атомарного изменения значения, позволяющая отказаться от записи. Если `fn` возвращает
+ * `false`, значение и его время жизни остаются прежними, а функция возвращает `false`
+ */
+func (cache *Cache[K, V]) update(key K, fn func(V) (V, bool)) bool {
+	cache.awaitWrites()
+
+	updated, err := cache.writeThrough(context.Background(), key, nil, func(shard *shard[K, V]) (V, bool, []evictedItem[K, V]) {
+		item, ok := shard.data[key]
+
+		now := cache.clock.Now()
+
+		if !ok || now.After(item.expireAt) {
+			var zero V
+
+			return zero, false, nil
+		}
+
+		if shard.prune != nil {
+			shard.pruneLocked(key, item, now)
+		}
+
+		value, updated := fn(item.value)
+
+		if !updated {
+			return value, false, nil
+		}
+
+		return value, true, shard.set(key, value, item.lifetime())
+	})
+
+	return updated && err == nil
+}
+
+/*
+ * Функция записи значения, вычисленного из текущего значения по ключу. Функция `fn` получает текущее
+ * значение и признак его наличия, вызывается под блокировкой на запись и не должна обращаться к кэшу.
+ * Значение записывается с временем жизни кэша. Возвращает ошибку хранилища (`WithStore`)
+ */
+func (cache *Cache[K, V]) upsert(key K, fn func(V, bool) V) error {
+	cache.awaitWrites()
+
+	_, err := cache.writeThrough(context.Background(), key, nil, func(shard *shard[K, V]) (V, bool, []evictedItem[K, V]) {
+		var current V
+
+		item, ok := shard.data[key]
+
+		now := cache.clock.Now()
+
+		if ok && now.After(item.expireAt) {
+			ok = false
+		}
+
+		if ok {
+			if shard.prune != nil {
+				shard.pruneLocked(key, item, now)
+			}
+
+			current = item.value
+		}
+
+		value := fn(current, ok)
+
+		return value, true, shard.set(key, value, useDefaultTTL)
+	})
+
+	return err
+}
+
+// Функция условной записи значения в зависимости от наличия актуального значения по ключу
+func (cache *Cache[K, V]) setIf(key K, value V, present bool) error {
+	cache.awaitWrites()
+
+	stored := cache.copyIn(value)
+
+	var conflict error
+
+	_, err := cache.writeThrough(context.Background(), key, nil, func(shard *shard[K, V]) (V, bool, []evictedItem[K, V]) {
+		item, ok := shard.data[key]
+		ok = ok && !cache.clock.Now().After(item.expireAt)
+
+		if ok != present {
+			conflict = ErrNotFound
+
+			if ok {
+				conflict = ErrExists
+			}
+
+			return value, false, nil
+		}
+
+		return value, true, shard.set(key, stored, useDefaultTTL)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return conflict
+}
+
+// Функция копирования значения, возвращаемого вызывающему коду (`WithCopyOnRead`)
+func (cache *Cache[K, V]) copyOut(value V) V {
+	if !cache.copyOnRead {
+		return value
+	}
+
+	return cache.clone(value)
+}
+
+// Функция копирования значения, записываемого в кэш (`WithCopyOnWrite`)
+func (cache *Cache[K, V]) copyIn(value V) V {
+	if !cache.copyOnWrite {
+		return value
+	}
+
+	return cache.clone(value)
+}
+
+// Функция учета удаленных значений в статистике и уведомления о них. Вызывается без удержания блокировки
+func (cache *Cache[K, V]) notifyEvicted(evicted []evictedItem[K, V]) {
+	for _, item := range evicted {
+		cache.record(item.key, item.reason)
+	}
+
+	if cache.onEvicted == nil {
+		return
+	}
+
+	for _, item := range evicted {
+		cache.onEvicted(item.key, item.value, item.reason)
+	}
+}
+
+/*
+ * Функция удаления значения из кэш-хранилища по ключу. Возвращает `true`, если значение
+ * присутствовало в хранилище на момент удаления. При заданном хранилище (`WithStore`) значение
+ * удаляется и из него, ошибка удаления доступна через `DeleteContext`
+ */
+func (cache *Cache[K, V]) Delete(key K) bool {
+	ok, _ := cache.DeleteContext(context.Background(), key)
+
+	return ok
+}
+
+/*
+ * Функция атомарного получения и удаления значения. Только один из одновременно вызвавших `Pop` потоков
+ * получит значение, поэтому функция подходит для однократной передачи значения обработчику. Удаление
+ * передается в `WithOnEvicted` с причиной `EvictedDeleted`, но не затрагивает хранилище (`WithStore`).
+ * Возвращает `false`, если значение отсутствует или просрочено
+ */
+func (cache *Cache[K, V]) Pop(key K) (V, bool) {
+	cache.awaitWrites()
+
+	shard := cache.shardFor(key)
+
+	var item *CacheItem[V]
+
+	shard.locked(func() {
+		current, ok := shard.data[key]
+
+		if !ok || shard.clock.Now().After(current.expireAt) {
+			return
+		}
+
+		item = current
+
+		shard.remove(key, EvictedDeleted)
+	})
+
+	cache.recordRead(key, item != nil)
+
+	if item == nil {
+		var zero V
+
+		return zero, false
+	}
+
+	cache.notifyEvicted([]evictedItem[K, V]{{key: key, value: item.value, reason: EvictedDeleted}})
+	cache.broadcast(key)
+
+	return cache.copyOut(item.value), true
+}
+
+// Функция удаления значения из кэша без удаления из хранилища (`WithStore`)
+func (cache *Cache[K, V]) invalidate(key K) bool {
+	cache.awaitWrites()
+
+	shard := cache.shardFor(key)
+
+	var item *CacheItem[V]
+
+	// На время удаления блокируем мьютекс на запись в кэш-хранилище
+	shard.locked(func() {
+		if current, ok := shard.data[key]; ok {
+			item = current
+
+			shard.remove(key, EvictedDeleted)
+		}
+	})
+
+	if item == nil {
+		return false
+	}
+
+	cache.notifyEvicted([]evictedItem[K, V]{{key: key, value: item.value, reason: EvictedDeleted}})
+
+	return true
+}
+
+/*
+ * Функция полной очистки кэш-хранилища. Вместо поэлементного удаления подменяем хранилище
+ * новым пустым словарем, поэтому очистка выполняется за константное время, а старый словарь
+ * будет освобожден сборщиком мусора Go. Фоновый сборщик протухших значений продолжает работу.
+ * Удаленные значения передаются в `WithOnEvicted` с причиной `EvictedCleared` после снятия блокировок
+ */
+func (cache *Cache[K, V]) Clear() {
+	cache.awaitWrites()
+
+	if cache.journal != nil || cache.hub.Load() != nil {
+		cache.notifyCleared(cache.clearLogged())
+
+		return
+	}
+
+	cleared := make([]map[K]*CacheItem[V], 0, len(cache.shards))
+
+	for _, shard := range cache.shards {
+		shard.mutex.Lock()
+		cleared = append(cleared, shard.data)
+		shard.reset()
+		shard.mutex.Unlock()
+	}
+
+	cache.notifyCleared(cleared)
+}
+
+/*
+ * Функция полной очистки кэша с записью в журнал (`WithPersistenceLog`) и передачей репликам. Все сегменты
+ * блокируются одновременно, чтобы запись очистки разделяла изменения ровно так же, как в памяти.
+ * Возвращает словари, замененные при очистке
+ */
+func (cache *Cache[K, V]) clearLogged() []map[K]*CacheItem[V] {
+	for _, shard := range cache.shards {
+		shard.mutex.Lock()
+	}
+
+	record := logRecord[K, V]{Op: logClear}
+
+	if cache.journal != nil {
+		cache.journal.append(record)
+	}
+
+	if hub := cache.hub.Load(); hub != nil {
+		hub.publish(record)
+	}
+
+	cleared := make([]map[K]*CacheItem[V], 0, len(cache.shards))
+
+	for _, shard := range cache.shards {
+		cleared = append(cleared, shard.data)
+		shard.reset()
+		shard.mutex.Unlock()
+	}
+
+	return cleared
+}
+
+/*
+ * Функция учета и уведомления о значениях, удаленных очисткой кэша. Словари перечислены в порядке
+ * сегментов, а после `reset` больше не доступны другим потокам, поэтому обходятся без блокировки
+ */
+func (cache *Cache[K, V]) notifyCleared(cleared []map[K]*CacheItem[V]) {
+	for i, data := range cleared {
+		cache.stats.cleared.Add(uint64(len(data)))
+
+		if cache.windowed {
+			cache.shards[i].window.record(EvictedCleared, uint64(len(data)))
+		}
+
+		if cache.onEvicted == nil {
+			continue
+		}
+
+		for key, item := range data {
+			cache.onEvicted(key, item.value, EvictedCleared)
+		}
+	}
+}
+
+/*
+ * Функция подсчета количества актуальных значений в кэш-хранилище. Просроченные, но еще
+ * не удаленные сборщиком мусора значения не учитываются
+ */
+func (cache *Cache[K, V]) Len() int {
+	now := cache.clock.Now()
+	count := 0
+
+	for _, shard := range cache.shards {
+		shard.mutex.RLock()
+
+		for _, item := range shard.data {
+			if !now.After(item.expireAt) {
+				count++
+			}
+		}
+
+		shard.mutex.RUnlock()
+	}
+
+	return count
+}
+
+/*
+ * Функция получения ключей всех актуальных значений кэш-хранилища. Порядок ключей не гарантируется
+ */
+func (cache *Cache[K, V]) Keys() []K {
+	now := cache.clock.Now()
+
+	var keys []K
+
+	for _, shard := range cache.shards {
+		shard.mutex.RLock()
+
+		for key, item := range shard.data {
+			if !now.After(item.expireAt) {
+				keys = append(keys, key)
+			}
+		}
+
+		shard.mutex.RUnlock()
+	}
+
+	return keys
+}
+
+/*
+ * Функция обхода актуальных значений кэш-хранилища. Ключи каждого сегмента копируются под блокировкой
+ * на чтение, после чего значения читаются по одному, а функция `fn` вызывается без удержания блокировки
+ * и может обращаться к кэшу. Значения, удаленные или истекшие во время обхода, пропускаются. Чтение не
+ * продлевает время жизни и не учитывается в статистике. Обход прекращается, если `fn` возвращает `false`
+ */
+func (cache *Cache[K, V]) Range(fn func(key K, value V) bool) {
+	for _, shard := range cache.shards {
+		shard.mutex.RLock()
+
+		keys := make([]K, 0, len(shard.data))
+
+		for key := range shard.data {
+			keys = append(keys, key)
+		}
+
+		shard.mutex.RUnlock()
+
+		for _, key := range keys {
+			value, ok := shard.peek(key)
+
+			if !ok {
+				continue
+			}
+
+			if !fn(key, cache.copyOut(value)) {
+				return
+			}
+		}
+	}
+}
+
+func (cache *Cache[K, V]) GarbageCollector() {
+	// Запускаем сборщик мусора, который срабатывает каждые N-секунд
+	// по интервалу и удаляет значения из кэш-хранилища. Интервал задается при создании кэша.
+	// Чем больше интервал по очистке хранилища, тем больше памяти оно начинает занимать
+	cache.collectGarbage(cache.clock.Ticker(cache.cleanupInterval))
+}
+
+// Функция работы сборщика мусора по срабатываниям тикера
+func (cache *Cache[K, V]) collectGarbage(ticker Ticker) {
+	// При завершении очистки закрываем интервал. Адаптивный сборщик заменяет тикер, поэтому
+	// закрывается последний созданный
+	defer func() { ticker.Stop() }()
+
+	interval := cache.cleanupInterval
+
+	// Количество вытесненных значений на момент предыдущего прохода для обнаружения массового вытеснения.
+	// Счетчики ведутся с создания кэша, поэтому первый проход учитывает вытеснения с момента создания
+	var evictions uint64
+
+	for {
+		select {
+		case <-ticker.C():
+			if cache.gcPauses.Load() > 0 {
+				continue
+			}
+
+			removed := cache.sweep(false)
+
+			if cache.log != nil {
+				evictions = cache.logEvictionStorm(evictions, interval)
+			}
+
+			if cache.minCleanupInterval > 0 {
+				if next := cache.nextCleanupInterval(interval, removed); next != interval {
+					ticker.Stop()
+					ticker = cache.clock.Ticker(next)
+					interval = next
+				}
+			}
+		case <-cache.stop:
+			// Кэш закрыт - завершаем работу горутины сборщика мусора
+			return
+		}
+	}
+}
+
+// Доля удаленных за проход значений, при которой адаптивный сборщик мусора сокращает интервал
+const adaptiveExpiredRatio = 0.25
+
+/*
+ * Функция удаления просроченных значений по требованию, не дожидаясь очередного прохода сборщика
+ * мусора: тесты могут проверить очистку сразу, а операторы - освободить память (см. `POST /expired`
+ * в `Handler`). В отличие от фонового прохода при `WithExpirationEngine(Sampling)` просматривает все
+ * значения, поэтому после возврата в кэше не остается просроченных значений. Проход учитывается
+ * в статистике (`Stats.Sweeps`). Возвращает количество удаленных значений
+ */
+func (cache *Cache[K, V]) DeleteExpired() int {
+	return cache.sweep(true)
+}
+
+/*
+ * Функция ручного вытеснения `n` значений, чтобы оператор мог освободить память без перезапуска сервиса
+ * (см. `POST /evict` в `Handler`). При ограничении емкости значения выбирает политика вытеснения каждого
+ * сегмента, а количество делится между сегментами пропорционально их заполнению, и недостающее вытесняется
+ * из остальных сегментов. Без политики вытесняются значения, ближайшие к истечению. Закрепленные значения
+ * (`Pin`) не вытесняются. Вытеснение передается в `WithOnEvicted` с причиной `EvictedCapacity`, а для уже
+ * просроченных значений - с `EvictedExpired`.
+ * Возвращает количество вытесненных значений
+ */
+func (cache *Cache[K, V]) EvictOldest(n int) int {
+	if n <= 0 {
+		return 0
+	}
+
+	cache.awaitWrites()
+
+	if cache.shards[0].policy != nil {
+		return cache.evictByPolicy(n)
+	}
+
+	return cache.evictNearestExpiry(n)
+}
+
+// Функция вытеснения `n` значений, выбранных политиками вытеснения сегментов
+func (cache *Cache[K, V]) evictByPolicy(n int) int {
+	total := 0
+
+	for _, shard := range cache.shards {
+		shard.mutex.RLock()
+		total += len(shard.data)
+		shard.mutex.RUnlock()
+	}
+
+	removed := 0
+
+	/*
+	 * Первый проход вытесняет из каждого сегмента долю, пропорциональную его заполнению. Сегмент может
+	 * вытеснить меньше своей доли (закрепленные значения, удаление другими горутинами), поэтому второй
+	 * проход вытесняет остаток из сегментов по очереди
+	 */
+	for _, proportional := range []bool{true, false} {
+		for _, shard := range cache.shards {
+			if removed >= n || total == 0 {
+				break
+			}
+
+			var evicted []evictedItem[K, V]
+
+			shard.locked(func() {
+				quota := n - removed
+
+				if proportional {
+					quota = min(ceilDiv(n*len(shard.data), total), quota)
+				}
+
+				for len(evicted) < quota {
+					if _, ok := peekVictim(shard.policy); !ok {
+						break
+					}
+
+					evicted = append(evicted, shard.evict()...)
+				}
+			})
+
+			removed += len(evicted)
+
+			cache.notifyEvicted(evicted)
+		}
+	}
+
+	return removed
+}
+
+// Значение-кандидат на ручное вытеснение без политики вытеснения
+type evictionCandidate[K comparable, V any] struct {
+	shard    *shard[K, V]
+	key      K
+	expireAt time.Time
+	version  uint64
+}
+
+/*
+ * Функция вытеснения `n` значений, ближайших к истечению. Кандидаты выбираются под блокировкой
+ * на чтение, поэтому значение, перезаписанное до вытеснения, пропускается по версии
+ */
+func (cache *Cache[K, V]) evictNearestExpiry(n int) int {
+	var candidates []evictionCandidate[K, V]
+
+	byExpiry := func(a, b evictionCandidate[K, V]) int {
+		return a.expireAt.Compare(b.expireAt)
+	}
+
+	for _, shard := range cache.shards {
+		var shardCandidates []evictionCandidate[K, V]
+
+		shard.mutex.RLock()
+
+		for key, item := range shard.data {
+			if !item.pinned {
+				shardCandidates = append(shardCandidates, evictionCandidate[K, V]{shard: shard, key: key, expireAt: item.expireAt, version: item.version})
+			}
+		}
+
+		shard.mutex.RUnlock()
+
+		slices.SortFunc(shardCandidates, byExpiry)
+
+		candidates = append(candidates, shardCandidates[:min(n, len(shardCandidates))]...)
+	}
+
+	slices.SortFunc(candidates, byExpiry)
+
+	candidates = candidates[:min(n, len(candidates))]
+
+	removed := 0
+
+	for _, shard := range cache.shards {
+		var evicted []evictedItem[K, V]
+
+		shard.locked(func() {
+			now := shard.clock.Now()
+
+			for _, candidate := range candidates {
+				item, ok := shard.data[candidate.key]
+
+				if candidate.shard != shard || !ok || item.version != candidate.version {
+					continue
+				}
+
+				// Просроченное значение удаляется как истекшее, как и при перезаписи
+				reason := EvictedCapacity
+
+				if now.After(item.expireAt) {
+					reason = EvictedExpired
+				}
+
+				shard.remove(candidate.key, reason)
+
+				evicted = append(evicted, evictedItem[K, V]{key: candidate.key, value: item.value, reason: reason})
+			}
+		})
+
+		removed += len(evicted)
+
+		cache.notifyEvicted(evicted)
+	}
+
+	return removed
+}
+
+/*
+ * Функция приостановки фонового сборщика мусора на время обслуживания (массовой загрузки, сохранения
+ * снимка), чтобы его проходы не конкурировали за блокировки сегментов. Если проход уже выполняется,
+ * функция дожидается его завершения. Приостановки вложенные: сборщик возобновляется, когда на каждый
+ * вызов `PauseGC` вызван `ResumeGC`. Явный вызов `DeleteExpired` и удаление просроченных значений
+ * при обращении (`WithoutBackgroundGC`) продолжают работать
+ */
+func (cache *Cache[K, V]) PauseGC() {
+	cache.gcPauses.Add(1)
+
+	// Дожидаемся завершения прохода, начатого до приостановки
+	cache.sweeping.Lock()
+	cache.sweeping.Unlock()
+}
+
+/*
+ * Функция возобновления сборщика мусора, приостановленного `PauseGC`. Очередной проход выполняется
+ * по следующему срабатыванию тикера. Лишние вызовы без парного `PauseGC` ничего не делают
+ */
+func (cache *Cache[K, V]) ResumeGC() {
+	for {
+		pauses := cache.gcPauses.Load()
+
+		if pauses <= 0 || cache.gcPauses.CompareAndSwap(pauses, pauses-1) {
+			return
+		}
+	}
+}
+
+/*
+ * Функция прохода сборщика мусора по всем сегментам. При `full` выборочная проверка (`Sampling`)
+ * заменяется полным просмотром. Возвращает количество удаленных значений
+ */
+func (cache *Cache[K, V]) sweep(full bool) int {
+	cache.sweeping.Lock()
+
+	defer cache.sweeping.Unlock()
+
+	start := time.Now()
+	removed := 0
+
+	// Очищаем сегменты по очереди, блокируя каждый только на время его очистки
+	for _, shard := range cache.shards {
+		var evicted []evictedItem[K, V]
+
+		switch {
+		case shard.wheel != nil:
+			evicted = expireWheelItems(shard)
+		case shard.sampling && !full:
+			evicted = sampleCacheItems(shard)
+		default:
+			evicted = cleanCacheItems(shard)
+		}
+
+		if shard.prune != nil {
+			pruneCacheItems(shard)
+		}
+
+		removed += len(evicted)
+
+		cache.notifyEvicted(evicted)
+	}
+
+	duration := time.Since(start)
+
+	cache.stats.recordSweep(duration)
+
+	if cache.log != nil {
+		cache.log.Debug("cache: gc sweep", "removed", removed, "duration", duration)
+	}
+
+	return removed
+}
+
+/*
+ * Функция выбора интервала адаптивного сборщика мусора (`WithAdaptiveCleanup`) по результату прохода.
+ * Если проход удалил не меньше `adaptiveExpiredRatio` значений хранилища, интервал сокращается вдвое,
+ * если ничего не удалил или хранилище пусто - удваивается, иначе остается прежним
+ */
+func (cache *Cache[K, V]) nextCleanupInterval(interval time.Duration, removed int) time.Duration {
+	entries := cache.Len()
+
+	switch {
+	case removed == 0:
+		interval *= 2
+	case float64(removed) >= adaptiveExpiredRatio*float64(entries+removed):
+		interval /= 2
+	}
+
+	return min(max(interval, cache.minCleanupInterval), cache.maxCleanupInterval)
+}
+
+/*
+ * Функция записи предупреждения о массовом вытеснении: с предыдущего прохода сборщика мусора
+ * вытеснено не меньше значений, чем осталось в кэше, то есть кэш полностью обновился за интервал.
+ * Возвращает текущее количество вытесненных значений
+ */
+func (cache *Cache[K, V]) logEvictionStorm(previous uint64, interval time.Duration) uint64 {
+	current := cache.stats.evictions.Load()
+	evicted := current - previous
+
+	if entries := cache.Len(); evicted > 0 && evicted >= uint64(entries) {
+		cache.log.Warn("cache: eviction storm", "evicted", evicted, "entries", entries, "interval", interval)
+	}
+
+	return current
+}
+
+// Функция оборачивания загрузчика записью его ошибок в журнал событий
+func (cache *Cache[K, V]) logLoader(loader func(context.Context, K) (V, error)) func(context.Context, K) (V, error) {
+	return func(ctx context.Context, key K) (V, error) {
+		value, err := loader(ctx, key)
+
+		if err != nil {
+			cache.log.Warn("cache: loader failed", "key", key, "error", err)
+		}
+
+		return value, err
+	}
+}
+
+/*
+ * Функция закрытия кэш-хранилища. Останавливает горутину сборщика мусора, удаляет все значения
+ * и помечает кэш непригодным к использованию: после закрытия новые значения не записываются,
+ * а чтение всегда возвращает нулевое значение. При отложенной записи (`WithWriteBehind`) перед
+ * завершением в хранилище записываются все накопленные изменения, ошибки которых можно получить,
+ * вызвав `Flush` перед `Close`. Повторный вызов ничего не делает
+ */
+func (cache *Cache[K, V]) Close() {
+	cache.closeOnce.Do(func() {
+		// Применяем записи из буфера до закрытия, иначе они попали бы в снимок и журнал не полностью
+		cache.Wait()
+
+		// Сообщения шины закрытому кэшу не нужны
+		if cache.unsubscribe != nil {
+			cache.unsubscribe()
+		}
+
+		// Закрываем каналы подписчиков до удаления значений, поэтому удаление при закрытии в них не попадает
+		cache.events.close()
+
+		// Сохраняем последний снимок до удаления значений
+		if cache.snapshotPath != "" {
+			_ = cache.SaveSnapshot()
+		}
+
+		for _, shard := range cache.shards {
+			shard.mutex.Lock()
+			shard.closed = true
+
+			// Журнал закрывается до удаления значений, поэтому удаление при закрытии в него не попадает
+			if cache.journal == nil {
+				shard.reset()
+			}
+
+			shard.mutex.Unlock()
+		}
+
+		if cache.journal != nil {
+			cache.compacting.Lock()
+			_ = cache.journal.close()
+			cache.compacting.Unlock()
+
+			for _, shard := range cache.shards {
+				shard.mutex.Lock()
+				shard.journal = nil
+				shard.reset()
+				shard.mutex.Unlock()
+			}
+		}
+
+		// Публикуем пустые копии сегментов, иначе `Get` продолжил бы читать значения закрытого кэша
+		for _, shard := range cache.shards {
+			if shard.view != nil {
+				shard.publishView()
+			}
+		}
+
+		// Сигнализируем сборщику мусора о необходимости завершения
+		close(cache.stop)
+
+		// Записываем в хранилище изменения, еще не сохраненные отложенной записью
+		if cache.writeBehind != nil {
+			_ = cache.Flush()
+		}
+	})
+}
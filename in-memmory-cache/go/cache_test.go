@@ -0,0 +1,270 @@
+package cache_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachetest"
+)
+
+// Функция создания кэша профилей, закрываемого по завершении теста
+func newProfiles(t *testing.T, opts ...cache.Option) *cache.ProfileCache {
+	t.Helper()
+
+	profiles, err := cache.New(opts...)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(profiles.Close)
+
+	return profiles
+}
+
+func TestGetReturnsNothingAfterTTL(t *testing.T) {
+	clock := cachetest.NewFakeClock(time.Now())
+
+	profiles := newProfiles(t, cache.WithClock(clock), cache.WithTTL(time.Minute), cache.WithoutBackgroundGC())
+
+	profiles.Set(&cache.Profile{UUID: "user-1", Name: "Alice"})
+
+	clock.Advance(59 * time.Second)
+
+	if profile, ok := profiles.Get("user-1"); !ok || profile.Name != "Alice" {
+		t.Fatalf("expected profile before ttl, got %v, %v", profile, ok)
+	}
+
+	clock.Advance(2 * time.Second)
+
+	if profile, ok := profiles.Get("user-1"); ok || profile != nil {
+		t.Fatalf("expected nil profile after ttl, got %v", profile)
+	}
+
+	if _, err := profiles.GetE("user-1"); !errors.Is(err, cache.ErrNotFound) && !errors.Is(err, cache.ErrExpired) {
+		t.Fatalf("expected not found or expired, got %v", err)
+	}
+}
+
+// Функция ожидания условия, которое выполняет фоновая горутина кэша
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBackgroundGCRemovesExpired(t *testing.T) {
+	clock := cachetest.NewFakeClock(time.Now())
+
+	profiles := newProfiles(t, cache.WithClock(clock), cache.WithTTL(time.Minute), cache.WithCleanupInterval(time.Second))
+
+	profiles.Set(&cache.Profile{UUID: "user-1"})
+	profiles.SetWithTTL(&cache.Profile{UUID: "user-2"}, time.Hour)
+
+	clock.Advance(2 * time.Minute)
+
+	eventually(t, func() bool { return profiles.Stats().Expired == 1 })
+
+	if profiles.Len() != 1 {
+		t.Fatalf("expected only the long-lived profile to remain, got %d", profiles.Len())
+	}
+
+	if _, ok := profiles.Get("user-2"); !ok {
+		t.Fatal("expected long-lived profile to remain")
+	}
+}
+
+func TestAddAndReplace(t *testing.T) {
+	profiles := newProfiles(t, cache.WithoutBackgroundGC())
+
+	if err := profiles.Replace(&cache.Profile{UUID: "user-1"}); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := profiles.Add(&cache.Profile{UUID: "user-1", Name: "Alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := profiles.Add(&cache.Profile{UUID: "user-1", Name: "Bob"}); !errors.Is(err, cache.ErrExists) {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+
+	if err := profiles.Replace(&cache.Profile{UUID: "user-1", Name: "Carol"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if profile, _ := profiles.Get("user-1"); profile.Name != "Carol" {
+		t.Fatalf("expected replaced profile, got %v", profile)
+	}
+}
+
+func TestOnEvictedReasons(t *testing.T) {
+	clock := cachetest.NewFakeClock(time.Now())
+
+	var (
+		mutex   sync.Mutex
+		reasons = map[string][]cache.EvictionReason{}
+	)
+
+	profiles := newProfiles(t,
+		cache.WithClock(clock),
+		cache.WithTTL(time.Minute),
+		cache.WithoutBackgroundGC(),
+		cache.WithMaxEntries(2),
+		cache.WithOnEvicted(func(key string, _ *cache.Profile, reason cache.EvictionReason) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			reasons[key] = append(reasons[key], reason)
+		}),
+	)
+
+	profiles.Set(&cache.Profile{UUID: "replaced"})
+	profiles.Set(&cache.Profile{UUID: "replaced"})
+	profiles.Delete("replaced")
+
+	profiles.Set(&cache.Profile{UUID: "evicted"})
+	profiles.Set(&cache.Profile{UUID: "expired"})
+	profiles.Set(&cache.Profile{UUID: "cleared"})
+
+	clock.Advance(2 * time.Minute)
+	profiles.DeleteExpired()
+
+	profiles.Set(&cache.Profile{UUID: "cleared"})
+	profiles.Clear()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	expected := map[string][]cache.EvictionReason{
+		"replaced": {cache.EvictedReplaced, cache.EvictedDeleted},
+		"evicted":  {cache.EvictedCapacity},
+		"expired":  {cache.EvictedExpired},
+		"cleared":  {cache.EvictedExpired, cache.EvictedCleared},
+	}
+
+	if fmt.Sprint(reasons) != fmt.Sprint(expected) {
+		t.Fatalf("expected reasons %v, got %v", expected, reasons)
+	}
+}
+
+func TestDeleteExpiredWithoutBackgroundGC(t *testing.T) {
+	clock := cachetest.NewFakeClock(time.Now())
+
+	profiles := newProfiles(t, cache.WithClock(clock), cache.WithTTL(time.Minute), cache.WithoutBackgroundGC())
+
+	for i := range 10 {
+		profiles.Set(&cache.Profile{UUID: fmt.Sprint("user-", i)})
+	}
+
+	clock.Advance(2 * time.Minute)
+
+	if n := profiles.DeleteExpired(); n != 10 {
+		t.Fatalf("expected 10 expired profiles, got %d", n)
+	}
+
+	if profiles.Len() != 0 {
+		t.Fatalf("expected empty cache, got %d", profiles.Len())
+	}
+}
+
+func TestStatsWindow(t *testing.T) {
+	clock := cachetest.NewFakeClock(time.Now())
+
+	profiles := newProfiles(t, cache.WithClock(clock), cache.WithStatsWindow(), cache.WithoutBackgroundGC())
+
+	profiles.Set(&cache.Profile{UUID: "user-1"})
+	profiles.Get("user-1")
+	profiles.Get("user-2")
+
+	if stats := profiles.StatsWindow(time.Minute); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("expected one hit and one miss in the window, got %+v", stats)
+	}
+
+	// Интервалы по 10 секунд сменяет фоновая горутина, а сдвиг часов сразу на несколько интервалов
+	// срабатывает один раз, поэтому часы сдвигаются по одному интервалу, пока чтения не выйдут из окна
+	eventually(t, func() bool {
+		clock.Advance(10 * time.Second)
+
+		return profiles.StatsWindow(time.Minute).Hits == 0
+	})
+
+	if stats := profiles.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("expected lifetime counters to remain, got %+v", stats)
+	}
+}
+
+func TestStatsWindowDisabledByDefault(t *testing.T) {
+	profiles := newProfiles(t, cache.WithoutBackgroundGC())
+
+	profiles.Set(&cache.Profile{UUID: "user-1"})
+	profiles.Get("user-1")
+
+	if stats := profiles.StatsWindow(time.Minute); stats.Hits != 0 || stats.Entries != 1 {
+		t.Fatalf("expected only entries without WithStatsWindow, got %+v", stats)
+	}
+}
+
+func TestSetDefaultTTLShiftsOnlyDefaultTTLEntries(t *testing.T) {
+	clock := cachetest.NewFakeClock(time.Now())
+
+	values := newValues(t, cache.WithClock(clock), cache.WithTTL(time.Minute))
+
+	values.Set("default", 1)
+	values.Set("updated", 2)
+	values.Set("swapped", 3)
+
+	// Явное время жизни, равное времени жизни по умолчанию, с ним не связано
+	values.SetWithTTL("explicit", 4, time.Minute)
+
+	// Перезапись с сохранением времени жизни сохраняет и его связь с временем жизни по умолчанию
+	values.Update("updated", func(value int) int { return value + 1 })
+
+	if _, version, ok := values.GetWithVersion("swapped"); !ok || values.CompareAndSwap("swapped", version, 4) != nil {
+		t.Fatal("expected CompareAndSwap to replace the value")
+	}
+
+	if err := values.SetDefaultTTL(2*time.Minute, true); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]time.Duration{
+		"default":  2 * time.Minute,
+		"updated":  2 * time.Minute,
+		"swapped":  2 * time.Minute,
+		"explicit": time.Minute,
+	}
+
+	for key, ttl := range expected {
+		if remaining, ok := values.TTL(key); !ok || remaining != ttl {
+			t.Fatalf("expected %q to expire in %s, got %s, %v", key, ttl, remaining, ok)
+		}
+	}
+
+	// Значение, записанное после изменения, получает новое время жизни и не сдвигается повторно
+	values.Set("later", 5)
+
+	if err := values.SetDefaultTTL(3*time.Minute, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining, _ := values.TTL("later"); remaining != 3*time.Minute {
+		t.Fatalf("expected the later entry to follow the default ttl, got %s", remaining)
+	}
+
+	if remaining, _ := values.TTL("explicit"); remaining != time.Minute {
+		t.Fatalf("expected the explicit ttl to stay unchanged, got %s", remaining)
+	}
+}
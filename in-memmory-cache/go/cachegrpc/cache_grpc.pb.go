@@ -0,0 +1,338 @@
+// Сервис кэша профилей для запуска кэша отдельным процессом (sidecar).
+//
+// Сервер и сгенерированный клиент находятся в отдельном модуле golang-cache/cachegrpc,
+// поэтому основной модуль кэша не зависит от gRPC и protobuf. Код генерируется
+// в каталоге cachegrpc командой go generate (см. server.go)
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: cache.proto
+
+package cachegrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ProfileCache_Get_FullMethodName       = "/cache.v1.ProfileCache/Get"
+	ProfileCache_Set_FullMethodName       = "/cache.v1.ProfileCache/Set"
+	ProfileCache_Delete_FullMethodName    = "/cache.v1.ProfileCache/Delete"
+	ProfileCache_Stats_FullMethodName     = "/cache.v1.ProfileCache/Stats"
+	ProfileCache_Watch_FullMethodName     = "/cache.v1.ProfileCache/Watch"
+	ProfileCache_Replicate_FullMethodName = "/cache.v1.ProfileCache/Replicate"
+)
+
+// ProfileCacheClient is the client API for ProfileCache service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ProfileCacheClient interface {
+	// Получение профиля по UUID. Отсутствующий профиль возвращается со статусом NOT_FOUND
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	// Запись профиля. Без ttl используется время жизни кэша
+	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error)
+	// Удаление профиля по UUID
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// Снимок статистики кэша
+	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	// Поток удалений профилей с причиной удаления
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchEvent], error)
+	// Поток репликации для реплики (Follow): полная копия актуальных профилей,
+	// затем записи, удаления и очистки
+	Replicate(ctx context.Context, in *ReplicateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReplicationRecord], error)
+}
+
+type profileCacheClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProfileCacheClient(cc grpc.ClientConnInterface) ProfileCacheClient {
+	return &profileCacheClient{cc}
+}
+
+func (c *profileCacheClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, ProfileCache_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileCacheClient) Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetResponse)
+	err := c.cc.Invoke(ctx, ProfileCache_Set_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileCacheClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, ProfileCache_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileCacheClient) Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, ProfileCache_Stats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileCacheClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ProfileCache_ServiceDesc.Streams[0], ProfileCache_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, WatchEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ProfileCache_WatchClient = grpc.ServerStreamingClient[WatchEvent]
+
+func (c *profileCacheClient) Replicate(ctx context.Context, in *ReplicateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReplicationRecord], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ProfileCache_ServiceDesc.Streams[1], ProfileCache_Replicate_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ReplicateRequest, ReplicationRecord]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ProfileCache_ReplicateClient = grpc.ServerStreamingClient[ReplicationRecord]
+
+// ProfileCacheServer is the server API for ProfileCache service.
+// All implementations must embed UnimplementedProfileCacheServer
+// for forward compatibility.
+type ProfileCacheServer interface {
+	// Получение профиля по UUID. Отсутствующий профиль возвращается со статусом NOT_FOUND
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	// Запись профиля. Без ttl используется время жизни кэша
+	Set(context.Context, *SetRequest) (*SetResponse, error)
+	// Удаление профиля по UUID
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	// Снимок статистики кэша
+	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
+	// Поток удалений профилей с причиной удаления
+	Watch(*WatchRequest, grpc.ServerStreamingServer[WatchEvent]) error
+	// Поток репликации для реплики (Follow): полная копия актуальных профилей,
+	// затем записи, удаления и очистки
+	Replicate(*ReplicateRequest, grpc.ServerStreamingServer[ReplicationRecord]) error
+	mustEmbedUnimplementedProfileCacheServer()
+}
+
+// UnimplementedProfileCacheServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedProfileCacheServer struct{}
+
+func (UnimplementedProfileCacheServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedProfileCacheServer) Set(context.Context, *SetRequest) (*SetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
+}
+func (UnimplementedProfileCacheServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedProfileCacheServer) Stats(context.Context, *StatsRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
+func (UnimplementedProfileCacheServer) Watch(*WatchRequest, grpc.ServerStreamingServer[WatchEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedProfileCacheServer) Replicate(*ReplicateRequest, grpc.ServerStreamingServer[ReplicationRecord]) error {
+	return status.Errorf(codes.Unimplemented, "method Replicate not implemented")
+}
+func (UnimplementedProfileCacheServer) mustEmbedUnimplementedProfileCacheServer() {}
+func (UnimplementedProfileCacheServer) testEmbeddedByValue()                      {}
+
+// UnsafeProfileCacheServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProfileCacheServer will
+// result in compilation errors.
+type UnsafeProfileCacheServer interface {
+	mustEmbedUnimplementedProfileCacheServer()
+}
+
+func RegisterProfileCacheServer(s grpc.ServiceRegistrar, srv ProfileCacheServer) {
+	// If the following call pancis, it indicates UnimplementedProfileCacheServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ProfileCache_ServiceDesc, srv)
+}
+
+func _ProfileCache_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileCacheServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileCache_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileCacheServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileCache_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileCacheServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileCache_Set_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileCacheServer).Set(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileCache_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileCacheServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileCache_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileCacheServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileCache_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileCacheServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileCache_Stats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileCacheServer).Stats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileCache_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProfileCacheServer).Watch(m, &grpc.GenericServerStream[WatchRequest, WatchEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ProfileCache_WatchServer = grpc.ServerStreamingServer[WatchEvent]
+
+func _ProfileCache_Replicate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReplicateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProfileCacheServer).Replicate(m, &grpc.GenericServerStream[ReplicateRequest, ReplicationRecord]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ProfileCache_ReplicateServer = grpc.ServerStreamingServer[ReplicationRecord]
+
+// ProfileCache_ServiceDesc is the grpc.ServiceDesc for ProfileCache service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ProfileCache_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cache.v1.ProfileCache",
+	HandlerType: (*ProfileCacheServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _ProfileCache_Get_Handler,
+		},
+		{
+			MethodName: "Set",
+			Handler:    _ProfileCache_Set_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _ProfileCache_Delete_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _ProfileCache_Stats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _ProfileCache_Watch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Replicate",
+			Handler:       _ProfileCache_Replicate_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cache.proto",
+}
@@ -0,0 +1,96 @@
+package cachegrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	cache "golang-cache"
+)
+
+/*
+ * Функция преобразования профиля в сообщение protobuf. Значения заказов кодируются в JSON, поскольку
+ * в Go они имеют тип interface{}. Нулевое время заказа передается без значения
+ */
+func ProfileToProto(profile *cache.Profile) (*Profile, error) {
+	message := &Profile{
+		Uuid:   profile.UUID,
+		Name:   profile.Name,
+		Orders: make([]*Order, 0, len(profile.Orders)),
+	}
+
+	for _, order := range profile.Orders {
+		if order == nil {
+			return nil, fmt.Errorf("cachegrpc: profile %q has a nil order", profile.UUID)
+		}
+
+		value, err := json.Marshal(order.Value)
+
+		if err != nil {
+			return nil, fmt.Errorf("cachegrpc: encode order %q value: %w", order.UUID, err)
+		}
+
+		message.Orders = append(message.Orders, &Order{
+			Uuid:      order.UUID,
+			ValueJson: value,
+			CreatedAt: timestampToProto(order.CreatedAt),
+			UpdatedAt: timestampToProto(order.UpdatedAt),
+		})
+	}
+
+	return message, nil
+}
+
+/*
+ * Функция преобразования сообщения protobuf в профиль. Значения заказов декодируются из JSON
+ * так же, как в `cachehttp`: числа становятся float64, а объекты - map[string]interface{}
+ */
+func ProfileFromProto(message *Profile) (*cache.Profile, error) {
+	profile := &cache.Profile{
+		UUID:   message.GetUuid(),
+		Name:   message.GetName(),
+		Orders: make([]*cache.Order, 0, len(message.GetOrders())),
+	}
+
+	for _, order := range message.GetOrders() {
+		if order == nil {
+			return nil, fmt.Errorf("cachegrpc: profile %q has a nil order", profile.UUID)
+		}
+
+		var value interface{}
+
+		if len(order.GetValueJson()) > 0 {
+			if err := json.Unmarshal(order.GetValueJson(), &value); err != nil {
+				return nil, fmt.Errorf("cachegrpc: decode order %q value: %w", order.GetUuid(), err)
+			}
+		}
+
+		profile.Orders = append(profile.Orders, &cache.Order{
+			UUID:      order.GetUuid(),
+			Value:     value,
+			CreatedAt: timestampFromProto(order.GetCreatedAt()),
+			UpdatedAt: timestampFromProto(order.GetUpdatedAt()),
+		})
+	}
+
+	return profile, nil
+}
+
+func timestampToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
+
+// `AsTime` возвращает для отсутствующего значения начало эпохи Unix, а не нулевое время
+func timestampFromProto(timestamp *timestamppb.Timestamp) time.Time {
+	if timestamp == nil {
+		return time.Time{}
+	}
+
+	return timestamp.AsTime()
+}
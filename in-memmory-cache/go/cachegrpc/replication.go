@@ -0,0 +1,124 @@
+package cachegrpc
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	cache "golang-cache"
+)
+
+/*
+ * Функция потока репликации основного экземпляра (`cache.Replicate`). Поток завершается при отмене
+ * запроса репликой, а реплика, отставшая от потока, отключается со статусом ABORTED и при переподключении
+ * получает полную копию заново
+ */
+func (server *Server) Replicate(request *ReplicateRequest, stream grpc.ServerStreamingServer[ReplicationRecord]) error {
+	err := server.profiles.Replicate(stream.Context(), func(record cache.ReplicationRecord[string, *cache.Profile]) error {
+		message, err := recordToProto(record)
+
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		return stream.Send(message)
+	})
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return nil
+	case errors.Is(err, cache.ErrReplicaLagged):
+		return status.Error(codes.Aborted, err.Error())
+	default:
+		return err
+	}
+}
+
+/*
+ * Функция работы кэша `profiles` репликой основного экземпляра, к серверу которого подключен `client`
+ * (`cache.Follow`). Реплика применяет поток, пока не будет отменен `ctx`, и переподключается после
+ * разрыва соединения. Возвращает ошибку `ctx`
+ */
+func Follow(ctx context.Context, profiles *cache.ProfileCache, client ProfileCacheClient) error {
+	return profiles.Follow(ctx, func(ctx context.Context) (cache.ReplicationReceiver[string, *cache.Profile], error) {
+		stream, err := client.Replicate(ctx, &ReplicateRequest{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return receiver{stream}, nil
+	})
+}
+
+// Источник записей репликации поверх клиентского потока `Replicate`
+type receiver struct {
+	stream grpc.ServerStreamingClient[ReplicationRecord]
+}
+
+func (receiver receiver) Recv() (cache.ReplicationRecord[string, *cache.Profile], error) {
+	message, err := receiver.stream.Recv()
+
+	if err != nil {
+		return cache.ReplicationRecord[string, *cache.Profile]{}, err
+	}
+
+	return recordFromProto(message)
+}
+
+func recordToProto(record cache.ReplicationRecord[string, *cache.Profile]) (*ReplicationRecord, error) {
+	message := &ReplicationRecord{Uuid: record.Key}
+
+	switch record.Op {
+	case cache.ReplicationSet:
+		profile, err := ProfileToProto(record.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		message.Op = ReplicationRecord_OP_SET
+		message.Profile = profile
+		message.Ttl = durationpb.New(record.TTL)
+		message.ExpireAt = timestamppb.New(record.ExpireAt)
+	case cache.ReplicationDelete:
+		message.Op = ReplicationRecord_OP_DELETE
+	case cache.ReplicationClear:
+		message.Op = ReplicationRecord_OP_CLEAR
+	default:
+		message.Op = ReplicationRecord_OP_HEARTBEAT
+	}
+
+	return message, nil
+}
+
+func recordFromProto(message *ReplicationRecord) (cache.ReplicationRecord[string, *cache.Profile], error) {
+	record := cache.ReplicationRecord[string, *cache.Profile]{Key: message.GetUuid()}
+
+	switch message.GetOp() {
+	case ReplicationRecord_OP_SET:
+		profile, err := ProfileFromProto(message.GetProfile())
+
+		if err != nil {
+			return record, err
+		}
+
+		record.Op = cache.ReplicationSet
+		record.Value = profile
+		record.TTL = message.GetTtl().AsDuration()
+		record.ExpireAt = timestampFromProto(message.GetExpireAt())
+	case ReplicationRecord_OP_DELETE:
+		record.Op = cache.ReplicationDelete
+	case ReplicationRecord_OP_CLEAR:
+		record.Op = cache.ReplicationClear
+	default:
+		record.Op = cache.ReplicationHeartbeat
+	}
+
+	return record, nil
+}
@@ -0,0 +1,90 @@
+package cachegrpc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachegrpc"
+)
+
+// Функция ожидания условия на реплике, которая применяет поток асинхронно
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFollowReplicatesProfiles(t *testing.T) {
+	primary := newProfiles(t)
+	replica := newProfiles(t)
+
+	// Профиль, записанный до подключения реплики, передается полной копией
+	primary.SetWithTTL(&cache.Profile{
+		UUID:   "user-1",
+		Name:   "Alice",
+		Orders: []*cache.Order{{UUID: "order-1", Value: map[string]interface{}{"sum": 10.5}}},
+	}, time.Hour)
+
+	server, err := cachegrpc.NewServer(primary)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := dial(t, server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+
+	go func() { done <- cachegrpc.Follow(ctx, replica, client) }()
+
+	defer func() {
+		cancel()
+
+		if err := <-done; err != context.Canceled {
+			t.Errorf("expected context.Canceled from Follow, got %v", err)
+		}
+	}()
+
+	eventually(t, func() bool { _, ok := replica.Peek("user-1"); return ok })
+
+	profile, _ := replica.Peek("user-1")
+
+	if profile.Name != "Alice" || len(profile.Orders) != 1 {
+		t.Fatalf("unexpected replicated profile %+v", profile)
+	}
+
+	if value, ok := profile.Orders[0].Value.(map[string]interface{}); !ok || value["sum"] != 10.5 {
+		t.Fatalf("unexpected replicated order value %#v", profile.Orders[0].Value)
+	}
+
+	if ttl, ok := replica.TTL("user-1"); !ok || ttl <= 0 || ttl > time.Hour {
+		t.Fatalf("expected replicated ttl within an hour, got %s", ttl)
+	}
+
+	// Изменения после копии передаются потоком
+	primary.Set(&cache.Profile{UUID: "user-2", Name: "Bob"})
+	primary.Delete("user-1")
+
+	eventually(t, func() bool {
+		_, deleted := replica.Peek("user-1")
+		_, added := replica.Peek("user-2")
+
+		return !deleted && added
+	})
+
+	primary.Clear()
+
+	eventually(t, func() bool { return replica.Len() == 0 })
+}
@@ -0,0 +1,171 @@
+/*
+ * Пакет gRPC-сервиса кэша профилей (`cache.proto`) для запуска кэша отдельным процессом. Содержит
+ * сервер поверх `cache.ProfileCache` и сгенерированный клиент `ProfileCacheClient`. Пакет вынесен
+ * в отдельный модуль, поэтому основной модуль кэша не зависит от gRPC и protobuf
+ */
+package cachegrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative cache.proto
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	cache "golang-cache"
+)
+
+/*
+ * Функциональная опция сервера. Опция проверяет переданные значения
+ * и возвращает ошибку, если они некорректны
+ */
+type Option func(*options) error
+
+type options struct {
+	watcher *Watcher
+}
+
+/*
+ * Опция рассылки удалений профилей подписчикам `Watch`. Без нее `Watch` возвращает статус
+ * FAILED_PRECONDITION, поскольку причины удалений кэш передает только в `cache.WithOnEvicted`
+ */
+func WithWatcher(watcher *Watcher) Option {
+	return func(o *options) error {
+		if watcher == nil {
+			return fmt.Errorf("cachegrpc: watcher must not be nil")
+		}
+
+		o.watcher = watcher
+
+		return nil
+	}
+}
+
+// Сервер gRPC-сервиса `ProfileCache`, регистрируется через `RegisterProfileCacheServer`
+type Server struct {
+	UnimplementedProfileCacheServer
+
+	profiles *cache.ProfileCache
+	watcher  *Watcher
+}
+
+/*
+ * Функция-конструктор сервера. Сервер не ограничивает доступ, поэтому его следует подключать
+ * к порту, доступному только сервисам внутри инфраструктуры, или к `grpc.Server` с авторизацией
+ */
+func NewServer(profiles *cache.ProfileCache, opts ...Option) (*Server, error) {
+	var o options
+
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Server{
+		profiles: profiles,
+		watcher:  o.watcher,
+	}, nil
+}
+
+func (server *Server) Get(ctx context.Context, request *GetRequest) (*GetResponse, error) {
+	profile, ok := server.profiles.Get(request.GetUuid())
+
+	if !ok {
+		return nil, status.Error(codes.NotFound, cache.ErrNotFound.Error())
+	}
+
+	message, err := ProfileToProto(profile)
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	response := &GetResponse{Profile: message}
+
+	// Время жизни читается по часам кэша (`WithClock`), а для профиля без истечения не задается
+	if ttl, ok := server.profiles.TTL(request.GetUuid()); ok && ttl != cache.NoExpiration {
+		response.Ttl = durationpb.New(ttl)
+	}
+
+	return response, nil
+}
+
+func (server *Server) Set(ctx context.Context, request *SetRequest) (*SetResponse, error) {
+	if request.GetProfile().GetUuid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "profile uuid is required")
+	}
+
+	// Профиль и время жизни проверяются целиком до записи, поэтому некорректный запрос не изменяет кэш
+	profile, err := ProfileFromProto(request.GetProfile())
+
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if request.Ttl == nil {
+		server.profiles.Set(profile)
+
+		return &SetResponse{}, nil
+	}
+
+	if err := request.Ttl.CheckValid(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ttl := request.Ttl.AsDuration()
+
+	if ttl <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "ttl must be positive, got %s", ttl)
+	}
+
+	server.profiles.SetWithTTL(profile, ttl)
+
+	return &SetResponse{}, nil
+}
+
+func (server *Server) Delete(ctx context.Context, request *DeleteRequest) (*DeleteResponse, error) {
+	return &DeleteResponse{Deleted: server.profiles.Delete(request.GetUuid())}, nil
+}
+
+func (server *Server) Stats(ctx context.Context, request *StatsRequest) (*StatsResponse, error) {
+	stats := server.profiles.Stats()
+
+	return &StatsResponse{
+		Hits:      stats.Hits,
+		Misses:    stats.Misses,
+		Expired:   stats.Expired,
+		Evictions: stats.Evictions,
+		Replaced:  stats.Replaced,
+		Deleted:   stats.Deleted,
+		Cleared:   stats.Cleared,
+		Entries:   int64(stats.Entries),
+	}, nil
+}
+
+/*
+ * Функция потока удалений профилей. Поток завершается при отмене запроса клиентом. Клиент, не успевающий
+ * читать поток, пропускает события, чтобы не задерживать удаление значений из кэша
+ */
+func (server *Server) Watch(request *WatchRequest, stream ProfileCache_WatchServer) error {
+	if server.watcher == nil {
+		return status.Error(codes.FailedPrecondition, "cachegrpc: watch requires WithWatcher")
+	}
+
+	events := server.watcher.subscribe()
+	defer server.watcher.unsubscribe(events)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
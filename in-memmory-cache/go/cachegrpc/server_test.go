@@ -0,0 +1,248 @@
+package cachegrpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	cache "golang-cache"
+	"golang-cache/cachegrpc"
+)
+
+// Функция запуска сервера в памяти процесса и подключения к нему клиента
+func dial(t *testing.T, server *cachegrpc.Server) cachegrpc.ProfileCacheClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+
+	grpcServer := grpc.NewServer()
+	cachegrpc.RegisterProfileCacheServer(grpcServer, server)
+
+	go grpcServer.Serve(listener)
+
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return cachegrpc.NewProfileCacheClient(conn)
+}
+
+func newProfiles(t *testing.T, opts ...cache.Option) *cache.ProfileCache {
+	t.Helper()
+
+	profiles, err := cache.New(append(opts, cache.WithoutBackgroundGC())...)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(profiles.Close)
+
+	return profiles
+}
+
+func TestServerSetGetDelete(t *testing.T) {
+	profiles := newProfiles(t)
+
+	server, err := cachegrpc.NewServer(profiles)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := dial(t, server)
+	ctx := context.Background()
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	profile, err := cachegrpc.ProfileToProto(&cache.Profile{
+		UUID: "user-1",
+		Name: "Alice",
+		Orders: []*cache.Order{
+			{UUID: "order-1", Value: map[string]interface{}{"sum": 10.5}, CreatedAt: created},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Set(ctx, &cachegrpc.SetRequest{Profile: profile, Ttl: durationpb.New(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := client.Get(ctx, &cachegrpc.GetRequest{Uuid: "user-1"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl := response.GetTtl().AsDuration(); ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("unexpected ttl %s", ttl)
+	}
+
+	got, err := cachegrpc.ProfileFromProto(response.GetProfile())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Name != "Alice" || len(got.Orders) != 1 {
+		t.Fatalf("unexpected profile %+v", got)
+	}
+
+	order := got.Orders[0]
+
+	if order.UUID != "order-1" || !order.CreatedAt.Equal(created) || !order.UpdatedAt.IsZero() {
+		t.Fatalf("unexpected order %+v", order)
+	}
+
+	if value, ok := order.Value.(map[string]interface{}); !ok || value["sum"] != 10.5 {
+		t.Fatalf("unexpected order value %#v", order.Value)
+	}
+
+	deleted, err := client.Delete(ctx, &cachegrpc.DeleteRequest{Uuid: "user-1"})
+
+	if err != nil || !deleted.GetDeleted() {
+		t.Fatalf("expected profile to be deleted, got %v, %v", deleted, err)
+	}
+
+	if _, err := client.Get(ctx, &cachegrpc.GetRequest{Uuid: "user-1"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NOT_FOUND, got %v", err)
+	}
+
+	stats, err := client.Stats(ctx, &cachegrpc.StatsRequest{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.GetHits() != 1 || stats.GetMisses() != 1 || stats.GetDeleted() != 1 || stats.GetEntries() != 0 {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+}
+
+func TestServerSetRejectsInvalidRequests(t *testing.T) {
+	profiles := newProfiles(t)
+
+	server, err := cachegrpc.NewServer(profiles)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := dial(t, server)
+	ctx := context.Background()
+
+	for name, request := range map[string]*cachegrpc.SetRequest{
+		"no profile":   {},
+		"no uuid":      {Profile: &cachegrpc.Profile{Name: "Alice"}},
+		"bad value":    {Profile: &cachegrpc.Profile{Uuid: "user-1", Orders: []*cachegrpc.Order{{Uuid: "order-1", ValueJson: []byte("{")}}}},
+		"negative ttl": {Profile: &cachegrpc.Profile{Uuid: "user-1"}, Ttl: durationpb.New(-time.Second)},
+	} {
+		if _, err := client.Set(ctx, request); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: expected INVALID_ARGUMENT, got %v", name, err)
+		}
+	}
+
+	if profiles.Len() != 0 {
+		t.Fatalf("invalid requests must not change the cache, got %d entries", profiles.Len())
+	}
+}
+
+func TestServerWatch(t *testing.T) {
+	watcher := cachegrpc.NewWatcher()
+
+	profiles := newProfiles(t, cache.WithOnEvicted(watcher.OnEvicted))
+
+	server, err := cachegrpc.NewServer(profiles, cachegrpc.WithWatcher(watcher))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := dial(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &cachegrpc.WatchRequest{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Подписка оформляется сервером асинхронно, поэтому удаления повторяются до первого события
+	profiles.Set(&cache.Profile{UUID: "user-1"})
+
+	events := make(chan *cachegrpc.WatchEvent)
+
+	go func() {
+		for {
+			event, err := stream.Recv()
+
+			if err != nil {
+				close(events)
+
+				return
+			}
+
+			events <- event
+		}
+	}()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("watch stream closed")
+			}
+
+			if event.GetUuid() != "user-1" || event.GetReason() != cachegrpc.WatchEvent_REASON_REPLACED {
+				t.Fatalf("unexpected event %v", event)
+			}
+
+			return
+		case <-ticker.C:
+			profiles.Set(&cache.Profile{UUID: "user-1"})
+		}
+	}
+}
+
+func TestServerWatchRequiresWatcher(t *testing.T) {
+	server, err := cachegrpc.NewServer(newProfiles(t))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := dial(t, server).Watch(context.Background(), &cachegrpc.WatchRequest{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := stream.Recv(); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FAILED_PRECONDITION, got %v", err)
+	}
+}
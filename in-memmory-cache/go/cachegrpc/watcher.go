@@ -0,0 +1,83 @@
+package cachegrpc
+
+import (
+	"sync"
+
+	cache "golang-cache"
+)
+
+// Размер очереди событий подписчика `Watch`. При переполнении новые события подписчика отбрасываются
+const watchBuffer = 1024
+
+/*
+ * Рассылка удалений профилей подписчикам `Watch`. Удаления с причиной передаются кэшем только
+ * в функцию обратного вызова, поэтому рассылка создается до кэша и подключается к нему дважды:
+ *
+ *	watcher := cachegrpc.NewWatcher()
+ *	profiles, err := cache.New(cache.WithOnEvicted(watcher.OnEvicted))
+ *	server, err := cachegrpc.NewServer(profiles, cachegrpc.WithWatcher(watcher))
+ */
+type Watcher struct {
+	mutex       sync.Mutex
+	subscribers map[chan *WatchEvent]struct{}
+}
+
+// Функция-конструктор рассылки удалений
+func NewWatcher() *Watcher {
+	return &Watcher{subscribers: make(map[chan *WatchEvent]struct{})}
+}
+
+/*
+ * Функция передачи удаления профиля подписчикам, передается в `cache.WithOnEvicted`. Кэш вызывает ее
+ * без удержания блокировки, а передача подписчикам не ожидает их, поэтому не задерживает операции кэша
+ */
+func (watcher *Watcher) OnEvicted(uuid string, profile *cache.Profile, reason cache.EvictionReason) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	if len(watcher.subscribers) == 0 {
+		return
+	}
+
+	event := &WatchEvent{Uuid: uuid, Reason: reasonToProto(reason)}
+
+	for events := range watcher.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+func (watcher *Watcher) subscribe() chan *WatchEvent {
+	events := make(chan *WatchEvent, watchBuffer)
+
+	watcher.mutex.Lock()
+	watcher.subscribers[events] = struct{}{}
+	watcher.mutex.Unlock()
+
+	return events
+}
+
+func (watcher *Watcher) unsubscribe(events chan *WatchEvent) {
+	watcher.mutex.Lock()
+	delete(watcher.subscribers, events)
+	watcher.mutex.Unlock()
+}
+
+func reasonToProto(reason cache.EvictionReason) WatchEvent_Reason {
+	switch reason {
+	case cache.EvictedExpired:
+		return WatchEvent_REASON_EXPIRED
+	case cache.EvictedCapacity:
+		return WatchEvent_REASON_CAPACITY
+	case cache.EvictedDeleted:
+		return WatchEvent_REASON_DELETED
+	case cache.EvictedReplaced:
+		return WatchEvent_REASON_REPLACED
+	case cache.EvictedCleared:
+		return WatchEvent_REASON_CLEARED
+	default:
+		return WatchEvent_REASON_UNSPECIFIED
+	}
+}
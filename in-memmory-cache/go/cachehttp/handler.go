@@ -0,0 +1,135 @@
+/*
+ * Пакет HTTP-интерфейса кэша профилей, позволяющий сервисам на других языках
+ * пользоваться тем же процессом кэша:
+ *
+ *   GET    /profiles/{uuid} - профиль в формате JSON, оставшееся время жизни в заголовке `X-Cache-TTL`
+ *   PUT    /profiles/{uuid} - запись профиля из тела запроса, время жизни можно задать заголовком `X-Cache-TTL`
+ *   DELETE /profiles/{uuid} - удаление профиля
+ *
+ * Время жизни в заголовке `X-Cache-TTL` задается в целых секундах
+ */
+package cachehttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	cache "golang-cache"
+)
+
+// Заголовок с временем жизни профиля в секундах
+const TTLHeader = "X-Cache-TTL"
+
+// Максимальный размер тела запроса записи профиля
+const maxBodySize = 1 << 20
+
+/*
+ * Функция создания HTTP-обработчика кэша профилей. Обработчик не ограничивает доступ, поэтому
+ * его следует подключать к порту, доступному только сервисам внутри инфраструктуры
+ */
+func NewHandler(profiles *cache.ProfileCache) http.Handler {
+	server := &server{profiles: profiles}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /profiles/{uuid}", server.get)
+	mux.HandleFunc("PUT /profiles/{uuid}", server.put)
+	mux.HandleFunc("DELETE /profiles/{uuid}", server.delete)
+
+	return mux
+}
+
+type server struct {
+	profiles *cache.ProfileCache
+}
+
+func (server *server) get(w http.ResponseWriter, r *http.Request) {
+	uuid := r.PathValue("uuid")
+
+	profile, ok := server.profiles.Get(uuid)
+
+	if !ok {
+		http.Error(w, cache.ErrNotFound.Error(), http.StatusNotFound)
+
+		return
+	}
+
+	// Время жизни читается по часам кэша (`WithClock`). Если профиль истек сразу после чтения,
+	// заголовок содержит ноль. Округляем вверх, чтобы профиль с временем жизни меньше секунды не выглядел истекшим
+	ttl, _ := server.profiles.TTL(uuid)
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+
+	// Профиль без истечения (`cache.NoExpiration`) имеет время жизни -1, как TTL в Redis
+	if ttl == cache.NoExpiration {
+		seconds = -1
+	}
+
+	w.Header().Set(TTLHeader, strconv.FormatInt(seconds, 10))
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(profile)
+}
+
+func (server *server) put(w http.ResponseWriter, r *http.Request) {
+	uuid := r.PathValue("uuid")
+
+	var profile cache.Profile
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&profile); err != nil {
+		http.Error(w, fmt.Sprintf("decode profile: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	// UUID в теле можно не указывать, но если он указан, то должен совпадать с адресом
+	if profile.UUID == "" {
+		profile.UUID = uuid
+	} else if profile.UUID != uuid {
+		http.Error(w, fmt.Sprintf("profile uuid %q does not match path %q", profile.UUID, uuid), http.StatusBadRequest)
+
+		return
+	}
+
+	// Профиль проверяется целиком до записи, поэтому некорректный запрос не изменяет кэш
+	if slices.Contains(profile.Orders, nil) {
+		http.Error(w, "profile orders must not be null", http.StatusBadRequest)
+
+		return
+	}
+
+	header := r.Header.Get(TTLHeader)
+
+	if header == "" {
+		server.profiles.Set(&profile)
+
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+
+	seconds, err := strconv.ParseInt(header, 10, 64)
+
+	if err != nil || seconds <= 0 {
+		http.Error(w, fmt.Sprintf("%s must be a positive number of seconds, got %q", TTLHeader, header), http.StatusBadRequest)
+
+		return
+	}
+
+	server.profiles.SetWithTTL(&profile, time.Duration(seconds)*time.Second)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *server) delete(w http.ResponseWriter, r *http.Request) {
+	if !server.profiles.Delete(r.PathValue("uuid")) {
+		http.Error(w, cache.ErrNotFound.Error(), http.StatusNotFound)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,138 @@
+package cachehttp_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachehttp"
+)
+
+// Функция запуска HTTP-сервера кэша профилей, останавливаемого по завершении теста
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	profiles, err := cache.New(cache.WithoutBackgroundGC())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(profiles.Close)
+
+	server := httptest.NewServer(cachehttp.NewHandler(profiles))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// Функция отправки запроса с необязательным временем жизни. Тело ответа закрывается по завершении теста
+func do(t *testing.T, method, url, body, ttl string) *http.Response {
+	t.Helper()
+
+	request, err := http.NewRequest(method, url, strings.NewReader(body))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl != "" {
+		request.Header.Set(cachehttp.TTLHeader, ttl)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { response.Body.Close() })
+
+	return response
+}
+
+func TestProfileLifecycle(t *testing.T) {
+	url := newServer(t).URL + "/profiles/user-1"
+
+	if response := do(t, http.MethodGet, url, "", ""); response.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing profile, got %d", response.StatusCode)
+	}
+
+	if response := do(t, http.MethodPut, url, `{"Name":"Alice","Orders":[{"UUID":"order-1"}]}`, "60"); response.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 on put, got %d", response.StatusCode)
+	}
+
+	response := do(t, http.MethodGet, url, "", "")
+
+	if response.StatusCode != http.StatusOK || response.Header.Get(cachehttp.TTLHeader) != "60" {
+		t.Fatalf("expected 200 with ttl 60, got %d and %q", response.StatusCode, response.Header.Get(cachehttp.TTLHeader))
+	}
+
+	var profile cache.Profile
+
+	if err := json.NewDecoder(response.Body).Decode(&profile); err != nil {
+		t.Fatal(err)
+	}
+
+	// UUID берется из адреса, если в теле он не указан
+	if profile.UUID != "user-1" || profile.Name != "Alice" || len(profile.Orders) != 1 {
+		t.Fatalf("expected stored profile, got %+v", profile)
+	}
+
+	if response := do(t, http.MethodDelete, url, "", ""); response.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 on delete, got %d", response.StatusCode)
+	}
+
+	if response := do(t, http.MethodDelete, url, "", ""); response.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 on repeated delete, got %d", response.StatusCode)
+	}
+}
+
+func TestPutWithoutTTLUsesDefault(t *testing.T) {
+	url := newServer(t).URL + "/profiles/user-1"
+
+	do(t, http.MethodPut, url, `{"Name":"Alice"}`, "")
+
+	expected := strconv.FormatInt(int64(cache.DefaultTTL/time.Second), 10)
+
+	if ttl := do(t, http.MethodGet, url, "", "").Header.Get(cachehttp.TTLHeader); ttl != expected {
+		t.Fatalf("expected default ttl %s, got %q", expected, ttl)
+	}
+}
+
+func TestInvalidPutIsRejected(t *testing.T) {
+	url := newServer(t).URL + "/profiles/user-1"
+
+	for name, step := range map[string]struct {
+		body string
+		ttl  string
+	}{
+		"uuid mismatch": {`{"UUID":"user-2"}`, ""},
+		"null order":    {`{"Orders":[null]}`, ""},
+		"malformed":     {`{"Name":`, ""},
+		"zero ttl":      {`{}`, "0"},
+		"invalid ttl":   {`{}`, "soon"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			response := do(t, http.MethodPut, url, step.body, step.ttl)
+
+			if response.StatusCode != http.StatusBadRequest {
+				message, _ := io.ReadAll(response.Body)
+
+				t.Fatalf("expected 400, got %d: %s", response.StatusCode, message)
+			}
+		})
+	}
+
+	// Отклоненный запрос не изменяет кэш
+	if response := do(t, http.MethodGet, url, "", ""); response.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected rejected puts to leave the cache unchanged, got %d", response.StatusCode)
+	}
+}
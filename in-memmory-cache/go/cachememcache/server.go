@@ -0,0 +1,484 @@
+/*
+ * Пакет сервера текстового протокола memcached поверх кэш-хранилища. Сервер поддерживает команды
+ * `get`, `gets`, `set`, `cas`, `delete`, `touch`, `version` и `quit`, поэтому существующие клиенты memcached
+ * на других языках могут пользоваться кэшем без изменения кода
+ */
+package cachememcache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	cache "golang-cache"
+)
+
+const (
+	// Максимальная длина ключа по протоколу memcached
+	maxKeyLength = 250
+
+	// Максимальный размер значения, как у memcached по умолчанию
+	maxValueSize = 1 << 20
+
+	// Максимальная длина строки команды вместе с `\r\n`. Более длинная строка не читается в память целиком
+	maxLineLength = 8 << 10
+
+	// Время истечения больше 30 дней протокол трактует как Unix-время, а не как количество секунд
+	relativeExpirationLimit = 60 * 60 * 24 * 30
+)
+
+// Ошибка чтения строки команды длиннее `maxLineLength`
+var errLineTooLong = errors.New("line too long")
+
+// Значение memcached: произвольные байты и флаги клиента, которые возвращаются без изменений
+type Item struct {
+	Flags uint32
+	Value []byte
+}
+
+/*
+ * Сервер протокола memcached. Время истечения `0` означает время жизни кэша, поскольку значения
+ * кэша всегда имеют ограниченное время жизни, а отрицательное время истечения удаляет значение.
+ * Абсолютное время истечения отсчитывается по часам кэша (`cache.WithClock`). Уникальное значение
+ * `gets` и `cas` - версия записи значения в кэше (`GetWithVersion`)
+ */
+type Server struct {
+	cache *cache.Cache[string, Item]
+}
+
+// Функция-конструктор сервера протокола memcached поверх кэша
+func NewServer(c *cache.Cache[string, Item]) *Server {
+	return &Server{cache: c}
+}
+
+/*
+ * Функция приема соединений. Каждое соединение обслуживается в отдельной горутине. Возвращает
+ * ошибку приема соединения, в том числе после закрытия `listener`
+ */
+func (server *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+
+		if err != nil {
+			return err
+		}
+
+		go server.ServeConn(conn)
+	}
+}
+
+// Функция обслуживания одного соединения до команды `quit`, закрытия соединения клиентом или ошибки ввода-вывода
+func (server *Server) ServeConn(conn net.Conn) {
+	defer conn.Close()
+
+	// Размер буфера чтения ограничивает длину строки команды
+	reader := bufio.NewReaderSize(conn, maxLineLength)
+	writer := bufio.NewWriter(conn)
+
+	for {
+		line, err := readLine(reader)
+
+		fields := strings.Fields(line)
+
+		switch {
+		case errors.Is(err, errLineTooLong):
+			writer.WriteString("CLIENT_ERROR line too long\r\n")
+		case err != nil:
+			return
+		case len(fields) == 0:
+			writer.WriteString("ERROR\r\n")
+		case fields[0] == "quit":
+			writer.Flush()
+
+			return
+		default:
+			if err := server.execute(fields, reader, writer); err != nil {
+				return
+			}
+		}
+
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+/*
+ * Функция чтения строки команды не длиннее буфера `reader`. Остаток слишком длинной строки пропускается
+ * до перевода строки без накопления в памяти, после чего возвращается `errLineTooLong`, и следующая
+ * команда читается с начала строки
+ */
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadSlice('\n')
+
+	if !errors.Is(err, bufio.ErrBufferFull) {
+		return string(line), err
+	}
+
+	for errors.Is(err, bufio.ErrBufferFull) {
+		_, err = reader.ReadSlice('\n')
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return "", errLineTooLong
+}
+
+// Ошибка формата команды, о которой сообщается клиенту без закрытия соединения
+type clientError string
+
+func (err clientError) Error() string {
+	return string(err)
+}
+
+/*
+ * Функция выполнения команды. Ошибка формата команды записывается клиенту как `CLIENT_ERROR`,
+ * а возвращается только ошибка ввода-вывода, после которой соединение закрывается
+ */
+func (server *Server) execute(fields []string, reader *bufio.Reader, writer *bufio.Writer) error {
+	var (
+		reply string
+		err   error
+	)
+
+	switch fields[0] {
+	case "get":
+		err = server.get(fields[1:], writer, false)
+	case "gets":
+		err = server.get(fields[1:], writer, true)
+	case "set":
+		reply, err = server.set(fields[1:], reader)
+	case "cas":
+		reply, err = server.cas(fields[1:], reader)
+	case "delete":
+		reply, err = server.delete(fields[1:])
+	case "touch":
+		reply, err = server.touch(fields[1:])
+	case "version":
+		reply = "VERSION golang-cache"
+	default:
+		reply = "ERROR"
+	}
+
+	var clientErr clientError
+
+	if errors.As(err, &clientErr) {
+		_, err = fmt.Fprintf(writer, "CLIENT_ERROR %s\r\n", clientErr)
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Пустой ответ означает, что клиент отказался от него аргументом `noreply`
+	if reply == "" {
+		return nil
+	}
+
+	_, err = writer.WriteString(reply + "\r\n")
+
+	return err
+}
+
+/*
+ * get <key>*, gets <key>*. `gets` дополнительно возвращает версию значения для `cas`, но, как и
+ * `GetWithVersion`, не продлевает время жизни и не учитывается политикой вытеснения
+ */
+func (server *Server) get(keys []string, writer *bufio.Writer, withVersion bool) error {
+	if len(keys) == 0 {
+		return clientError("bad command line format")
+	}
+
+	for _, key := range keys {
+		var (
+			item    Item
+			version uint64
+			ok      bool
+		)
+
+		if withVersion {
+			item, version, ok = server.cache.GetWithVersion(key)
+		} else {
+			item, ok = server.cache.Get(key)
+		}
+
+		if !ok {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(writer, "VALUE %s %d %d", key, item.Flags, len(item.Value)); err != nil {
+			return err
+		}
+
+		if withVersion {
+			fmt.Fprintf(writer, " %d", version)
+		}
+
+		writer.WriteString("\r\n")
+		writer.Write(item.Value)
+		writer.WriteString("\r\n")
+	}
+
+	_, err := writer.WriteString("END\r\n")
+
+	return err
+}
+
+// Аргументы команды записи, общие для `set` и `cas`
+type storage struct {
+	key     string
+	item    Item
+	ttl     time.Duration
+	noreply bool
+}
+
+/*
+ * Функция разбора команды записи с `count` обязательными аргументами и чтения блока данных. Возвращает
+ * пустой ответ и `nil` аргументы, если ответ уже определен (например слишком большое значение)
+ */
+func (server *Server) readStorage(args []string, count int, reader *bufio.Reader) (*storage, string, error) {
+	if len(args) != count && len(args) != count+1 {
+		return nil, "", clientError("bad command line format")
+	}
+
+	size, err := strconv.Atoi(args[3])
+
+	if err != nil || size < 0 {
+		return nil, "", clientError("bad data chunk")
+	}
+
+	// Данные читаются до проверки остальных аргументов, чтобы не принять их за следующую команду
+	if size > maxValueSize {
+		if _, err := reader.Discard(size + 2); err != nil {
+			return nil, "", err
+		}
+
+		return nil, "SERVER_ERROR object too large for cache", nil
+	}
+
+	data := make([]byte, size+2)
+
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, "", err
+	}
+
+	if string(data[size:]) != "\r\n" {
+		return nil, "", clientError("bad data chunk")
+	}
+
+	key := args[0]
+
+	if err := validateKey(key); err != nil {
+		return nil, "", err
+	}
+
+	flags, err := strconv.ParseUint(args[1], 10, 32)
+
+	if err != nil {
+		return nil, "", clientError("bad command line format")
+	}
+
+	ttl, err := parseExpiration(args[2], server.cache.Clock().Now())
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	noreply, err := parseNoreply(args, count)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &storage{key: key, item: Item{Flags: uint32(flags), Value: data[:size:size]}, ttl: ttl, noreply: noreply}, "", nil
+}
+
+// set <key> <flags> <exptime> <bytes> [noreply]
+func (server *Server) set(args []string, reader *bufio.Reader) (string, error) {
+	command, reply, err := server.readStorage(args, 4, reader)
+
+	if command == nil {
+		return reply, err
+	}
+
+	switch {
+	case command.ttl == 0:
+		server.cache.Set(command.key, command.item)
+	case command.ttl < 0:
+		server.cache.Delete(command.key)
+	default:
+		server.cache.SetWithTTL(command.key, command.item, command.ttl)
+	}
+
+	return command.reply("STORED"), nil
+}
+
+// cas <key> <flags> <exptime> <bytes> <cas unique> [noreply]
+func (server *Server) cas(args []string, reader *bufio.Reader) (string, error) {
+	command, reply, err := server.readStorage(args, 5, reader)
+
+	if command == nil {
+		return reply, err
+	}
+
+	version, err := strconv.ParseUint(args[4], 10, 64)
+
+	if err != nil {
+		return "", clientError("bad command line format")
+	}
+
+	err = server.cache.CompareAndSwap(command.key, version, command.item)
+
+	switch {
+	case errors.Is(err, cache.ErrNotFound):
+		return command.reply("NOT_FOUND"), nil
+	case errors.Is(err, cache.ErrVersionMismatch):
+		return command.reply("EXISTS"), nil
+	case err != nil:
+		return "SERVER_ERROR " + err.Error(), nil
+	}
+
+	// `CompareAndSwap` сохраняет время жизни значения, поэтому время истечения команды применяется
+	// отдельно. Значение могло быть изменено между вызовами, тогда продлевается уже новое значение
+	switch {
+	case command.ttl == 0:
+		server.cache.Expire(command.key, server.cache.DefaultTTL())
+	case command.ttl < 0:
+		server.cache.Delete(command.key)
+	default:
+		server.cache.Expire(command.key, command.ttl)
+	}
+
+	return command.reply("STORED"), nil
+}
+
+// Функция получения ответа команды с учетом `noreply`
+func (command *storage) reply(reply string) string {
+	if command.noreply {
+		return ""
+	}
+
+	return reply
+}
+
+// delete <key> [noreply]
+func (server *Server) delete(args []string) (string, error) {
+	noreply, err := parseNoreply(args, 1)
+
+	if err != nil {
+		return "", err
+	}
+
+	reply := "DELETED"
+
+	if !server.cache.Delete(args[0]) {
+		reply = "NOT_FOUND"
+	}
+
+	if noreply {
+		return "", nil
+	}
+
+	return reply, nil
+}
+
+// touch <key> <exptime> [noreply]
+func (server *Server) touch(args []string) (string, error) {
+	noreply, err := parseNoreply(args, 2)
+
+	if err != nil {
+		return "", err
+	}
+
+	ttl, err := parseExpiration(args[1], server.cache.Clock().Now())
+
+	if err != nil {
+		return "", err
+	}
+
+	var ok bool
+
+	switch {
+	case ttl == 0:
+		ok = server.cache.Touch(args[0])
+	case ttl < 0:
+		ok = server.cache.Delete(args[0])
+	default:
+		ok = server.cache.Expire(args[0], ttl)
+	}
+
+	switch {
+	case noreply:
+		return "", nil
+	case !ok:
+		return "NOT_FOUND", nil
+	}
+
+	return "TOUCHED", nil
+}
+
+/*
+ * Функция разбора необязательного последнего аргумента `noreply` команды с `count` обязательными
+ * аргументами. Последний аргумент с другим значением - ошибка формата команды
+ */
+func parseNoreply(args []string, count int) (bool, error) {
+	switch {
+	case len(args) == count:
+		return false, nil
+	case len(args) == count+1 && args[count] == "noreply":
+		return true, nil
+	}
+
+	return false, clientError("bad command line format")
+}
+
+/*
+ * Функция разбора времени истечения memcached. Абсолютное время отсчитывается от `now` по часам кэша.
+ * Возвращает ноль для времени жизни по умолчанию и отрицательную длительность для уже истекшего значения
+ */
+func parseExpiration(value string, now time.Time) (time.Duration, error) {
+	exptime, err := strconv.ParseInt(value, 10, 64)
+
+	if err != nil {
+		return 0, clientError("bad command line format")
+	}
+
+	switch {
+	case exptime == 0:
+		return 0, nil
+	case exptime < 0:
+		return -1, nil
+	case exptime <= relativeExpirationLimit:
+		return time.Duration(exptime) * time.Second, nil
+	}
+
+	if ttl := time.Unix(exptime, 0).Sub(now); ttl > 0 {
+		return ttl, nil
+	}
+
+	return -1, nil
+}
+
+// Функция проверки ключа по правилам протокола: не длиннее 250 байт и без управляющих символов
+func validateKey(key string) error {
+	if len(key) > maxKeyLength {
+		return clientError("key too long")
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return clientError("bad key")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package cachememcache_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachememcache"
+	"golang-cache/cachetest"
+)
+
+// Функция подключения клиента к серверу через соединение в памяти процесса
+func dial(t *testing.T, opts ...cache.Option) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	values, err := cache.NewCache[string, cachememcache.Item](append(opts, cache.WithoutBackgroundGC())...)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(values.Close)
+
+	client, conn := net.Pipe()
+
+	go cachememcache.NewServer(values).ServeConn(conn)
+
+	t.Cleanup(func() { client.Close() })
+
+	return client, bufio.NewReader(client)
+}
+
+// Функция отправки команды и чтения ответа из `lines` строк
+func roundTrip(t *testing.T, conn net.Conn, reader *bufio.Reader, request string, lines int) string {
+	t.Helper()
+
+	if _, err := io.WriteString(conn, request); err != nil {
+		t.Fatal(err)
+	}
+
+	var reply strings.Builder
+
+	for range lines {
+		line, err := reader.ReadString('\n')
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reply.WriteString(line)
+	}
+
+	return reply.String()
+}
+
+func TestCommands(t *testing.T) {
+	conn, reader := dial(t)
+
+	for _, step := range []struct {
+		request string
+		lines   int
+		reply   string
+	}{
+		{"version\r\n", 1, "VERSION golang-cache\r\n"},
+		{"get user\r\n", 1, "END\r\n"},
+		{"set user 42 0 5\r\nAlice\r\n", 1, "STORED\r\n"},
+		{"get user missing\r\n", 3, "VALUE user 42 5\r\nAlice\r\nEND\r\n"},
+		{"touch user 60\r\n", 1, "TOUCHED\r\n"},
+		// Лишние байты после блока данных читаются как следующая команда
+		{"set user 0 0 5\r\nAlice!!\r\n", 2, "CLIENT_ERROR bad data chunk\r\nERROR\r\n"},
+		{"delete user\r\n", 1, "DELETED\r\n"},
+		{"delete user\r\n", 1, "NOT_FOUND\r\n"},
+		{"flush_all\r\n", 1, "ERROR\r\n"},
+	} {
+		if reply := roundTrip(t, conn, reader, step.request, step.lines); reply != step.reply {
+			t.Fatalf("%q: expected %q, got %q", step.request, step.reply, reply)
+		}
+	}
+}
+
+func TestNoreplySuppressesReply(t *testing.T) {
+	conn, reader := dial(t)
+
+	reply := roundTrip(t, conn, reader, "set user 0 0 5 noreply\r\nAlice\r\nget user\r\n", 3)
+
+	if reply != "VALUE user 0 5\r\nAlice\r\nEND\r\n" {
+		t.Fatalf("expected only the get reply, got %q", reply)
+	}
+}
+
+func TestLineTooLong(t *testing.T) {
+	conn, reader := dial(t)
+
+	reply := roundTrip(t, conn, reader, "get "+strings.Repeat("k", 16<<10)+"\r\n", 1)
+
+	if reply != "CLIENT_ERROR line too long\r\n" {
+		t.Fatalf("expected line too long error, got %q", reply)
+	}
+
+	// Остаток длинной строки пропускается, и соединение продолжает обслуживаться
+	if reply := roundTrip(t, conn, reader, "version\r\n", 1); reply != "VERSION golang-cache\r\n" {
+		t.Fatalf("expected connection to stay usable, got %q", reply)
+	}
+}
+
+func TestGetsAndCas(t *testing.T) {
+	conn, reader := dial(t)
+
+	roundTrip(t, conn, reader, "set user 0 0 5\r\nAlice\r\n", 1)
+
+	var (
+		flags, size int
+		unique      uint64
+	)
+
+	reply := roundTrip(t, conn, reader, "gets user\r\n", 3)
+
+	if _, err := fmt.Sscanf(reply, "VALUE user %d %d %d\r\nAlice\r\nEND\r\n", &flags, &size, &unique); err != nil {
+		t.Fatalf("expected gets to return a cas unique, got %q: %v", reply, err)
+	}
+
+	for _, step := range []struct {
+		request string
+		reply   string
+	}{
+		{fmt.Sprintf("cas user 0 0 3 %d\r\nBob\r\n", unique+1), "EXISTS\r\n"},
+		{fmt.Sprintf("cas missing 0 0 3 %d\r\nBob\r\n", unique), "NOT_FOUND\r\n"},
+		{fmt.Sprintf("cas user 7 0 3 %d\r\nBob\r\n", unique), "STORED\r\n"},
+		// Значение уже перезаписано, поэтому прежняя версия больше не подходит
+		{fmt.Sprintf("cas user 0 0 3 %d\r\nEve\r\n", unique), "EXISTS\r\n"},
+		{"get user\r\n", "VALUE user 7 3\r\nBob\r\nEND\r\n"},
+	} {
+		if reply := roundTrip(t, conn, reader, step.request, strings.Count(step.reply, "\n")); reply != step.reply {
+			t.Fatalf("%q: expected %q, got %q", step.request, step.reply, reply)
+		}
+	}
+}
+
+func TestNoreplyIsParsedPerCommand(t *testing.T) {
+	conn, reader := dial(t)
+
+	for _, step := range []struct {
+		request string
+		reply   string
+	}{
+		// В `get` слово `noreply` - обычный ключ
+		{"get noreply\r\n", "END\r\n"},
+		{"delete user bogus\r\n", "CLIENT_ERROR bad command line format\r\n"},
+		{"touch user 60 noreply\r\nversion\r\n", "VERSION golang-cache\r\n"},
+		{"set user 0 0 5 bogus\r\nAlice\r\n", "CLIENT_ERROR bad command line format\r\n"},
+		{"set user 0 0 5\r\nAlice\r\ndelete user noreply\r\nget user\r\n", "STORED\r\nEND\r\n"},
+	} {
+		if reply := roundTrip(t, conn, reader, step.request, strings.Count(step.reply, "\n")); reply != step.reply {
+			t.Fatalf("%q: expected %q, got %q", step.request, step.reply, reply)
+		}
+	}
+}
+
+func TestAbsoluteExpirationUsesCacheClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := cachetest.NewFakeClock(start)
+
+	conn, reader := dial(t, cache.WithClock(clock))
+
+	// Время больше 30 дней - абсолютное Unix-время, которое отсчитывается по часам кэша, а не по системным
+	request := fmt.Sprintf("set user 0 %d 5\r\nAlice\r\n", start.Add(time.Minute).Unix())
+
+	if reply := roundTrip(t, conn, reader, request, 1); reply != "STORED\r\n" {
+		t.Fatalf("expected STORED, got %q", reply)
+	}
+
+	if reply := roundTrip(t, conn, reader, "get user\r\n", 3); reply != "VALUE user 0 5\r\nAlice\r\nEND\r\n" {
+		t.Fatalf("expected the value before its absolute expiry, got %q", reply)
+	}
+
+	clock.Advance(time.Minute + time.Second)
+
+	if reply := roundTrip(t, conn, reader, "get user\r\n", 1); reply != "END\r\n" {
+		t.Fatalf("expected the value to expire by the cache clock, got %q", reply)
+	}
+}
@@ -0,0 +1,321 @@
+/*
+ * Пакет шины сообщений об изменении значений (`cache.WithInvalidationBus`) поверх NATS.
+ * Пакет не зависит от клиентской библиотеки NATS: команды `CONNECT`, `PUB`, `SUB` и `UNSUB`
+ * передаются по текстовому протоколу NATS напрямую
+ */
+package cachenats
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cache "golang-cache"
+)
+
+// Пауза между попытками переподключения после разрыва соединения
+const reconnectDelay = time.Second
+
+// Параметры подключения, передаваемые серверу командой `CONNECT`
+const connectOptions = `{"verbose":false,"pedantic":false,"name":"golang-cache"}`
+
+// Ошибка отправки при отсутствии соединения с сервером
+var ErrDisconnected = errors.New("cachenats: not connected")
+
+/*
+ * Шина сообщений поверх темы NATS. Отправка и подписки используют одно соединение, которое после разрыва
+ * переподключается в фоне с восстановлением подписок. Отправка во время разрыва возвращает `ErrDisconnected`
+ * и учитывается кэшем в `Stats.DeadLetters`, а сообщения, отправленные другими экземплярами во время разрыва,
+ * не доставляются
+ */
+type Bus struct {
+	addr    string
+	subject string
+	dialer  net.Dialer
+
+	mutex    sync.Mutex
+	conn     net.Conn
+	writer   *bufio.Writer
+	handlers map[int]func(cache.Invalidation)
+	nextSID  int
+	closed   bool
+	stop     chan struct{}
+}
+
+/*
+ * Функция подключения к серверу NATS по адресу `addr` с темой `subject`. Ошибка первого подключения
+ * возвращается, а последующие разрывы обрабатываются переподключением в фоне
+ */
+func Connect(addr, subject string) (*Bus, error) {
+	bus := &Bus{
+		addr:     addr,
+		subject:  subject,
+		handlers: make(map[int]func(cache.Invalidation)),
+		stop:     make(chan struct{}),
+	}
+
+	conn, reader, err := bus.dial()
+
+	if err != nil {
+		return nil, err
+	}
+
+	bus.conn = conn
+	bus.writer = bufio.NewWriter(conn)
+
+	go bus.run(conn, reader)
+
+	return bus, nil
+}
+
+// Функция отправки сообщения в тему
+func (bus *Bus) Publish(ctx context.Context, message cache.Invalidation) error {
+	payload := message.Source + "\n" + message.Key
+
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if bus.conn == nil {
+		return ErrDisconnected
+	}
+
+	fmt.Fprintf(bus.writer, "PUB %s %d\r\n%s\r\n", bus.subject, len(payload), payload)
+
+	if err := bus.writer.Flush(); err != nil {
+		return fmt.Errorf("cachenats: publish: %w", err)
+	}
+
+	return nil
+}
+
+// Функция подписки на тему. Подписка восстанавливается после переподключения
+func (bus *Bus) Subscribe(fn func(cache.Invalidation)) (func(), error) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if bus.closed {
+		return nil, net.ErrClosed
+	}
+
+	bus.nextSID++
+	sid := bus.nextSID
+
+	bus.handlers[sid] = fn
+
+	// Без соединения подписка будет оформлена при переподключении
+	if bus.conn != nil {
+		fmt.Fprintf(bus.writer, "SUB %s %d\r\n", bus.subject, sid)
+
+		if err := bus.writer.Flush(); err != nil {
+			delete(bus.handlers, sid)
+
+			return nil, fmt.Errorf("cachenats: subscribe: %w", err)
+		}
+	}
+
+	return func() {
+		bus.mutex.Lock()
+		defer bus.mutex.Unlock()
+
+		if _, ok := bus.handlers[sid]; !ok {
+			return
+		}
+
+		delete(bus.handlers, sid)
+
+		if bus.conn != nil {
+			fmt.Fprintf(bus.writer, "UNSUB %d\r\n", sid)
+			bus.writer.Flush()
+		}
+	}, nil
+}
+
+// Функция закрытия соединения и прекращения переподключений
+func (bus *Bus) Close() error {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if bus.closed {
+		return nil
+	}
+
+	bus.closed = true
+	close(bus.stop)
+
+	if bus.conn == nil {
+		return nil
+	}
+
+	err := bus.conn.Close()
+	bus.conn = nil
+
+	return err
+}
+
+/*
+ * Функция подключения к серверу: чтение `INFO` и отправка `CONNECT`. Возвращает и буфер чтения,
+ * в котором могут остаться команды сервера, отправленные сразу после приветствия
+ */
+func (bus *Bus) dial() (net.Conn, *bufio.Reader, error) {
+	conn, err := bus.dialer.Dial("tcp", bus.addr)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("cachenats: dial %s: %w", bus.addr, err)
+	}
+
+	reader := bufio.NewReader(conn)
+
+	// Сервер начинает соединение строкой `INFO {...}`
+	line, err := reader.ReadString('\n')
+
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+
+		return nil, nil, fmt.Errorf("cachenats: unexpected greeting from %s: %q", bus.addr, line)
+	}
+
+	if _, err := io.WriteString(conn, "CONNECT "+connectOptions+"\r\n"); err != nil {
+		conn.Close()
+
+		return nil, nil, fmt.Errorf("cachenats: connect: %w", err)
+	}
+
+	return conn, reader, nil
+}
+
+// Функция чтения сообщений соединения и переподключения после его разрыва до закрытия шины
+func (bus *Bus) run(conn net.Conn, reader *bufio.Reader) {
+	for {
+		bus.read(conn, reader)
+
+		bus.mutex.Lock()
+
+		if bus.closed {
+			bus.mutex.Unlock()
+
+			return
+		}
+
+		conn.Close()
+		bus.conn = nil
+
+		bus.mutex.Unlock()
+
+		if conn, reader = bus.reconnect(); conn == nil {
+			return
+		}
+	}
+}
+
+// Функция переподключения с восстановлением подписок. Возвращает `nil`, если шина закрыта
+func (bus *Bus) reconnect() (net.Conn, *bufio.Reader) {
+	for {
+		select {
+		case <-bus.stop:
+			return nil, nil
+		case <-time.After(reconnectDelay):
+		}
+
+		conn, reader, err := bus.dial()
+
+		if err != nil {
+			continue
+		}
+
+		bus.mutex.Lock()
+
+		if bus.closed {
+			bus.mutex.Unlock()
+			conn.Close()
+
+			return nil, nil
+		}
+
+		writer := bufio.NewWriter(conn)
+
+		for sid := range bus.handlers {
+			fmt.Fprintf(writer, "SUB %s %d\r\n", bus.subject, sid)
+		}
+
+		if err := writer.Flush(); err != nil {
+			bus.mutex.Unlock()
+			conn.Close()
+
+			continue
+		}
+
+		bus.conn = conn
+		bus.writer = writer
+
+		bus.mutex.Unlock()
+
+		return conn, reader
+	}
+}
+
+// Функция чтения команд сервера до ошибки соединения
+func (bus *Bus) read(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "PING":
+			bus.mutex.Lock()
+
+			if bus.conn == conn {
+				bus.writer.WriteString("PONG\r\n")
+				bus.writer.Flush()
+			}
+
+			bus.mutex.Unlock()
+		case "MSG":
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			if len(fields) < 4 {
+				return
+			}
+
+			sid, _ := strconv.Atoi(fields[2])
+			size, err := strconv.Atoi(fields[len(fields)-1])
+
+			if err != nil || size < 0 {
+				return
+			}
+
+			payload := make([]byte, size+2)
+
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+
+			bus.mutex.Lock()
+			fn := bus.handlers[sid]
+			bus.mutex.Unlock()
+
+			source, key, ok := strings.Cut(string(payload[:size]), "\n")
+
+			if fn != nil && ok {
+				fn(cache.Invalidation{Source: source, Key: key})
+			}
+		case "-ERR":
+			// Сервер закрывает соединение после ошибки протокола
+			return
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package cachenats_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachenats"
+)
+
+// Подписка соединения сервера на тему
+type subscription struct {
+	conn    net.Conn
+	subject string
+}
+
+/*
+ * Сервер NATS в памяти процесса с командами `CONNECT`, `PUB`, `SUB`, `UNSUB`, `PING` и `PONG`.
+ * Сервер может разрывать открытые соединения, прекращать прием подключений и снова запускаться на том же адресе
+ */
+type fakeNATS struct {
+	addr string
+
+	mutex    sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]bool
+	subs     map[net.Conn]map[string]subscription
+	pongs    int
+}
+
+func newFakeNATS(t *testing.T) *fakeNATS {
+	t.Helper()
+
+	server := &fakeNATS{conns: make(map[net.Conn]bool), subs: make(map[net.Conn]map[string]subscription)}
+
+	if err := server.listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(server.stop)
+
+	return server
+}
+
+func (server *fakeNATS) listen(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		return err
+	}
+
+	server.mutex.Lock()
+	server.addr = listener.Addr().String()
+	server.listener = listener
+	server.mutex.Unlock()
+
+	go server.serve(listener)
+
+	return nil
+}
+
+// Функция остановки сервера: прекращение приема подключений и разрыв открытых соединений
+func (server *fakeNATS) stop() {
+	server.mutex.Lock()
+	server.listener.Close()
+	server.mutex.Unlock()
+
+	server.disconnect()
+}
+
+// Функция повторного запуска сервера на прежнем адресе
+func (server *fakeNATS) restart(t *testing.T) {
+	t.Helper()
+
+	if err := server.listen(server.addr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Функция разрыва всех открытых соединений
+func (server *fakeNATS) disconnect() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	for conn := range server.conns {
+		conn.Close()
+	}
+
+	clear(server.conns)
+	clear(server.subs)
+}
+
+// Функция получения количества подписок на тему
+func (server *fakeNATS) subscribed(subject string) int {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	count := 0
+
+	for _, subs := range server.subs {
+		for _, sub := range subs {
+			if sub.subject == subject {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+// Функция отправки `PING` всем клиентам
+func (server *fakeNATS) ping() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	for conn := range server.conns {
+		io.WriteString(conn, "PING\r\n")
+	}
+}
+
+func (server *fakeNATS) ponged() int {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	return server.pongs
+}
+
+func (server *fakeNATS) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		server.mutex.Lock()
+		server.conns[conn] = true
+		server.subs[conn] = make(map[string]subscription)
+		server.mutex.Unlock()
+
+		go server.serveConn(conn)
+	}
+}
+
+func (server *fakeNATS) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, "INFO {\"server_id\":\"fake\"}\r\n"); err != nil {
+		return
+	}
+
+	reader := bufio.NewReader(conn)
+
+	for {
+		line, err := reader.ReadString('\n')
+
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		server.mutex.Lock()
+
+		switch fields[0] {
+		case "SUB":
+			// SUB <subject> <sid>
+			if subs := server.subs[conn]; subs != nil {
+				subs[fields[2]] = subscription{conn: conn, subject: fields[1]}
+			}
+		case "UNSUB":
+			delete(server.subs[conn], fields[1])
+		case "PONG":
+			server.pongs++
+		case "PUB":
+			// PUB <subject> <#bytes>\r\n<payload>\r\n
+			size, _ := strconv.Atoi(fields[2])
+			payload := make([]byte, size+2)
+
+			server.mutex.Unlock()
+
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+
+			server.mutex.Lock()
+
+			for _, subs := range server.subs {
+				for sid, sub := range subs {
+					if sub.subject == fields[1] {
+						io.WriteString(sub.conn, "MSG "+sub.subject+" "+sid+" "+fields[2]+"\r\n"+string(payload))
+					}
+				}
+			}
+		}
+
+		server.mutex.Unlock()
+	}
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func connect(t *testing.T, server *fakeNATS) *cachenats.Bus {
+	t.Helper()
+
+	bus, err := cachenats.Connect(server.addr, "invalidation")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { bus.Close() })
+
+	return bus
+}
+
+// Функция подписки на шину с передачей сообщений в канал
+func subscribe(t *testing.T, bus *cachenats.Bus) <-chan cache.Invalidation {
+	t.Helper()
+
+	messages := make(chan cache.Invalidation, 16)
+
+	unsubscribe, err := bus.Subscribe(func(message cache.Invalidation) { messages <- message })
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(unsubscribe)
+
+	return messages
+}
+
+func receive(t *testing.T, messages <-chan cache.Invalidation) cache.Invalidation {
+	t.Helper()
+
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a message from the bus")
+
+		return cache.Invalidation{}
+	}
+}
+
+func TestBusDeliversPublishedMessages(t *testing.T) {
+	server := newFakeNATS(t)
+	bus := connect(t, server)
+
+	messages := subscribe(t, bus)
+
+	eventually(t, func() bool { return server.subscribed("invalidation") == 1 })
+
+	sent := cache.Invalidation{Source: "instance", Key: "user:1"}
+
+	if err := bus.Publish(context.Background(), sent); err != nil {
+		t.Fatal(err)
+	}
+
+	if message := receive(t, messages); message != sent {
+		t.Fatalf("expected %+v, got %+v", sent, message)
+	}
+}
+
+func TestBusAnswersPing(t *testing.T) {
+	server := newFakeNATS(t)
+	connect(t, server)
+
+	server.ping()
+
+	eventually(t, func() bool { return server.ponged() == 1 })
+}
+
+func TestBusUnsubscribeStopsDelivery(t *testing.T) {
+	server := newFakeNATS(t)
+	bus := connect(t, server)
+
+	unsubscribe, err := bus.Subscribe(func(cache.Invalidation) { t.Error("expected no messages after unsubscribe") })
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventually(t, func() bool { return server.subscribed("invalidation") == 1 })
+
+	unsubscribe()
+
+	eventually(t, func() bool { return server.subscribed("invalidation") == 0 })
+}
+
+func TestBusReconnectsAndRestoresSubscriptions(t *testing.T) {
+	server := newFakeNATS(t)
+	bus := connect(t, server)
+
+	messages := subscribe(t, bus)
+
+	eventually(t, func() bool { return server.subscribed("invalidation") == 1 })
+
+	server.disconnect()
+
+	// Шина переподключается после паузы `reconnectDelay` и заново оформляет подписку
+	eventually(t, func() bool { return server.subscribed("invalidation") == 1 })
+
+	sent := cache.Invalidation{Source: "instance", Key: "user:1"}
+
+	if err := bus.Publish(context.Background(), sent); err != nil {
+		t.Fatal(err)
+	}
+
+	if message := receive(t, messages); message != sent {
+		t.Fatalf("expected %+v, got %+v", sent, message)
+	}
+}
+
+func TestBusCountsDeadLettersWhileDisconnected(t *testing.T) {
+	server := newFakeNATS(t)
+	bus := connect(t, server)
+
+	values, err := cache.NewCache[string, int](cache.WithInvalidationBus(bus), cache.WithoutBackgroundGC())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(values.Close)
+
+	server.stop()
+
+	eventually(t, func() bool {
+		return errors.Is(bus.Publish(context.Background(), cache.Invalidation{Source: "test", Key: "key"}), cachenats.ErrDisconnected)
+	})
+
+	values.Set("key", 1)
+	values.Delete("key")
+
+	if dead := values.Stats().DeadLetters; dead != 2 {
+		t.Fatalf("expected both messages to be counted as dead letters, got %d", dead)
+	}
+
+	// После восстановления сервера шина переподключается, и сообщения снова отправляются
+	server.restart(t)
+
+	eventually(t, func() bool { return server.subscribed("invalidation") == 1 })
+
+	values.Set("key", 2)
+
+	if dead := values.Stats().DeadLetters; dead != 2 {
+		t.Fatalf("expected no dead letters after reconnecting, got %d", dead)
+	}
+}
+
+func TestConnectFailsWithoutServer(t *testing.T) {
+	server := newFakeNATS(t)
+	server.stop()
+
+	if _, err := cachenats.Connect(server.addr, "invalidation"); err == nil {
+		t.Fatal("expected Connect to fail without a server")
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Пакет подключения провайдеров OpenTelemetry к кэш-хранилищу. Вынесен в отдельный модуль, поэтому
+ * основной пакет кэша не зависит от модулей OpenTelemetry, а его интерфейсы `cache.TracerProvider`
+ * и `cache.MeterProvider` реализуются здесь поверх `trace.TracerProvider` и `metric.MeterProvider`
+ */
+package cacheotel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	cache "golang-cache"
+)
+
+/*
+ * Опция телеметрии кэша с провайдерами OpenTelemetry, например `otel.GetTracerProvider()`
+ * и `otel.GetMeterProvider()`. Span-ы и метрики те же, что и у `cache.WithTelemetry`.
+ * Один из провайдеров может быть `nil`, но не оба сразу
+ */
+func WithTelemetry(tp trace.TracerProvider, mp metric.MeterProvider) cache.Option {
+	var (
+		tracerProvider cache.TracerProvider
+		meterProvider  cache.MeterProvider
+	)
+
+	if tp != nil {
+		tracerProvider = tracerProviderAdapter{tp}
+	}
+
+	if mp != nil {
+		meterProvider = meterProviderAdapter{mp}
+	}
+
+	return cache.WithTelemetry(tracerProvider, meterProvider)
+}
+
+type tracerProviderAdapter struct{ provider trace.TracerProvider }
+
+func (adapter tracerProviderAdapter) Tracer(name string) cache.Tracer {
+	return tracerAdapter{adapter.provider.Tracer(name)}
+}
+
+type tracerAdapter struct{ tracer trace.Tracer }
+
+func (adapter tracerAdapter) Start(ctx context.Context, spanName string) (context.Context, cache.Span) {
+	ctx, span := adapter.tracer.Start(ctx, spanName)
+
+	return ctx, spanAdapter{span}
+}
+
+type spanAdapter struct{ span trace.Span }
+
+// Функция записи ошибки в span. Статус span-а также становится ошибочным, чтобы span выделялся в трассировке
+func (adapter spanAdapter) RecordError(err error) {
+	adapter.span.RecordError(err)
+	adapter.span.SetStatus(codes.Error, err.Error())
+}
+
+func (adapter spanAdapter) End() {
+	adapter.span.End()
+}
+
+type meterProviderAdapter struct{ provider metric.MeterProvider }
+
+func (adapter meterProviderAdapter) Meter(name string) cache.Meter {
+	return meterAdapter{adapter.provider.Meter(name)}
+}
+
+type meterAdapter struct{ meter metric.Meter }
+
+func (adapter meterAdapter) Int64Counter(name, description string) (cache.Int64Counter, error) {
+	counter, err := adapter.meter.Int64Counter(name, metric.WithDescription(description))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return int64CounterAdapter{counter}, nil
+}
+
+func (adapter meterAdapter) Float64Histogram(name, description, unit string) (cache.Float64Histogram, error) {
+	histogram, err := adapter.meter.Float64Histogram(name, metric.WithDescription(description), metric.WithUnit(unit))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return float64HistogramAdapter{histogram}, nil
+}
+
+type int64CounterAdapter struct{ counter metric.Int64Counter }
+
+func (adapter int64CounterAdapter) Add(ctx context.Context, incr int64) {
+	adapter.counter.Add(ctx, incr)
+}
+
+type float64HistogramAdapter struct{ histogram metric.Float64Histogram }
+
+func (adapter float64HistogramAdapter) Record(ctx context.Context, value float64) {
+	adapter.histogram.Record(ctx, value)
+}
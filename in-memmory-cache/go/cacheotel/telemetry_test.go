@@ -0,0 +1,113 @@
+package cacheotel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	cache "golang-cache"
+	"golang-cache/cacheotel"
+)
+
+var errUnknown = errors.New("unknown profile")
+
+func loadProfile(ctx context.Context, key string) (*cache.Profile, error) {
+	if key == "missing" {
+		return nil, errUnknown
+	}
+
+	return &cache.Profile{UUID: key}, nil
+}
+
+func TestWithTelemetryRecordsSpansAndMetrics(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	profiles, err := cache.New(
+		cache.WithoutBackgroundGC(),
+		cache.WithLoader(loadProfile),
+		cacheotel.WithTelemetry(tp, mp),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer profiles.Close()
+
+	ctx := context.Background()
+
+	if _, err := profiles.Cache.GetContext(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := profiles.Cache.GetContext(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := profiles.Cache.GetContext(ctx, "missing"); !errors.Is(err, errUnknown) {
+		t.Fatalf("expected loader error, got %v", err)
+	}
+
+	spans := map[string]int{}
+	failed := 0
+
+	for _, span := range recorder.Ended() {
+		spans[span.Name()]++
+
+		if span.Name() == "cache.load" && span.Status().Code == codes.Error && len(span.Events()) == 1 {
+			failed++
+		}
+	}
+
+	if spans["cache.get"] != 3 || spans["cache.load"] != 2 || failed != 1 {
+		t.Fatalf("unexpected spans: %v, failed loads: %d", spans, failed)
+	}
+
+	var data metricdata.ResourceMetrics
+
+	if err := reader.Collect(ctx, &data); err != nil {
+		t.Fatal(err)
+	}
+
+	sums := map[string]int64{}
+	histograms := map[string]uint64{}
+
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch value := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, point := range value.DataPoints {
+					sums[m.Name] += point.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, point := range value.DataPoints {
+					histograms[m.Name] += point.Count
+				}
+			}
+		}
+	}
+
+	if sums["cache.hits"] != 1 || sums["cache.misses"] != 2 {
+		t.Fatalf("unexpected counters: %v", sums)
+	}
+
+	if histograms["cache.load.duration"] != 2 {
+		t.Fatalf("unexpected load duration count: %v", histograms)
+	}
+}
+
+func TestWithTelemetryRequiresProvider(t *testing.T) {
+	if _, err := cache.New(cacheotel.WithTelemetry(nil, nil)); err == nil {
+		t.Fatal("expected error without providers")
+	}
+}
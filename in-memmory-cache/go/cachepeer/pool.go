@@ -0,0 +1,174 @@
+/*
+ * Пакет пула узлов в стиле groupcache. Экземпляры сервиса делят пространство ключей согласованным
+ * хешированием: при промахе значение запрашивается по HTTP у узла-владельца ключа, и только владелец
+ * обращается к источнику данных. Так каждое значение загружается из источника один раз на весь парк
+ * экземпляров, а общая доля попаданий растет с количеством экземпляров
+ */
+package cachepeer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	cache "golang-cache"
+	"golang-cache/internal/hashring"
+)
+
+// Путь, по которому узлы запрашивают значения друг у друга
+const BasePath = "/_cache/"
+
+// Количество точек каждого узла на кольце. Чем больше точек, тем равномернее ключи делятся между узлами
+const ringReplicas = 64
+
+// Заголовок запроса от другого узла. Такой запрос загружается из источника без повторной пересылки
+const peerHeader = "X-Cache-Peer"
+
+/*
+ * Пул узлов. Функция `Load` подключается к кэшу как загрузчик (`cache.WithLoader`), а `Handler`
+ * отдает значения кэша другим узлам. Значения передаются между узлами в формате JSON
+ */
+type Pool[V any] struct {
+	self   string
+	origin func(context.Context, string) (V, error)
+	client *http.Client
+
+	mutex sync.RWMutex
+	ring  *hashring.Ring
+}
+
+/*
+ * Функция-конструктор пула. `self` - базовый адрес данного узла в том же виде, что и в `SetPeers`
+ * (например `http://10.0.0.1:8080`), `origin` - загрузчик из источника данных. До вызова `SetPeers`
+ * все ключи принадлежат данному узлу
+ */
+func NewPool[V any](self string, origin func(ctx context.Context, key string) (V, error)) *Pool[V] {
+	return &Pool[V]{
+		self:   self,
+		origin: origin,
+		client: http.DefaultClient,
+		ring:   hashring.New([]string{self}, ringReplicas),
+	}
+}
+
+/*
+ * Функция замены списка узлов. Список должен включать данный узел и совпадать на всех узлах,
+ * иначе узлы по-разному определят владельцев ключей
+ */
+func (pool *Pool[V]) SetPeers(peers ...string) {
+	ring := hashring.New(peers, ringReplicas)
+
+	pool.mutex.Lock()
+	pool.ring = ring
+	pool.mutex.Unlock()
+}
+
+// Функция получения узла-владельца ключа
+func (pool *Pool[V]) Owner(key string) string {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	return pool.ring.Owner(key)
+}
+
+/*
+ * Функция загрузки значения для `cache.WithLoader`. Ключи данного узла, а также запросы от других узлов
+ * загружаются из источника. Остальные ключи запрашиваются у узла-владельца, а при его недоступности или
+ * ошибке значение загружается из источника локально
+ */
+func (pool *Pool[V]) Load(ctx context.Context, key string) (V, error) {
+	owner := pool.Owner(key)
+
+	if owner == "" || owner == pool.self || fromPeer(ctx) {
+		return pool.origin(ctx, key)
+	}
+
+	if value, err := pool.fetch(ctx, owner, key); err == nil {
+		return value, nil
+	}
+
+	return pool.origin(ctx, key)
+}
+
+// Функция запроса значения у узла-владельца
+func (pool *Pool[V]) fetch(ctx context.Context, peer, key string) (V, error) {
+	var value V
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, peer+BasePath+url.PathEscape(key), nil)
+
+	if err != nil {
+		return value, err
+	}
+
+	request.Header.Set(peerHeader, pool.self)
+
+	response, err := pool.client.Do(request)
+
+	if err != nil {
+		return value, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return value, fmt.Errorf("cachepeer: peer %s returned %s", peer, response.Status)
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&value); err != nil {
+		return value, fmt.Errorf("cachepeer: decode value from %s: %w", peer, err)
+	}
+
+	return value, nil
+}
+
+type peerKey struct{}
+
+func fromPeer(ctx context.Context) bool {
+	return ctx.Value(peerKey{}) != nil
+}
+
+/*
+ * Функция создания HTTP-обработчика, отдающего значения кэша другим узлам по пути `BasePath`.
+ * Промах загружается загрузчиком кэша, то есть `Pool.Load`, из источника данных
+ */
+func Handler[V any](c *cache.Cache[string, V]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		escaped, ok := strings.CutPrefix(r.URL.EscapedPath(), BasePath)
+
+		if !ok || r.Method != http.MethodGet {
+			http.Error(w, "bad request", http.StatusBadRequest)
+
+			return
+		}
+
+		key, err := url.PathUnescape(escaped)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		ctx := r.Context()
+
+		if r.Header.Get(peerHeader) != "" {
+			ctx = context.WithValue(ctx, peerKey{}, true)
+		}
+
+		value, err := c.GetContext(ctx, key)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		_ = json.NewEncoder(w).Encode(value)
+	})
+}
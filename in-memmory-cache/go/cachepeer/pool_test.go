@@ -0,0 +1,120 @@
+package cachepeer_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	cache "golang-cache"
+	"golang-cache/cachepeer"
+)
+
+// Узел парка: кэш с пулом в качестве загрузчика и HTTP-сервер для других узлов
+type node struct {
+	url    string
+	pool   *cachepeer.Pool[string]
+	values *cache.Cache[string, string]
+}
+
+// Функция запуска `n` узлов с общим источником данных, считающим загрузки каждого ключа
+func newNodes(t *testing.T, n int, loads map[string]int, mutex *sync.Mutex) []node {
+	t.Helper()
+
+	origin := func(ctx context.Context, key string) (string, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		loads[key]++
+
+		return "value of " + key, nil
+	}
+
+	nodes := make([]node, n)
+	urls := make([]string, n)
+
+	for i := range nodes {
+		// Адрес узла нужен пулу до запуска сервера, поэтому сервер создается без обработчика
+		server := httptest.NewUnstartedServer(nil)
+		url := "http://" + server.Listener.Addr().String()
+
+		pool := cachepeer.NewPool(url, origin)
+
+		values, err := cache.NewCache[string, string](cache.WithLoader(pool.Load), cache.WithoutBackgroundGC())
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(values.Close)
+
+		server.Config.Handler = cachepeer.Handler(values)
+		server.Start()
+
+		t.Cleanup(server.Close)
+
+		nodes[i] = node{url: url, pool: pool, values: values}
+		urls[i] = url
+	}
+
+	for _, node := range nodes {
+		node.pool.SetPeers(urls...)
+	}
+
+	return nodes
+}
+
+func TestOwnerLoadsFromOriginOnce(t *testing.T) {
+	var mutex sync.Mutex
+
+	loads := map[string]int{}
+
+	nodes := newNodes(t, 3, loads, &mutex)
+
+	keys := []string{"user-1", "user-2", "user-3", "user-4", "user-5", "user-6"}
+
+	for _, node := range nodes {
+		for _, key := range keys {
+			value, err := node.values.GetContext(context.Background(), key)
+
+			if err != nil || value != "value of "+key {
+				t.Fatalf("%s: expected value of %s, got %q, %v", node.url, key, value, err)
+			}
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// Каждый ключ загружается из источника только владельцем, остальные узлы получают его у владельца
+	for _, key := range keys {
+		if loads[key] != 1 {
+			t.Fatalf("expected %s to be loaded from origin once, got %d", key, loads[key])
+		}
+	}
+
+	for _, node := range nodes {
+		for _, key := range keys {
+			if owner := node.pool.Owner(key); owner != nodes[0].pool.Owner(key) {
+				t.Fatalf("expected nodes to agree on the owner of %s, got %s", key, owner)
+			}
+		}
+	}
+}
+
+func TestOriginIsUsedWhenOwnerIsDown(t *testing.T) {
+	var mutex sync.Mutex
+
+	loads := map[string]int{}
+
+	nodes := newNodes(t, 2, loads, &mutex)
+
+	// Недоступный владелец не приводит к ошибке: значение загружается из источника локально
+	nodes[1].pool.SetPeers(nodes[1].url, "http://127.0.0.1:1")
+
+	for _, key := range []string{"user-1", "user-2", "user-3", "user-4"} {
+		if value, err := nodes[1].values.GetContext(context.Background(), key); err != nil || value != "value of "+key {
+			t.Fatalf("expected value of %s, got %q, %v", key, value, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Пакет сборщика метрик кэш-хранилища для клиентской библиотеки Prometheus. Вынесен в отдельный модуль,
+ * поэтому основной пакет кэша не зависит от `github.com/prometheus/client_golang`
+ */
+package cacheprom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	cache "golang-cache"
+)
+
+// Описание метрики и способ получения ее значения из снимка статистики
+type metric struct {
+	desc  *prometheus.Desc
+	kind  prometheus.ValueType
+	value func(cache.Stats) float64
+}
+
+/*
+ * Сборщик метрик кэша, реализующий `prometheus.Collector`. Метрики всех кэшей имеют общие имена
+ * с префиксом `cache_` и различаются постоянной меткой `cache`,
+ * поэтому сборщики нескольких кэшей регистрируются в одном `prometheus.Registry`.
+ *
+ * Статистика читается при каждом сборе метрик, поэтому сборщик не хранит значений между запросами
+ */
+type Collector struct {
+	provider cache.StatsProvider
+	metrics  []metric
+	sweep    *prometheus.Desc
+}
+
+/*
+ * Функция-конструктор сборщика метрик. Параметр `name` становится значением метки `cache`
+ */
+func NewCollector(provider cache.StatsProvider, name string) *Collector {
+	labels := prometheus.Labels{"cache": name}
+
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(name, help, nil, labels)
+	}
+
+	return &Collector{
+		provider: provider,
+		metrics: []metric{
+			{desc("cache_hits_total", "Total number of cache hits."), prometheus.CounterValue, func(s cache.Stats) float64 { return float64(s.Hits) }},
+			{desc("cache_misses_total", "Total number of cache misses."), prometheus.CounterValue, func(s cache.Stats) float64 { return float64(s.Misses) }},
+			{desc("cache_expired_total", "Total number of entries removed by the garbage collector after TTL expiry."), prometheus.CounterValue, func(s cache.Stats) float64 { return float64(s.Expired) }},
+			{desc("cache_evictions_total", "Total number of entries evicted due to capacity limits."), prometheus.CounterValue, func(s cache.Stats) float64 { return float64(s.Evictions) }},
+			{desc("cache_replaced_total", "Total number of entries replaced by a new value for the same key."), prometheus.CounterValue, func(s cache.Stats) float64 { return float64(s.Replaced) }},
+			{desc("cache_deleted_total", "Total number of entries removed explicitly."), prometheus.CounterValue, func(s cache.Stats) float64 { return float64(s.Deleted) }},
+			{desc("cache_cleared_total", "Total number of entries removed by clearing the cache."), prometheus.CounterValue, func(s cache.Stats) float64 { return float64(s.Cleared) }},
+			{desc("cache_dead_letters_total", "Total number of invalidation messages that could not be published."), prometheus.CounterValue, func(s cache.Stats) float64 { return float64(s.DeadLetters) }},
+			{desc("cache_entries", "Current number of entries in the cache."), prometheus.GaugeValue, func(s cache.Stats) float64 { return float64(s.Entries) }},
+		},
+		sweep: desc("cache_gc_sweep_duration_seconds", "Duration of garbage collector sweeps."),
+	}
+}
+
+func (collector *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range collector.metrics {
+		ch <- metric.desc
+	}
+
+	ch <- collector.sweep
+}
+
+func (collector *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := collector.provider.Stats()
+
+	for _, metric := range collector.metrics {
+		ch <- prometheus.MustNewConstMetric(metric.desc, metric.kind, metric.value(stats))
+	}
+
+	// Длительность проходов сборщика мусора экспортируется как summary без квантилей,
+	// что позволяет строить среднюю длительность прохода через rate(sum) / rate(count)
+	ch <- prometheus.MustNewConstSummary(collector.sweep, stats.Sweeps, stats.SweepDuration.Seconds(), nil)
+}
@@ -0,0 +1,61 @@
+package cacheprom_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	cache "golang-cache"
+	"golang-cache/cacheprom"
+)
+
+func TestCollectorRegistersSeveralCaches(t *testing.T) {
+	profiles, err := cache.New(cache.WithoutBackgroundGC())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer profiles.Close()
+
+	sessions, err := cache.New(cache.WithoutBackgroundGC())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sessions.Close()
+
+	profiles.Set(&cache.Profile{UUID: "a"})
+	profiles.Get("a")
+	profiles.Get("b")
+
+	registry := prometheus.NewPedanticRegistry()
+
+	registry.MustRegister(cacheprom.NewCollector(profiles, "user_profiles"), cacheprom.NewCollector(sessions, "sessions"))
+
+	expected := `
+# HELP cache_entries Current number of entries in the cache.
+# TYPE cache_entries gauge
+cache_entries{cache="sessions"} 0
+cache_entries{cache="user_profiles"} 1
+# HELP cache_hits_total Total number of cache hits.
+# TYPE cache_hits_total counter
+cache_hits_total{cache="sessions"} 0
+cache_hits_total{cache="user_profiles"} 1
+# HELP cache_misses_total Total number of cache misses.
+# TYPE cache_misses_total counter
+cache_misses_total{cache="sessions"} 0
+cache_misses_total{cache="user_profiles"} 1
+`
+
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "cache_entries", "cache_hits_total", "cache_misses_total"); err != nil {
+		t.Fatal(err)
+	}
+
+	if count, err := testutil.GatherAndCount(registry, "cache_gc_sweep_duration_seconds"); err != nil || count != 2 {
+		t.Fatalf("expected 2 sweep summaries, got %d (%v)", count, err)
+	}
+}
@@ -0,0 +1,86 @@
+package cacheredis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+/*
+ * Хранилище значений в Redis. Реализует `cache.Store` для сквозной и отложенной записи (`WithStore`,
+ * `WithWriteBehind`) и `cache.Backend` для второго уровня двухуровневого кэша (`NewTiered`).
+ * Значения хранятся в формате JSON по ключам с префиксом `prefix`
+ */
+type Backend[V any] struct {
+	client *client
+	prefix string
+}
+
+// Функция-конструктор хранилища для сервера Redis по адресу `addr`
+func NewBackend[V any](addr, prefix string) *Backend[V] {
+	return &Backend[V]{client: &client{addr: addr}, prefix: prefix}
+}
+
+// Функция получения значения. Отсутствующий ключ возвращается как `false` без ошибки
+func (backend *Backend[V]) Get(ctx context.Context, key string) (V, bool, error) {
+	var value V
+
+	reply, err := backend.client.do(ctx, "GET", backend.prefix+key)
+
+	if err != nil || reply == nil {
+		return value, false, err
+	}
+
+	data, ok := reply.(string)
+
+	if !ok {
+		return value, false, fmt.Errorf("cacheredis: unexpected GET reply %T", reply)
+	}
+
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return value, false, fmt.Errorf("cacheredis: decode %s: %w", key, err)
+	}
+
+	return value, true, nil
+}
+
+// Функция записи значения с временем жизни. Неположительное время жизни записывает значение без истечения
+func (backend *Backend[V]) Set(ctx context.Context, key string, value V, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return fmt.Errorf("cacheredis: encode %s: %w", key, err)
+	}
+
+	args := []string{"SET", backend.prefix + key, string(data)}
+
+	// Redis принимает время жизни в миллисекундах, поэтому округляем вверх до ненулевого значения
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(int64((ttl+time.Millisecond-1)/time.Millisecond), 10))
+	}
+
+	_, err = backend.client.do(ctx, args...)
+
+	return err
+}
+
+// Функция сохранения значения без истечения для `cache.Store`
+func (backend *Backend[V]) Save(ctx context.Context, key string, value V) error {
+	return backend.Set(ctx, key, value, 0)
+}
+
+// Функция удаления значения. Удаление отсутствующего ключа не является ошибкой
+func (backend *Backend[V]) Delete(ctx context.Context, key string) error {
+	_, err := backend.client.do(ctx, "DEL", backend.prefix+key)
+
+	return err
+}
+
+// Функция закрытия простаивающих соединений с Redis
+func (backend *Backend[V]) Close() error {
+	backend.client.close()
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package cacheredis_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cacheredis"
+)
+
+type profile struct {
+	Name   string   `json:"name"`
+	Orders []string `json:"orders"`
+}
+
+func newBackend(t *testing.T, server *fakeRedis) *cacheredis.Backend[profile] {
+	t.Helper()
+
+	backend := cacheredis.NewBackend[profile](server.addr(), "profiles:")
+
+	t.Cleanup(func() { backend.Close() })
+
+	return backend
+}
+
+func TestBackendSetGetDelete(t *testing.T) {
+	server := newFakeRedis(t)
+	backend := newBackend(t, server)
+
+	ctx := context.Background()
+	stored := profile{Name: "Alice", Orders: []string{"order-1", "order-2"}}
+
+	// Время жизни передается в миллисекундах с округлением вверх
+	if err := backend.Set(ctx, "user-1", stored, 1500*time.Microsecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, ttl, ok := server.get("profiles:user-1"); !ok || ttl != "2" || !strings.Contains(data, `"name":"Alice"`) {
+		t.Fatalf("expected a JSON value with a 2ms ttl under the prefixed key, got %q, %q, %v", data, ttl, ok)
+	}
+
+	value, ok, err := backend.Get(ctx, "user-1")
+
+	if err != nil || !ok || value.Name != stored.Name || len(value.Orders) != 2 {
+		t.Fatalf("expected the stored profile, got %+v, %v, %v", value, ok, err)
+	}
+
+	if err := backend.Delete(ctx, "user-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, ok := server.get("profiles:user-1"); ok {
+		t.Fatal("expected Delete to remove the key")
+	}
+}
+
+func TestBackendMissIsNotAnError(t *testing.T) {
+	backend := newBackend(t, newFakeRedis(t))
+
+	if _, ok, err := backend.Get(context.Background(), "missing"); ok || err != nil {
+		t.Fatalf("expected a miss without an error, got %v, %v", ok, err)
+	}
+
+	// Удаление отсутствующего ключа также не является ошибкой
+	if err := backend.Delete(context.Background(), "missing"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackendSaveStoresWithoutExpiration(t *testing.T) {
+	server := newFakeRedis(t)
+	backend := newBackend(t, server)
+
+	if err := backend.Save(context.Background(), "user-1", profile{Name: "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ttl, ok := server.get("profiles:user-1"); !ok || ttl != "" {
+		t.Fatalf("expected the value to be stored without PX, got ttl %q, %v", ttl, ok)
+	}
+}
+
+func TestBackendReturnsServerErrors(t *testing.T) {
+	server := newFakeRedis(t)
+	backend := newBackend(t, server)
+
+	ctx := context.Background()
+
+	server.failWith("READONLY You can't write against a read only replica")
+
+	if err := backend.Set(ctx, "user-1", profile{Name: "Alice"}, 0); err == nil || !strings.Contains(err.Error(), "READONLY") {
+		t.Fatalf("expected the server error from Set, got %v", err)
+	}
+
+	if _, ok, err := backend.Get(ctx, "user-1"); ok || err == nil {
+		t.Fatalf("expected the server error from Get, got %v, %v", ok, err)
+	}
+
+	if err := backend.Delete(ctx, "user-1"); err == nil {
+		t.Fatal("expected the server error from Delete")
+	}
+
+	// Ответ-ошибка не нарушает протокол, поэтому соединения пула остаются рабочими
+	server.failWith("")
+
+	if err := backend.Set(ctx, "user-1", profile{Name: "Alice"}, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackendRejectsUndecodableValues(t *testing.T) {
+	server := newFakeRedis(t)
+	backend := newBackend(t, server)
+
+	server.mutex.Lock()
+	server.values["profiles:broken"] = "not json"
+	server.mutex.Unlock()
+
+	if _, ok, err := backend.Get(context.Background(), "broken"); ok || err == nil {
+		t.Fatalf("expected a decode error, got %v, %v", ok, err)
+	}
+}
+
+func TestBackendFailsWhenServerIsDown(t *testing.T) {
+	server := newFakeRedis(t)
+	backend := newBackend(t, server)
+
+	server.listener.Close()
+	server.disconnect()
+
+	if _, _, err := backend.Get(context.Background(), "user-1"); err == nil {
+		t.Fatal("expected Get to fail without a server")
+	}
+}
+
+func TestBackendAsSecondTier(t *testing.T) {
+	server := newFakeRedis(t)
+	backend := newBackend(t, server)
+
+	l1, err := cache.NewCache[string, profile](cache.WithTTL(time.Minute), cache.WithoutBackgroundGC())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(l1.Close)
+
+	tiered, err := cache.NewTiered[string, profile](l1, backend, time.Hour)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tiered.Set("user-1", profile{Name: "Alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// После удаления из L1 значение читается из Redis
+	l1.Delete("user-1")
+
+	if value, ok := tiered.Get("user-1"); !ok || value.Name != "Alice" {
+		t.Fatalf("expected the value from Redis, got %+v, %v", value, ok)
+	}
+
+	if stats := tiered.Stats(); stats.L2Hits != 1 {
+		t.Fatalf("expected an L2 hit, got %+v", stats)
+	}
+}
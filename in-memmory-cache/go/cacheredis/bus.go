@@ -0,0 +1,310 @@
+/*
+ * Пакет шины сообщений об изменении значений (`cache.WithInvalidationBus`) поверх Redis pub/sub.
+ * Пакет не зависит от клиентской библиотеки Redis: команды `PUBLISH` и `SUBSCRIBE` передаются
+ * по протоколу RESP напрямую
+ */
+package cacheredis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cache "golang-cache"
+)
+
+const (
+	// Пауза перед повторным подключением подписки после разрыва соединения
+	reconnectDelay = time.Second
+
+	// Время ожидания ответа на `PUBLISH`, если контекст отправки не задает срок
+	publishTimeout = 5 * time.Second
+)
+
+/*
+ * Шина сообщений поверх канала Redis pub/sub. Сообщения отправляются через пул соединений клиента,
+ * а подписка использует отдельное соединение, которое переподключается после разрыва. Сообщения,
+ * отправленные во время разрыва подписки, не доставляются, поэтому время жизни значений остается
+ * последней защитой от устаревших данных
+ */
+type Bus struct {
+	addr    string
+	channel string
+	dialer  net.Dialer
+	client  *client
+}
+
+// Функция-конструктор шины для сервера Redis по адресу `addr` и канала `channel`
+func NewBus(addr, channel string) *Bus {
+	return &Bus{addr: addr, channel: channel, client: &client{addr: addr}}
+}
+
+/*
+ * Функция отправки сообщения в канал. Отправка ожидает ответ сервера, поэтому ошибка Redis
+ * или разрыв соединения возвращаются вызывающему и учитываются кэшем в `Stats.DeadLetters`.
+ * Без срока в контексте ответ ожидается не дольше `publishTimeout`
+ */
+func (bus *Bus) Publish(ctx context.Context, message cache.Invalidation) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, publishTimeout)
+		defer cancel()
+	}
+
+	reply, err := bus.client.do(ctx, "PUBLISH", bus.channel, encode(message))
+
+	if err != nil {
+		return err
+	}
+
+	if _, ok := reply.(int64); !ok {
+		return fmt.Errorf("cacheredis: unexpected PUBLISH reply %T", reply)
+	}
+
+	return nil
+}
+
+/*
+ * Функция подписки на канал. Первое подключение выполняется синхронно, и его ошибка возвращается,
+ * а после разрыва соединения подписка переподключается в фоне до отмены
+ */
+func (bus *Bus) Subscribe(fn func(cache.Invalidation)) (func(), error) {
+	conn, err := bus.subscribe()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mutex   sync.Mutex
+		current = conn
+		stop    = make(chan struct{})
+	)
+
+	go func() {
+		for {
+			bus.receive(conn, fn)
+
+			for {
+				select {
+				case <-stop:
+					return
+				case <-time.After(reconnectDelay):
+				}
+
+				if conn, err = bus.subscribe(); err == nil {
+					break
+				}
+			}
+
+			mutex.Lock()
+
+			// Подписка отменена во время подключения - закрываем новое соединение
+			select {
+			case <-stop:
+				mutex.Unlock()
+				conn.conn.Close()
+
+				return
+			default:
+			}
+
+			current = conn
+
+			mutex.Unlock()
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			mutex.Lock()
+			close(stop)
+			current.conn.Close()
+			mutex.Unlock()
+		})
+	}, nil
+}
+
+// Функция закрытия соединений для отправки сообщений. Подписки отменяются отдельно
+func (bus *Bus) Close() error {
+	bus.client.close()
+
+	return nil
+}
+
+/*
+ * Функция подключения к серверу и подписки на канал. Подписка считается выполненной после
+ * подтверждения сервера, которое ожидается не дольше `publishTimeout`
+ */
+func (bus *Bus) subscribe() (*clientConn, error) {
+	conn, err := bus.dialer.Dial("tcp", bus.addr)
+
+	if err != nil {
+		return nil, fmt.Errorf("cacheredis: dial %s: %w", bus.addr, err)
+	}
+
+	cc := &clientConn{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+
+	if err := cc.subscribe(bus.channel); err != nil {
+		conn.Close()
+
+		return nil, fmt.Errorf("cacheredis: SUBSCRIBE: %w", err)
+	}
+
+	return cc, nil
+}
+
+func (cc *clientConn) subscribe(channel string) error {
+	if err := cc.conn.SetDeadline(time.Now().Add(publishTimeout)); err != nil {
+		return err
+	}
+
+	writeCommand(cc.writer, "SUBSCRIBE", channel)
+
+	if err := cc.writer.Flush(); err != nil {
+		return err
+	}
+
+	reply, err := readReply(cc.reader)
+
+	if err != nil {
+		return err
+	}
+
+	// Подтверждение подписки: ["subscribe", channel, count]
+	if items, ok := reply.([]any); !ok || len(items) != 3 || items[0] != "subscribe" {
+		return fmt.Errorf("unexpected reply %v", reply)
+	}
+
+	return cc.conn.SetDeadline(time.Time{})
+}
+
+// Функция чтения сообщений подписки до разрыва соединения
+func (bus *Bus) receive(cc *clientConn, fn func(cache.Invalidation)) {
+	defer cc.conn.Close()
+
+	for {
+		reply, err := readReply(cc.reader)
+
+		if err != nil {
+			return
+		}
+
+		// Сообщение канала: ["message", channel, payload]
+		items, ok := reply.([]any)
+
+		if !ok || len(items) != 3 || items[0] != "message" {
+			continue
+		}
+
+		payload, _ := items[2].(string)
+
+		if message, ok := decode(payload); ok {
+			fn(message)
+		}
+	}
+}
+
+// Ответ-ошибка сервера Redis
+type replyError string
+
+func (err replyError) Error() string {
+	return "cacheredis: " + string(err)
+}
+
+// Сообщение передается в канал строкой `<source>\n<key>`
+func encode(message cache.Invalidation) string {
+	return message.Source + "\n" + message.Key
+}
+
+func decode(payload string) (cache.Invalidation, bool) {
+	source, key, ok := strings.Cut(payload, "\n")
+
+	return cache.Invalidation{Source: source, Key: key}, ok
+}
+
+// Функция записи команды массивом строк RESP
+func writeCommand(writer *bufio.Writer, args ...string) {
+	fmt.Fprintf(writer, "*%d\r\n", len(args))
+
+	for _, arg := range args {
+		fmt.Fprintf(writer, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+/*
+ * Функция чтения ответа RESP. Строки возвращаются как `string`, числа как `int64`,
+ * массивы как `[]any`, а ответ-ошибка - как ошибка
+ */
+func readReply(reader *bufio.Reader) (any, error) {
+	line, err := reader.ReadString('\n')
+
+	if err != nil {
+		return nil, err
+	}
+
+	line = strings.TrimRight(line, "\r\n")
+
+	if line == "" {
+		return nil, errors.New("cacheredis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, replyError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+
+		if err != nil {
+			return nil, err
+		}
+
+		if size < 0 {
+			return nil, nil
+		}
+
+		data := make([]byte, size+2)
+
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+
+		if err != nil {
+			return nil, err
+		}
+
+		items := make([]any, 0, max(count, 0))
+
+		for range count {
+			item, err := readReply(reader)
+
+			if err != nil {
+				return nil, err
+			}
+
+			items = append(items, item)
+		}
+
+		return items, nil
+	default:
+		return nil, fmt.Errorf("cacheredis: unexpected reply %q", line)
+	}
+}
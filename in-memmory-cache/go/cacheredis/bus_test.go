@@ -0,0 +1,419 @@
+package cacheredis_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cacheredis"
+)
+
+/*
+ * Сервер Redis в памяти процесса с командами `GET`, `SET`, `DEL`, `PUBLISH` и `SUBSCRIBE`.
+ * Сервер может отвечать ошибкой на все команды и разрывать открытые соединения
+ */
+type fakeRedis struct {
+	listener net.Listener
+
+	mutex       sync.Mutex
+	values      map[string]string
+	ttls        map[string]string
+	conns       map[net.Conn]bool
+	subscribers map[string][]net.Conn
+	fail        string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &fakeRedis{
+		listener:    listener,
+		values:      make(map[string]string),
+		ttls:        make(map[string]string),
+		conns:       make(map[net.Conn]bool),
+		subscribers: make(map[string][]net.Conn),
+	}
+
+	go server.serve()
+
+	t.Cleanup(func() {
+		listener.Close()
+		server.disconnect()
+	})
+
+	return server
+}
+
+func (server *fakeRedis) addr() string {
+	return server.listener.Addr().String()
+}
+
+// Функция установки ошибки, которой сервер отвечает на все команды. Пустая строка восстанавливает работу
+func (server *fakeRedis) failWith(message string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.fail = message
+}
+
+// Функция разрыва всех открытых соединений, включая подписки
+func (server *fakeRedis) disconnect() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	for conn := range server.conns {
+		conn.Close()
+	}
+
+	clear(server.conns)
+	clear(server.subscribers)
+}
+
+// Функция получения количества подписчиков канала
+func (server *fakeRedis) subscribed(channel string) int {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	return len(server.subscribers[channel])
+}
+
+func (server *fakeRedis) get(key string) (value, ttl string, ok bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	value, ok = server.values[key]
+
+	return value, server.ttls[key], ok
+}
+
+func (server *fakeRedis) serve() {
+	for {
+		conn, err := server.listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		server.mutex.Lock()
+		server.conns[conn] = true
+		server.mutex.Unlock()
+
+		go server.serveConn(conn)
+	}
+}
+
+func (server *fakeRedis) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+
+	for {
+		args, err := readCommand(reader)
+
+		if err != nil {
+			return
+		}
+
+		server.mutex.Lock()
+		reply := server.execute(conn, args)
+		server.mutex.Unlock()
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// Функция выполнения команды под блокировкой сервера и кодирования ответа
+func (server *fakeRedis) execute(conn net.Conn, args []string) string {
+	if server.fail != "" {
+		return "-" + server.fail + "\r\n"
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		value, ok := server.values[args[1]]
+
+		if !ok {
+			return "$-1\r\n"
+		}
+
+		return bulk(value)
+	case "SET":
+		server.values[args[1]] = args[2]
+		delete(server.ttls, args[1])
+
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			server.ttls[args[1]] = args[4]
+		}
+
+		return "+OK\r\n"
+	case "DEL":
+		_, ok := server.values[args[1]]
+
+		delete(server.values, args[1])
+		delete(server.ttls, args[1])
+
+		if ok {
+			return ":1\r\n"
+		}
+
+		return ":0\r\n"
+	case "PUBLISH":
+		subscribers := server.subscribers[args[1]]
+
+		for _, subscriber := range subscribers {
+			io.WriteString(subscriber, "*3\r\n"+bulk("message")+bulk(args[1])+bulk(args[2]))
+		}
+
+		return ":" + strconv.Itoa(len(subscribers)) + "\r\n"
+	case "SUBSCRIBE":
+		server.subscribers[args[1]] = append(server.subscribers[args[1]], conn)
+
+		return "*3\r\n" + bulk("subscribe") + bulk(args[1]) + ":1\r\n"
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+func bulk(value string) string {
+	return "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+}
+
+// Функция чтения команды, переданной массивом строк RESP
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	var count int
+
+	if _, err := fmt.Fscanf(reader, "*%d\r\n", &count); err != nil {
+		return nil, err
+	}
+
+	args := make([]string, count)
+
+	for i := range args {
+		var size int
+
+		if _, err := fmt.Fscanf(reader, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+
+		data := make([]byte, size+2)
+
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+
+		args[i] = string(data[:size])
+	}
+
+	return args, nil
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Функция подписки на шину с передачей сообщений в канал
+func subscribe(t *testing.T, bus *cacheredis.Bus) <-chan cache.Invalidation {
+	t.Helper()
+
+	messages := make(chan cache.Invalidation, 16)
+
+	unsubscribe, err := bus.Subscribe(func(message cache.Invalidation) { messages <- message })
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(unsubscribe)
+
+	return messages
+}
+
+func receive(t *testing.T, messages <-chan cache.Invalidation) cache.Invalidation {
+	t.Helper()
+
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a message from the bus")
+
+		return cache.Invalidation{}
+	}
+}
+
+func TestBusDeliversPublishedMessages(t *testing.T) {
+	server := newFakeRedis(t)
+
+	bus := cacheredis.NewBus(server.addr(), "invalidation")
+	t.Cleanup(func() { bus.Close() })
+
+	messages := subscribe(t, bus)
+
+	sent := cache.Invalidation{Source: "instance", Key: "user:1"}
+
+	if err := bus.Publish(context.Background(), sent); err != nil {
+		t.Fatal(err)
+	}
+
+	if message := receive(t, messages); message != sent {
+		t.Fatalf("expected %+v, got %+v", sent, message)
+	}
+}
+
+func TestBusPublishReturnsServerErrors(t *testing.T) {
+	server := newFakeRedis(t)
+
+	bus := cacheredis.NewBus(server.addr(), "invalidation")
+	t.Cleanup(func() { bus.Close() })
+
+	server.failWith("ERR publish is disabled")
+
+	err := bus.Publish(context.Background(), cache.Invalidation{Source: "instance", Key: "key"})
+
+	if err == nil || !strings.Contains(err.Error(), "publish is disabled") {
+		t.Fatalf("expected the server error, got %v", err)
+	}
+
+	// Ответ-ошибка не нарушает протокол, поэтому следующая отправка проходит по тому же соединению
+	server.failWith("")
+
+	if err := bus.Publish(context.Background(), cache.Invalidation{Source: "instance", Key: "key"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBusPublishFailsWhenServerIsDown(t *testing.T) {
+	server := newFakeRedis(t)
+
+	bus := cacheredis.NewBus(server.addr(), "invalidation")
+	t.Cleanup(func() { bus.Close() })
+
+	server.listener.Close()
+	server.disconnect()
+
+	if err := bus.Publish(context.Background(), cache.Invalidation{Source: "instance", Key: "key"}); err == nil {
+		t.Fatal("expected Publish to fail without a server")
+	}
+}
+
+func TestBusSubscribeReturnsServerErrors(t *testing.T) {
+	server := newFakeRedis(t)
+
+	server.failWith("NOPERM no permissions to access a channel")
+
+	bus := cacheredis.NewBus(server.addr(), "invalidation")
+
+	if _, err := bus.Subscribe(func(cache.Invalidation) {}); err == nil || !strings.Contains(err.Error(), "NOPERM") {
+		t.Fatalf("expected the subscription error, got %v", err)
+	}
+}
+
+func TestBusResubscribesAfterDisconnect(t *testing.T) {
+	server := newFakeRedis(t)
+
+	bus := cacheredis.NewBus(server.addr(), "invalidation")
+	t.Cleanup(func() { bus.Close() })
+
+	messages := subscribe(t, bus)
+
+	server.disconnect()
+
+	// Подписка переподключается после паузы `reconnectDelay`
+	eventually(t, func() bool { return server.subscribed("invalidation") == 1 })
+
+	sent := cache.Invalidation{Source: "instance", Key: "user:1"}
+
+	// Простаивающее соединение отправки разорвано сервером, поэтому первая отправка может завершиться ошибкой
+	eventually(t, func() bool { return bus.Publish(context.Background(), sent) == nil })
+
+	if message := receive(t, messages); message != sent {
+		t.Fatalf("expected %+v, got %+v", sent, message)
+	}
+}
+
+func TestBusUnsubscribeStopsDelivery(t *testing.T) {
+	server := newFakeRedis(t)
+
+	bus := cacheredis.NewBus(server.addr(), "invalidation")
+	t.Cleanup(func() { bus.Close() })
+
+	unsubscribe, err := bus.Subscribe(func(cache.Invalidation) { t.Error("expected no messages after unsubscribe") })
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unsubscribe()
+
+	// Соединение подписки закрыто при отмене, поэтому сообщение не доходит до функции подписчика
+	if err := bus.Publish(context.Background(), cache.Invalidation{Source: "instance", Key: "key"}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+}
+
+// Функция создания кэша, подписанного на шину сервера
+func newInstance(t *testing.T, server *fakeRedis) *cache.Cache[string, int] {
+	t.Helper()
+
+	bus := cacheredis.NewBus(server.addr(), "invalidation")
+	t.Cleanup(func() { bus.Close() })
+
+	values, err := cache.NewCache[string, int](cache.WithInvalidationBus(bus), cache.WithoutBackgroundGC())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(values.Close)
+
+	return values
+}
+
+func TestBusInvalidatesOtherInstances(t *testing.T) {
+	server := newFakeRedis(t)
+
+	// Значение записывается до подписки второго экземпляра, поэтому единственное сообщение, которое он получит, - запись ниже
+	reader := newInstance(t, server)
+	reader.Set("key", 1)
+
+	writer := newInstance(t, server)
+	writer.Set("key", 2)
+
+	eventually(t, func() bool {
+		_, ok := reader.Peek("key")
+
+		return !ok
+	})
+
+	if value, ok := writer.Peek("key"); !ok || value != 2 {
+		t.Fatalf("expected the writer to skip its own message, got %d, %v", value, ok)
+	}
+}
@@ -0,0 +1,119 @@
+package cacheredis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Максимальное количество простаивающих соединений клиента
+const maxIdleConns = 8
+
+/*
+ * Клиент запросов к Redis с пулом соединений. Каждая команда выполняется на отдельном соединении
+ * из пула, а соединение с ошибкой закрывается и не возвращается в пул
+ */
+type client struct {
+	addr   string
+	dialer net.Dialer
+
+	mutex sync.Mutex
+	idle  []*clientConn
+}
+
+type clientConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// Функция выполнения команды и чтения ответа
+func (client *client) do(ctx context.Context, args ...string) (any, error) {
+	cc, err := client.get(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, _ := ctx.Deadline()
+
+	if err := cc.conn.SetDeadline(deadline); err != nil {
+		cc.conn.Close()
+
+		return nil, err
+	}
+
+	writeCommand(cc.writer, args...)
+
+	if err := cc.writer.Flush(); err != nil {
+		cc.conn.Close()
+
+		return nil, fmt.Errorf("cacheredis: %s: %w", args[0], err)
+	}
+
+	reply, err := readReply(cc.reader)
+
+	// Ответ-ошибка Redis не нарушает протокол, поэтому соединение можно использовать дальше
+	if _, ok := err.(replyError); err != nil && !ok {
+		cc.conn.Close()
+
+		return nil, fmt.Errorf("cacheredis: %s: %w", args[0], err)
+	}
+
+	client.put(cc)
+
+	return reply, err
+}
+
+func (client *client) get(ctx context.Context) (*clientConn, error) {
+	client.mutex.Lock()
+
+	if n := len(client.idle); n > 0 {
+		cc := client.idle[n-1]
+		client.idle = client.idle[:n-1]
+
+		client.mutex.Unlock()
+
+		return cc, nil
+	}
+
+	client.mutex.Unlock()
+
+	conn, err := client.dialer.DialContext(ctx, "tcp", client.addr)
+
+	if err != nil {
+		return nil, fmt.Errorf("cacheredis: dial %s: %w", client.addr, err)
+	}
+
+	return &clientConn{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}, nil
+}
+
+func (client *client) put(cc *clientConn) {
+	_ = cc.conn.SetDeadline(time.Time{})
+
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if len(client.idle) >= maxIdleConns {
+		cc.conn.Close()
+
+		return
+	}
+
+	client.idle = append(client.idle, cc)
+}
+
+// Функция закрытия простаивающих соединений
+func (client *client) close() {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	for _, cc := range client.idle {
+		cc.conn.Close()
+	}
+
+	client.idle = nil
+}
@@ -0,0 +1,457 @@
+/*
+ * Пакет сервера протокола Redis (RESP) поверх кэш-хранилища. Сервер поддерживает команды
+ * `GET`, `SET`, `DEL`, `EXPIRE`, `TTL`, `KEYS`, `PING` и `QUIT`, поэтому `redis-cli` и стандартные
+ * клиенты Redis можно направить на кэш для отладки и в легковесных развертываниях
+ */
+package cacheresp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	cache "golang-cache"
+)
+
+const (
+	// Максимальное количество аргументов команды
+	maxArgs = 1024
+
+	// Максимальная длина строки протокола вместе с `\r\n`: встроенной команды или заголовка массива и аргумента
+	maxLineLength = 64 << 10
+
+	// Максимальное время жизни `SET` и `EXPIRE`, которое помещается в `time.Duration`
+	maxTTL = time.Duration(math.MaxInt64)
+)
+
+const (
+	// Максимальный размер аргумента команды по умолчанию (`WithMaxArgSize`)
+	DefaultMaxArgSize = 1 << 20
+
+	// Максимальный суммарный размер аргументов команды по умолчанию (`WithMaxCommandSize`)
+	DefaultMaxCommandSize = 16 << 20
+)
+
+/*
+ * Функциональная опция сервера протокола Redis. Опция проверяет переданные значения
+ * и возвращает ошибку, если они некорректны
+ */
+type Option func(*options) error
+
+type options struct {
+	maxArgSize     int
+	maxCommandSize int
+}
+
+/*
+ * Опция максимального размера аргумента команды, например значения `SET`. Команда с аргументом
+ * большего размера считается ошибкой протокола. По умолчанию `DefaultMaxArgSize`
+ */
+func WithMaxArgSize(size int) Option {
+	return func(o *options) error {
+		if size <= 0 {
+			return fmt.Errorf("cacheresp: max arg size must be positive, got %d", size)
+		}
+
+		o.maxArgSize = size
+
+		return nil
+	}
+}
+
+/*
+ * Опция максимального суммарного размера аргументов одной команды. Ограничение на каждый аргумент
+ * (`WithMaxArgSize`) при 1024 аргументах допускает команду около гигабайта, поэтому общий размер
+ * ограничивается отдельно. Команда большего размера считается ошибкой протокола. По умолчанию `DefaultMaxCommandSize`
+ */
+func WithMaxCommandSize(size int) Option {
+	return func(o *options) error {
+		if size <= 0 {
+			return fmt.Errorf("cacheresp: max command size must be positive, got %d", size)
+		}
+
+		o.maxCommandSize = size
+
+		return nil
+	}
+}
+
+/*
+ * Сервер протокола Redis поверх кэша строк. `SET` без `EX`/`PX` использует время жизни кэша,
+ * поэтому `TTL` никогда не возвращает `-1`, а шаблон `KEYS` разбирается по правилам `path.Match`,
+ * в которых `*` не совпадает с `/`
+ */
+type Server struct {
+	cache          *cache.Cache[string, []byte]
+	maxArgSize     int
+	maxCommandSize int
+}
+
+// Функция-конструктор сервера протокола Redis поверх кэша
+func NewServer(c *cache.Cache[string, []byte], opts ...Option) (*Server, error) {
+	o := &options{maxArgSize: DefaultMaxArgSize, maxCommandSize: DefaultMaxCommandSize}
+
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Server{cache: c, maxArgSize: o.maxArgSize, maxCommandSize: o.maxCommandSize}, nil
+}
+
+/*
+ * Функция приема соединений. Каждое соединение обслуживается в отдельной горутине. Возвращает
+ * ошибку приема соединения, в том числе после закрытия `listener`
+ */
+func (server *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+
+		if err != nil {
+			return err
+		}
+
+		go server.ServeConn(conn)
+	}
+}
+
+// Функция обслуживания одного соединения до команды `QUIT`, закрытия соединения клиентом или ошибки протокола
+func (server *Server) ServeConn(conn net.Conn) {
+	defer conn.Close()
+
+	// Размер буфера чтения ограничивает длину строки протокола
+	reader := bufio.NewReaderSize(conn, maxLineLength)
+	writer := bufio.NewWriter(conn)
+
+	for {
+		args, err := server.readCommand(reader)
+
+		if err != nil {
+			// Ошибку протокола сообщаем клиенту, после чего закрываем соединение,
+			// поскольку границу следующей команды определить уже нельзя
+			if err != io.EOF {
+				writeError(writer, "ERR Protocol error: "+err.Error())
+				writer.Flush()
+			}
+
+			return
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := strings.EqualFold(args[0], "quit")
+
+		if quit {
+			writeSimple(writer, "OK")
+		} else {
+			server.execute(args, writer)
+		}
+
+		if err := writer.Flush(); err != nil || quit {
+			return
+		}
+	}
+}
+
+// Функция выполнения команды с записью ответа клиенту
+func (server *Server) execute(args []string, writer *bufio.Writer) {
+	name := strings.ToUpper(args[0])
+
+	switch name {
+	case "PING":
+		if len(args) > 1 {
+			writeBulk(writer, []byte(args[1]))
+		} else {
+			writeSimple(writer, "PONG")
+		}
+	case "GET":
+		if !checkArity(writer, args, 2, 2) {
+			return
+		}
+
+		value, ok := server.cache.Get(args[1])
+
+		if !ok {
+			writer.WriteString("$-1\r\n")
+
+			return
+		}
+
+		writeBulk(writer, value)
+	case "SET":
+		if !checkArity(writer, args, 3, 5) {
+			return
+		}
+
+		server.set(args, writer)
+	case "DEL":
+		if !checkArity(writer, args, 2, maxArgs) {
+			return
+		}
+
+		deleted := 0
+
+		for _, key := range args[1:] {
+			if server.cache.Delete(key) {
+				deleted++
+			}
+		}
+
+		writeInteger(writer, int64(deleted))
+	case "EXPIRE":
+		if !checkArity(writer, args, 3, 3) {
+			return
+		}
+
+		seconds, err := strconv.ParseInt(args[2], 10, 64)
+
+		if err != nil {
+			writeError(writer, "ERR value is not an integer or out of range")
+
+			return
+		}
+
+		if seconds > int64(maxTTL/time.Second) {
+			writeError(writer, "ERR invalid expire time in 'expire' command")
+
+			return
+		}
+
+		var ok bool
+
+		// Неположительное время жизни удаляет значение, как и в Redis
+		if seconds <= 0 {
+			ok = server.cache.Delete(args[1])
+		} else {
+			ok = server.cache.Expire(args[1], time.Duration(seconds)*time.Second)
+		}
+
+		writeInteger(writer, boolInteger(ok))
+	case "TTL":
+		if !checkArity(writer, args, 2, 2) {
+			return
+		}
+
+		ttl, ok := server.cache.TTL(args[1])
+
+		if !ok {
+			writeInteger(writer, -2)
+
+			return
+		}
+
+		// Как и в Redis, ключ без истечения имеет TTL -1
+		if ttl == cache.NoExpiration {
+			writeInteger(writer, -1)
+
+			return
+		}
+
+		writeInteger(writer, int64((ttl+time.Second-1)/time.Second))
+	case "KEYS":
+		if !checkArity(writer, args, 2, 2) {
+			return
+		}
+
+		keys, err := server.cache.KeysMatching(args[1])
+
+		if err != nil {
+			writeError(writer, "ERR "+err.Error())
+
+			return
+		}
+
+		fmt.Fprintf(writer, "*%d\r\n", len(keys))
+
+		for _, key := range keys {
+			writeBulk(writer, []byte(key))
+		}
+	case "COMMAND":
+		// `redis-cli` запрашивает описание команд при подключении, пустой ответ его устраивает
+		writer.WriteString("*0\r\n")
+	default:
+		writeError(writer, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+}
+
+// SET key value [EX seconds | PX milliseconds]
+func (server *Server) set(args []string, writer *bufio.Writer) {
+	value := []byte(args[2])
+
+	if len(args) == 3 {
+		server.cache.Set(args[1], value)
+		writeSimple(writer, "OK")
+
+		return
+	}
+
+	if len(args) != 5 {
+		writeError(writer, "ERR syntax error")
+
+		return
+	}
+
+	amount, err := strconv.ParseInt(args[4], 10, 64)
+
+	if err != nil {
+		writeError(writer, "ERR value is not an integer or out of range")
+
+		return
+	}
+
+	var unit time.Duration
+
+	switch strings.ToUpper(args[3]) {
+	case "EX":
+		unit = time.Second
+	case "PX":
+		unit = time.Millisecond
+	default:
+		writeError(writer, "ERR syntax error")
+
+		return
+	}
+
+	// Время жизни, которое не помещается в `time.Duration`, отклоняется, а не переполняется
+	if amount <= 0 || amount > int64(maxTTL/unit) {
+		writeError(writer, "ERR invalid expire time in 'set' command")
+
+		return
+	}
+
+	server.cache.SetWithTTL(args[1], value, time.Duration(amount)*unit)
+	writeSimple(writer, "OK")
+}
+
+/*
+ * Функция чтения команды: массива строк RESP (`*2\r\n$3\r\nGET\r\n$1\r\nk\r\n`)
+ * либо встроенной команды, разделенной пробелами (`GET k\r\n`), как при работе через telnet.
+ * Аргумент читается порциями по мере поступления данных, поэтому заявленная клиентом длина
+ * не приводит к выделению памяти заранее
+ */
+func (server *Server) readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+
+	if err != nil || count > maxArgs {
+		return nil, fmt.Errorf("invalid multibulk length")
+	}
+
+	args := make([]string, 0, max(count, 0))
+
+	// Суммарный размер аргументов, прочитанных к текущему моменту (`WithMaxCommandSize`)
+	total := 0
+
+	for range count {
+		line, err := readLine(reader)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("expected '$', got '%.1s'", line)
+		}
+
+		size, err := strconv.Atoi(line[1:])
+
+		if err != nil || size < 0 || size > server.maxArgSize {
+			return nil, fmt.Errorf("invalid bulk length")
+		}
+
+		if total += size; total > server.maxCommandSize {
+			return nil, fmt.Errorf("command too large")
+		}
+
+		var data bytes.Buffer
+
+		if _, err := io.CopyN(&data, reader, int64(size)); err != nil {
+			return nil, err
+		}
+
+		var crlf [2]byte
+
+		if _, err := io.ReadFull(reader, crlf[:]); err != nil {
+			return nil, err
+		}
+
+		if string(crlf[:]) != "\r\n" {
+			return nil, fmt.Errorf("expected CRLF after bulk string")
+		}
+
+		args = append(args, data.String())
+	}
+
+	return args, nil
+}
+
+// Функция чтения строки протокола без завершающего `\r\n`. Строка длиннее буфера `reader` считается ошибкой протокола
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadSlice('\n')
+
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", fmt.Errorf("too big request line")
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// Функция проверки количества аргументов команды с записью ошибки клиенту
+func checkArity(writer *bufio.Writer, args []string, least, most int) bool {
+	if len(args) < least || len(args) > most {
+		writeError(writer, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(args[0])))
+
+		return false
+	}
+
+	return true
+}
+
+func writeSimple(writer *bufio.Writer, value string) {
+	writer.WriteString("+" + value + "\r\n")
+}
+
+func writeError(writer *bufio.Writer, message string) {
+	writer.WriteString("-" + message + "\r\n")
+}
+
+func writeInteger(writer *bufio.Writer, value int64) {
+	fmt.Fprintf(writer, ":%d\r\n", value)
+}
+
+func writeBulk(writer *bufio.Writer, value []byte) {
+	fmt.Fprintf(writer, "$%d\r\n", len(value))
+	writer.Write(value)
+	writer.WriteString("\r\n")
+}
+
+func boolInteger(value bool) int64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,160 @@
+package cacheresp_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	cache "golang-cache"
+	"golang-cache/cacheresp"
+)
+
+// Функция подключения клиента к серверу через соединение в памяти процесса
+func dial(t *testing.T, opts ...cacheresp.Option) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	values, err := cache.NewCache[string, []byte](cache.WithoutBackgroundGC())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(values.Close)
+
+	server, err := cacheresp.NewServer(values, opts...)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, conn := net.Pipe()
+
+	go server.ServeConn(conn)
+
+	t.Cleanup(func() { client.Close() })
+
+	return client, bufio.NewReader(client)
+}
+
+// Функция кодирования команды массивом строк RESP
+func command(args ...string) string {
+	var b strings.Builder
+
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+
+	return b.String()
+}
+
+// Функция отправки команды и чтения ответа из `lines` строк
+func roundTrip(t *testing.T, conn net.Conn, reader *bufio.Reader, request string, lines int) string {
+	t.Helper()
+
+	if _, err := io.WriteString(conn, request); err != nil {
+		t.Fatal(err)
+	}
+
+	var reply strings.Builder
+
+	for range lines {
+		line, err := reader.ReadString('\n')
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reply.WriteString(line)
+	}
+
+	return reply.String()
+}
+
+func TestCommands(t *testing.T) {
+	conn, reader := dial(t)
+
+	for _, step := range []struct {
+		request string
+		lines   int
+		reply   string
+	}{
+		{command("PING"), 1, "+PONG\r\n"},
+		{command("GET", "user"), 1, "$-1\r\n"},
+		{command("SET", "user", "Alice", "EX", "10"), 1, "+OK\r\n"},
+		{command("GET", "user"), 2, "$5\r\nAlice\r\n"},
+		{command("TTL", "user"), 1, ":10\r\n"},
+		{command("KEYS", "us*"), 3, "*1\r\n$4\r\nuser\r\n"},
+		{command("SET", "user", "Alice", "EX", "0"), 1, "-ERR invalid expire time in 'set' command\r\n"},
+		// Время жизни, которое переполнило бы `time.Duration`, отклоняется
+		{command("SET", "user", "Alice", "EX", "9223372037"), 1, "-ERR invalid expire time in 'set' command\r\n"},
+		{command("SET", "user", "Alice", "PX", "9223372036855"), 1, "-ERR invalid expire time in 'set' command\r\n"},
+		{command("EXPIRE", "user", "9223372037"), 1, "-ERR invalid expire time in 'expire' command\r\n"},
+		{command("TTL", "user"), 1, ":10\r\n"},
+		{command("DEL", "user", "missing"), 1, ":1\r\n"},
+		{command("TTL", "user"), 1, ":-2\r\n"},
+		{command("FLUSHALL"), 1, "-ERR unknown command 'FLUSHALL'\r\n"},
+	} {
+		if reply := roundTrip(t, conn, reader, step.request, step.lines); reply != step.reply {
+			t.Fatalf("%q: expected %q, got %q", step.request, step.reply, reply)
+		}
+	}
+}
+
+func TestArgumentLargerThanLimitIsRejected(t *testing.T) {
+	conn, reader := dial(t, cacheresp.WithMaxArgSize(4))
+
+	reply := roundTrip(t, conn, reader, "*2\r\n$3\r\nGET\r\n$5\r\n", 1)
+
+	if !strings.HasPrefix(reply, "-ERR Protocol error") {
+		t.Fatalf("expected protocol error, got %q", reply)
+	}
+
+	// После ошибки протокола сервер закрывает соединение
+	if _, err := reader.ReadString('\n'); err != io.EOF {
+		t.Fatalf("expected connection to be closed, got %v", err)
+	}
+}
+
+func TestCommandLargerThanLimitIsRejected(t *testing.T) {
+	conn, reader := dial(t, cacheresp.WithMaxArgSize(4), cacheresp.WithMaxCommandSize(6))
+
+	// Каждый аргумент меньше ограничения, но вместе они его превышают
+	reply := roundTrip(t, conn, reader, "*3\r\n$3\r\nSET\r\n$4\r\n", 1)
+
+	if reply != "-ERR Protocol error: command too large\r\n" {
+		t.Fatalf("expected command too large error, got %q", reply)
+	}
+}
+
+func TestBulkStringWithoutCRLFIsRejected(t *testing.T) {
+	conn, reader := dial(t)
+
+	reply := roundTrip(t, conn, reader, "*1\r\n$4\r\nPINGxx", 1)
+
+	if reply != "-ERR Protocol error: expected CRLF after bulk string\r\n" {
+		t.Fatalf("expected CRLF protocol error, got %q", reply)
+	}
+}
+
+func TestMaxArgSizeMustBePositive(t *testing.T) {
+	values, err := cache.NewCache[string, []byte](cache.WithoutBackgroundGC())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer values.Close()
+
+	if _, err := cacheresp.NewServer(values, cacheresp.WithMaxArgSize(0)); err == nil {
+		t.Fatal("expected error for non-positive max arg size")
+	}
+
+	if _, err := cacheresp.NewServer(values, cacheresp.WithMaxCommandSize(-1)); err == nil {
+		t.Fatal("expected error for non-positive max command size")
+	}
+}
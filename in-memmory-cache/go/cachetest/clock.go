@@ -0,0 +1,103 @@
+/*
+ * Пакет вспомогательных средств для тестирования кода, использующего кэш-хранилище
+ */
+package cachetest
+
+import (
+	"sync"
+	"time"
+
+	cache "golang-cache"
+)
+
+/*
+ * Управляемые часы для `cache.WithClock`. Время стоит на месте, пока тест не сдвинет его вызовом
+ * `Advance`, поэтому истечение значений и срабатывание сборщика мусора проверяются детерминированно
+ * и без ожидания. Тикеры, как и `time.Ticker`, пропускают срабатывания, которые никто не успел прочитать
+ */
+type FakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// Тикер управляемых часов
+type fakeTicker struct {
+	clock  *FakeClock
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+// Функция-конструктор управляемых часов, показывающих время `start`
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+func (clock *FakeClock) Now() time.Time {
+	clock.mutex.Lock()
+
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+func (clock *FakeClock) Ticker(d time.Duration) cache.Ticker {
+	clock.mutex.Lock()
+
+	defer clock.mutex.Unlock()
+
+	ticker := &fakeTicker{
+		clock:  clock,
+		c:      make(chan time.Time, 1),
+		period: d,
+		next:   clock.now.Add(d),
+	}
+
+	clock.tickers = append(clock.tickers, ticker)
+
+	return ticker
+}
+
+/*
+ * Функция сдвига часов вперед на `d`. Тикеры, интервал которых истек, срабатывают. Сдвиг сразу на
+ * несколько интервалов приводит к одному срабатыванию, как у `time.Ticker` с медленным получателем
+ */
+func (clock *FakeClock) Advance(d time.Duration) {
+	clock.mutex.Lock()
+
+	defer clock.mutex.Unlock()
+
+	clock.now = clock.now.Add(d)
+
+	for _, ticker := range clock.tickers {
+		for !ticker.next.After(clock.now) {
+			select {
+			case ticker.c <- ticker.next:
+			default:
+			}
+
+			ticker.next = ticker.next.Add(ticker.period)
+		}
+	}
+}
+
+func (ticker *fakeTicker) C() <-chan time.Time {
+	return ticker.c
+}
+
+func (ticker *fakeTicker) Stop() {
+	clock := ticker.clock
+
+	clock.mutex.Lock()
+
+	defer clock.mutex.Unlock()
+
+	for i, t := range clock.tickers {
+		if t == ticker {
+			clock.tickers = append(clock.tickers[:i], clock.tickers[i+1:]...)
+
+			break
+		}
+	}
+}
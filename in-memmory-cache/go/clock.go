@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * Источник времени кэш-хранилища. Все проверки времени жизни и фоновые горутины кэша получают время
+ * и тикеры через `Clock`, поэтому в тестах системные часы можно заменить управляемыми (`WithClock`),
+ * например `cachetest.FakeClock`, и проверять истечение значений без ожидания
+ */
+type Clock interface {
+	Now() time.Time
+	Ticker(d time.Duration) Ticker
+}
+
+// Тикер, срабатывающий с заданным интервалом
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Системные часы, используемые по умолчанию
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Ticker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t systemTicker) Stop() {
+	t.ticker.Stop()
+}
+
+/*
+ * Грубые часы (`WithCoarseClock`): текущее время обновляется единственной фоновой горутиной с заданным
+ * шагом, а `Now` только атомарно читает сохраненное значение. При высокой частоте операций это дешевле
+ * обращения к системным часам на каждом `Get` и `Set`, но время отстает от точного не больше чем на шаг
+ */
+type coarseClock struct {
+	clock Clock
+	now   atomic.Pointer[time.Time]
+}
+
+func newCoarseClock(clock Clock) *coarseClock {
+	coarse := &coarseClock{clock: clock}
+
+	now := clock.Now()
+	coarse.now.Store(&now)
+
+	return coarse
+}
+
+func (clock *coarseClock) Now() time.Time {
+	return *clock.now.Load()
+}
+
+func (clock *coarseClock) Ticker(d time.Duration) Ticker {
+	return clock.clock.Ticker(d)
+}
+
+// Функция обновления времени грубых часов до закрытия кэша
+func (clock *coarseClock) run(ticker Ticker, stop <-chan struct{}) {
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			now := clock.clock.Now()
+			clock.now.Store(&now)
+		case <-stop:
+			return
+		}
+	}
+}
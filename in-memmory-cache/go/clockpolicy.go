@@ -0,0 +1,125 @@
+package cache
+
+// Ячейка кольца политики CLOCK
+type clockSlot[K comparable] struct {
+	key        K
+	used       bool
+	referenced bool
+}
+
+/*
+ * Политика вытеснения "второй шанс" (CLOCK), приближение LRU. Ключи хранятся в кольце ячеек с битом
+ * обращения: чтение только устанавливает бит, без перемещения элементов списка. Стрелка обходит кольцо
+ * в поисках кандидата, сбрасывая биты по пути, поэтому значение, к которому обращались после прошлого
+ * прохода стрелки, получает второй шанс. Ячейки удаленных ключей переиспользуются
+ */
+type clockPolicy[K comparable] struct {
+	slots   []clockSlot[K]
+	indexes map[K]int
+	free    []int
+	hand    int
+}
+
+func newClockPolicy[K comparable]() *clockPolicy[K] {
+	return &clockPolicy[K]{indexes: make(map[K]int)}
+}
+
+func (policy *clockPolicy[K]) OnGet(key K) {
+	if index, ok := policy.indexes[key]; ok {
+		policy.slots[index].referenced = true
+	}
+}
+
+func (policy *clockPolicy[K]) OnSet(key K) {
+	if index, ok := policy.indexes[key]; ok {
+		policy.slots[index].referenced = true
+
+		return
+	}
+
+	slot := clockSlot[K]{key: key, used: true}
+
+	if n := len(policy.free); n > 0 {
+		index := policy.free[n-1]
+		policy.free = policy.free[:n-1]
+
+		policy.slots[index] = slot
+		policy.indexes[key] = index
+
+		return
+	}
+
+	policy.indexes[key] = len(policy.slots)
+	policy.slots = append(policy.slots, slot)
+}
+
+func (policy *clockPolicy[K]) OnDelete(key K) {
+	index, ok := policy.indexes[key]
+
+	if !ok {
+		return
+	}
+
+	policy.slots[index] = clockSlot[K]{}
+	policy.free = append(policy.free, index)
+
+	delete(policy.indexes, key)
+}
+
+// Стрелка останавливается на кандидате, поэтому повторный вызов до его удаления вернет тот же ключ
+func (policy *clockPolicy[K]) Victim() (K, bool) {
+	if len(policy.indexes) == 0 {
+		var zero K
+
+		return zero, false
+	}
+
+	// Не больше двух оборотов: за первый сбрасываются все биты обращения
+	for {
+		if policy.hand >= len(policy.slots) {
+			policy.hand = 0
+		}
+
+		slot := &policy.slots[policy.hand]
+
+		if slot.used {
+			if !slot.referenced {
+				return slot.key, true
+			}
+
+			slot.referenced = false
+		}
+
+		policy.hand++
+	}
+}
+
+/*
+ * Функция выбора кандидата без движения стрелки и сброса битов обращения. Возвращает ключ,
+ * на котором остановился бы `Victim`: первую ячейку без бита обращения после стрелки, а если
+ * биты установлены у всех ячеек - первую занятую, поскольку за полный оборот биты будут сброшены
+ */
+func (policy *clockPolicy[K]) peekVictim() (K, bool) {
+	var (
+		first K
+		found bool
+	)
+
+	for i := range policy.slots {
+		slot := policy.slots[(policy.hand+i)%len(policy.slots)]
+
+		if !slot.used {
+			continue
+		}
+
+		if !slot.referenced {
+			return slot.key, true
+		}
+
+		if !found {
+			first, found = slot.key, true
+		}
+	}
+
+	return first, found
+}
@@ -0,0 +1,305 @@
+/*
+ * Пакет клиента кластера кэш-серверов. Клиент распределяет профили между узлами, запущенными
+ * с HTTP-интерфейсом `cachehttp`, по кольцу согласованного хеширования с виртуальными узлами
+ * и хранит каждый профиль на нескольких узлах (фактор репликации)
+ */
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachehttp"
+	"golang-cache/internal/hashring"
+)
+
+// Количество виртуальных точек узла на кольце по умолчанию
+const DefaultVirtualNodes = 128
+
+/*
+ * Функциональная опция клиента кластера. Опция проверяет переданные значения
+ * и возвращает ошибку, если они некорректны
+ */
+type Option func(*options) error
+
+type options struct {
+	replication  int
+	virtualNodes int
+	httpClient   *http.Client
+}
+
+/*
+ * Опция фактора репликации: количество узлов, на которые записывается каждый профиль.
+ * При недоступности узла чтение обращается к следующей реплике. По умолчанию 1
+ */
+func WithReplicationFactor(n int) Option {
+	return func(o *options) error {
+		if n <= 0 {
+			return fmt.Errorf("cluster: replication factor must be positive, got %d", n)
+		}
+
+		o.replication = n
+
+		return nil
+	}
+}
+
+// Опция количества виртуальных точек узла на кольце. По умолчанию `DefaultVirtualNodes`
+func WithVirtualNodes(n int) Option {
+	return func(o *options) error {
+		if n <= 0 {
+			return fmt.Errorf("cluster: virtual nodes must be positive, got %d", n)
+		}
+
+		o.virtualNodes = n
+
+		return nil
+	}
+}
+
+// Опция HTTP-клиента для обращения к узлам. По умолчанию `http.DefaultClient`
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) error {
+		if client == nil {
+			return fmt.Errorf("cluster: http client must not be nil")
+		}
+
+		o.httpClient = client
+
+		return nil
+	}
+}
+
+/*
+ * Клиент кластера. Узлы задаются базовыми адресами (например `http://10.0.0.1:8080`). Добавление
+ * и удаление узла перестраивает кольцо, и запросы прозрачно направляются новым владельцам ключей
+ */
+type Client struct {
+	replication  int
+	virtualNodes int
+	http         *http.Client
+
+	mutex sync.RWMutex
+	ring  *hashring.Ring
+}
+
+// Функция-конструктор клиента кластера из списка узлов
+func NewClient(nodes []string, opts ...Option) (*Client, error) {
+	o := &options{replication: 1, virtualNodes: DefaultVirtualNodes, httpClient: http.DefaultClient}
+
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("cluster: at least one node is required")
+	}
+
+	return &Client{
+		replication:  o.replication,
+		virtualNodes: o.virtualNodes,
+		http:         o.httpClient,
+		ring:         hashring.New(nodes, o.virtualNodes),
+	}, nil
+}
+
+// Функция добавления узла в кластер. Повторное добавление ничего не делает
+func (client *Client) AddNode(node string) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	nodes := client.ring.Nodes()
+
+	if !slices.Contains(nodes, node) {
+		client.ring = hashring.New(append(nodes, node), client.virtualNodes)
+	}
+}
+
+// Функция удаления узла из кластера
+func (client *Client) RemoveNode(node string) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	nodes := slices.DeleteFunc(client.ring.Nodes(), func(n string) bool { return n == node })
+
+	client.ring = hashring.New(nodes, client.virtualNodes)
+}
+
+// Функция получения узлов кластера
+func (client *Client) Nodes() []string {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+
+	return client.ring.Nodes()
+}
+
+// Функция получения узлов, ответственных за профиль: владельца и реплик
+func (client *Client) owners(uuid string) []string {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+
+	return client.ring.Owners(uuid, client.replication)
+}
+
+/*
+ * Функция получения профиля. Реплики опрашиваются по порядку до первого ответа, поэтому недоступный
+ * узел не приводит к промаху. Возвращает `cache.ErrNotFound`, если профиль отсутствует на всех доступных
+ * репликах, и ошибку последнего узла, если ни одна реплика не ответила
+ */
+func (client *Client) Get(ctx context.Context, uuid string) (*cache.Profile, error) {
+	var errs []error
+
+	for _, node := range client.owners(uuid) {
+		profile, err := client.get(ctx, node, uuid)
+
+		if err == nil {
+			return profile, nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	// Ответ «не найдено» от любой реплики считаем достоверным, если остальные реплики недоступны
+	for _, err := range errs {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil, cache.ErrNotFound
+		}
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+/*
+ * Функция записи профиля на все реплики с временем жизни `ttl` (ноль - время жизни узла). Возвращает
+ * ошибку, только если профиль не удалось записать ни на одну реплику
+ */
+func (client *Client) Set(ctx context.Context, profile *cache.Profile, ttl time.Duration) error {
+	body, err := json.Marshal(profile)
+
+	if err != nil {
+		return fmt.Errorf("cluster: encode profile: %w", err)
+	}
+
+	var errs []error
+
+	owners := client.owners(profile.UUID)
+
+	for _, node := range owners {
+		request, err := client.request(ctx, http.MethodPut, node, profile.UUID, bytes.NewReader(body))
+
+		if err != nil {
+			return err
+		}
+
+		if ttl > 0 {
+			request.Header.Set(cachehttp.TTLHeader, strconv.FormatInt(int64((ttl+time.Second-1)/time.Second), 10))
+		}
+
+		if err := client.do(request, http.StatusNoContent); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == len(owners) {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+/*
+ * Функция удаления профиля со всех реплик. Отсутствие профиля на узле не является ошибкой,
+ * возвращаются ошибки недоступных узлов
+ */
+func (client *Client) Delete(ctx context.Context, uuid string) error {
+	var errs []error
+
+	for _, node := range client.owners(uuid) {
+		request, err := client.request(ctx, http.MethodDelete, node, uuid, nil)
+
+		if err != nil {
+			return err
+		}
+
+		if err := client.do(request, http.StatusNoContent); err != nil && !errors.Is(err, cache.ErrNotFound) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (client *Client) get(ctx context.Context, node, uuid string) (*cache.Profile, error) {
+	request, err := client.request(ctx, http.MethodGet, node, uuid, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.http.Do(request)
+
+	if err != nil {
+		return nil, fmt.Errorf("cluster: %s: %w", node, err)
+	}
+
+	defer response.Body.Close()
+
+	if err := statusError(node, response, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	var profile cache.Profile
+
+	if err := json.NewDecoder(response.Body).Decode(&profile); err != nil {
+		return nil, fmt.Errorf("cluster: %s: decode profile: %w", node, err)
+	}
+
+	return &profile, nil
+}
+
+func (client *Client) request(ctx context.Context, method, node, uuid string, body *bytes.Reader) (*http.Request, error) {
+	target := node + "/profiles/" + url.PathEscape(uuid)
+
+	// Нулевой *bytes.Reader в интерфейсе io.Reader не равен nil, поэтому тело передается явно
+	if body == nil {
+		return http.NewRequestWithContext(ctx, method, target, nil)
+	}
+
+	return http.NewRequestWithContext(ctx, method, target, body)
+}
+
+func (client *Client) do(request *http.Request, expected int) error {
+	response, err := client.http.Do(request)
+
+	if err != nil {
+		return fmt.Errorf("cluster: %s: %w", request.URL.Host, err)
+	}
+
+	defer response.Body.Close()
+
+	return statusError(request.URL.Host, response, expected)
+}
+
+// Функция преобразования ответа узла в ошибку. Ответ 404 преобразуется в `cache.ErrNotFound`
+func statusError(node string, response *http.Response, expected int) error {
+	switch response.StatusCode {
+	case expected:
+		return nil
+	case http.StatusNotFound:
+		return cache.ErrNotFound
+	default:
+		return fmt.Errorf("cluster: %s returned %s", node, response.Status)
+	}
+}
@@ -0,0 +1,100 @@
+package cluster_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachehttp"
+	"golang-cache/cluster"
+)
+
+// Функция запуска узлов кластера с HTTP-интерфейсом кэша профилей
+func newNodes(t *testing.T, n int) []*httptest.Server {
+	t.Helper()
+
+	nodes := make([]*httptest.Server, n)
+
+	for i := range nodes {
+		profiles, err := cache.New(cache.WithoutBackgroundGC())
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(profiles.Close)
+
+		nodes[i] = httptest.NewServer(cachehttp.NewHandler(profiles))
+
+		t.Cleanup(nodes[i].Close)
+	}
+
+	return nodes
+}
+
+func TestReplicaServesProfileWhenNodeIsDown(t *testing.T) {
+	nodes := newNodes(t, 2)
+
+	client, err := cluster.NewClient([]string{nodes[0].URL, nodes[1].URL}, cluster.WithReplicationFactor(2))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := client.Set(ctx, &cache.Profile{UUID: "user-1", Name: "Alice"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// Профиль записан на обе реплики, поэтому остановка одного узла не приводит к промаху
+	nodes[0].Close()
+
+	profile, err := client.Get(ctx, "user-1")
+
+	if err != nil || profile.Name != "Alice" {
+		t.Fatalf("expected profile from the remaining replica, got %v, %v", profile, err)
+	}
+
+	if _, err := client.Get(ctx, "user-2"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing profile, got %v", err)
+	}
+}
+
+func TestDeleteRemovesProfileFromAllReplicas(t *testing.T) {
+	nodes := newNodes(t, 3)
+
+	client, err := cluster.NewClient([]string{nodes[0].URL, nodes[1].URL, nodes[2].URL}, cluster.WithReplicationFactor(2))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := client.Set(ctx, &cache.Profile{UUID: "user-1"}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Delete(ctx, "user-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Повторное удаление отсутствующего профиля не является ошибкой
+	if err := client.Delete(ctx, "user-1"); err != nil {
+		t.Fatalf("expected repeated delete to succeed, got %v", err)
+	}
+
+	if _, err := client.Get(ctx, "user-1"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestNodesAreRequired(t *testing.T) {
+	if _, err := cluster.NewClient(nil); err == nil {
+		t.Fatal("expected error for an empty node list")
+	}
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// Метаданные значения кэша для отладки (`Entry`)
+type EntryInfo struct {
+	// Время записи ключа. Сохраняется при перезаписи актуального значения
+	CreatedAt time.Time
+
+	// Время последней записи значения
+	UpdatedAt time.Time
+
+	// Время последнего чтения. Нулевое, если значение не читали или учет чтений выключен (`WithAccessTracking`)
+	AccessedAt time.Time
+
+	// Количество чтений с момента записи ключа при включенном учете чтений (`WithAccessTracking`)
+	Accesses uint64
+
+	// Время истечения значения. Нулевое для значений без истечения (`NoExpiration`)
+	ExpireAt time.Time
+
+	// Оценка размера значения в байтах
+	Size int64
+
+	// Версия записи значения (`CompareAndSwap`)
+	Version uint64
+
+	// Значение закреплено (`Pin`)
+	Pinned bool
+}
+
+/*
+ * Функция получения метаданных значения, чтобы отладочные инструменты могли объяснить, почему
+ * значение находится или не находится в кэше. Не продлевает время жизни и не учитывается как чтение.
+ * Возвращает `false`, если значение отсутствует или просрочено
+ */
+func (cache *Cache[K, V]) Entry(key K) (EntryInfo, bool) {
+	shard := cache.shardFor(key)
+
+	shard.mutex.RLock()
+
+	defer shard.mutex.RUnlock()
+
+	item, ok := shard.data[key]
+
+	if !ok || shard.clock.Now().After(item.expireAt) {
+		return EntryInfo{}, false
+	}
+
+	info := EntryInfo{
+		CreatedAt: time.Unix(0, item.created),
+		UpdatedAt: time.Unix(0, item.updated),
+		Accesses:  uint64(item.accesses.Load()),
+		ExpireAt:  item.expireAt,
+		Size:      item.size,
+		Version:   item.version,
+		Pinned:    item.pinned,
+	}
+
+	if accessed := item.accessed.Load(); accessed != 0 {
+		info.AccessedAt = time.Unix(0, accessed)
+	}
+
+	if item.expireAt.Equal(neverExpires) {
+		info.ExpireAt = time.Time{}
+	}
+
+	// Без ограничения памяти размер при записи не оценивается, поэтому оцениваем его рефлексией
+	if shard.maxBytes == 0 {
+		info.Size = EstimateSize(key) + EstimateSize(item.value)
+	}
+
+	return info, true
+}
+
+// Функция учета чтения значения в метаданных. При выборочном учете одно чтение представляет `weight` чтений
+func (item *CacheItem[V]) access(now time.Time, weight uint32) {
+	item.accessed.Store(now.UnixNano())
+	item.accesses.Add(weight)
+}
+
+// Функция выбора чтения для учета при выборочном учете чтений (`WithAccessSampling`)
+func (shard *shard[K, V]) sampled() bool {
+	return shard.trackingRate <= 1 || rand.Uint32N(shard.trackingRate) == 0
+}
+
+// Количество чтений ключа в отчетах `TopKeys` и `ColdKeys`
+type KeyAccesses[K comparable] struct {
+	Key      K
+	Accesses uint64
+}
+
+/*
+ * Функция получения `n` наиболее читаемых ключей по убыванию количества чтений, чтобы при планировании
+ * емкости было видно, какие профили занимают кэш. Требует учета чтений (`WithAccessTracking`,
+ * `WithAccessSampling`), без него возвращает `nil`. Учитываются только актуальные значения
+ */
+func (cache *Cache[K, V]) TopKeys(n int) []KeyAccesses[K] {
+	return cache.rankKeys(n, func(a, b keyRank[K]) int {
+		return cmp.Or(cmp.Compare(b.accesses, a.accesses), cmp.Compare(b.accessed, a.accessed))
+	})
+}
+
+/*
+ * Функция получения `n` наименее читаемых ключей по возрастанию количества чтений, например кандидатов
+ * на более короткое время жизни. При равном количестве первыми идут ключи, которые дольше не читали.
+ * Требует учета чтений (`WithAccessTracking`, `WithAccessSampling`), без него возвращает `nil`
+ */
+func (cache *Cache[K, V]) ColdKeys(n int) []KeyAccesses[K] {
+	return cache.rankKeys(n, func(a, b keyRank[K]) int {
+		return cmp.Or(cmp.Compare(a.accesses, b.accesses), cmp.Compare(a.accessed, b.accessed))
+	})
+}
+
+// Ключ с количеством и временем последнего чтения для `TopKeys` и `ColdKeys`
+type keyRank[K comparable] struct {
+	key      K
+	accesses uint32
+	accessed int64
+}
+
+// Функция выбора `n` первых ключей в порядке `compare`. Каждый сегмент сортируется отдельно под блокировкой на чтение
+func (cache *Cache[K, V]) rankKeys(n int, compare func(a, b keyRank[K]) int) []KeyAccesses[K] {
+	if n <= 0 || !cache.shards[0].tracking {
+		return nil
+	}
+
+	now := cache.clock.Now()
+
+	var ranks []keyRank[K]
+
+	for _, shard := range cache.shards {
+		var shardRanks []keyRank[K]
+
+		shard.mutex.RLock()
+
+		for key, item := range shard.data {
+			if !now.After(item.expireAt) {
+				shardRanks = append(shardRanks, keyRank[K]{key: key, accesses: item.accesses.Load(), accessed: item.accessed.Load()})
+			}
+		}
+
+		shard.mutex.RUnlock()
+
+		slices.SortFunc(shardRanks, compare)
+
+		ranks = append(ranks, shardRanks[:min(n, len(shardRanks))]...)
+	}
+
+	slices.SortFunc(ranks, compare)
+
+	keys := make([]KeyAccesses[K], min(n, len(ranks)))
+
+	for i := range keys {
+		keys[i] = KeyAccesses[K]{Key: ranks[i].key, Accesses: uint64(ranks[i].accesses)}
+	}
+
+	return keys
+}
@@ -0,0 +1,26 @@
+package cache
+
+import "errors"
+
+var (
+	// Значение по ключу уже присутствует в кэш-хранилище (`Add`)
+	ErrExists = errors.New("cache: key already exists")
+
+	// Значение по ключу отсутствует в кэш-хранилище или просрочено (`Replace`)
+	ErrNotFound = errors.New("cache: key not found")
+
+	// Значение по ключу присутствует в кэш-хранилище, но его время жизни истекло (`GetE`)
+	ErrExpired = errors.New("cache: key expired")
+
+	// Версия значения изменилась с момента чтения (`CompareAndSwap`)
+	ErrVersionMismatch = errors.New("cache: version mismatch")
+
+	// Кэш-хранилище закрыто вызовом `Close`
+	ErrClosed = errors.New("cache: cache is closed")
+
+	// Загрузчик паниковал во время загрузки, которую ожидал данный поток (`GetOrCompute`, `GetContext`)
+	ErrLoaderPanicked = errors.New("cache: loader panicked")
+
+	// Реплика отстала от потока репликации больше чем на очередь изменений (`Replicate`)
+	ErrReplicaLagged = errors.New("cache: replica lagged behind the replication stream")
+)
@@ -0,0 +1,273 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Размеры буферов каналов `Watch` и `Events`. При переполнении из буфера вытесняется самое старое событие
+const (
+	watchBuffer  = 16
+	streamBuffer = 1024
+)
+
+// Тип изменения значения, передаваемого в событии
+type EventType int
+
+const (
+	// Значение записано или перезаписано
+	EventSet EventType = iota + 1
+
+	// Значение удалено из кэша (`Delete`, `Clear`)
+	EventDelete
+
+	// Значение удалено сборщиком мусора по истечении времени жизни
+	EventExpire
+
+	// Значение вытеснено из-за ограничения количества значений или памяти
+	EventEvict
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	default:
+		return fmt.Sprintf("EventType(%d)", int(t))
+	}
+}
+
+/*
+ * Событие изменения значения кэша. Для записи `Value` содержит новое значение, а для удаления
+ * и истечения - удаленное значение
+ */
+type Event[K comparable, V any] struct {
+	Type  EventType
+	Key   K
+	Value V
+}
+
+/*
+ * Рассылка событий изменения значений подписчикам `Watch` и в общий поток `Events`. События передаются
+ * под блокировкой сегмента, поэтому порядок событий одного ключа совпадает с порядком изменений.
+ * Без подписчиков рассылка не захватывает блокировку
+ */
+type eventHub[K comparable, V any] struct {
+	mutex    sync.Mutex
+	watchers map[K]map[chan Event[K, V]]struct{}
+	stream   chan Event[K, V]
+	closed   bool
+
+	// Количество подписчиков, включая общий поток, для проверки без блокировки
+	watching atomic.Int64
+
+	// Копирование значений событий (`WithCopyOnRead`)
+	clone func(V) V
+}
+
+// Функция передачи события подписчикам ключа. Вызывается под блокировкой сегмента
+func (hub *eventHub[K, V]) publish(t EventType, key K, value V) {
+	if hub.watching.Load() == 0 {
+		return
+	}
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.send(t, key, value)
+}
+
+// Функция передачи событий удаления значений при очистке сегмента. Вызывается под блокировкой сегмента
+func (hub *eventHub[K, V]) publishCleared(data map[K]*CacheItem[V]) {
+	if hub.watching.Load() == 0 {
+		return
+	}
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	// Общий поток получает удаление каждого значения, а подписчики - только своих ключей
+	if hub.stream != nil {
+		for key, item := range data {
+			hub.send(EventDelete, key, item.value)
+		}
+
+		return
+	}
+
+	for key := range hub.watchers {
+		if item, ok := data[key]; ok {
+			hub.send(EventDelete, key, item.value)
+		}
+	}
+}
+
+// Функция отправки события подписчикам ключа и в общий поток. Вызывается под блокировкой рассылки
+func (hub *eventHub[K, V]) send(t EventType, key K, value V) {
+	event := func() Event[K, V] {
+		event := Event[K, V]{Type: t, Key: key, Value: value}
+
+		if hub.clone != nil {
+			event.Value = hub.clone(value)
+		}
+
+		return event
+	}
+
+	for events := range hub.watchers[key] {
+		push(events, event())
+	}
+
+	if hub.stream != nil {
+		push(hub.stream, event())
+	}
+}
+
+/*
+ * Функция неблокирующей отправки события. Если подписчик не успевает читать события, из буфера
+ * вытесняется самое старое событие. Вызывается под блокировкой рассылки, которая исключает
+ * одновременную отправку в тот же канал
+ */
+func push[K comparable, V any](events chan Event[K, V], event Event[K, V]) {
+	for {
+		select {
+		case events <- event:
+			return
+		default:
+		}
+
+		select {
+		case <-events:
+		default:
+		}
+	}
+}
+
+func (hub *eventHub[K, V]) watch(key K) (chan Event[K, V], error) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if hub.closed {
+		return nil, ErrClosed
+	}
+
+	events := make(chan Event[K, V], watchBuffer)
+
+	if hub.watchers[key] == nil {
+		hub.watchers[key] = make(map[chan Event[K, V]]struct{})
+	}
+
+	hub.watchers[key][events] = struct{}{}
+	hub.watching.Add(1)
+
+	return events, nil
+}
+
+func (hub *eventHub[K, V]) unwatch(key K, events chan Event[K, V]) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if _, ok := hub.watchers[key][events]; !ok {
+		return
+	}
+
+	delete(hub.watchers[key], events)
+
+	if len(hub.watchers[key]) == 0 {
+		delete(hub.watchers, key)
+	}
+
+	hub.watching.Add(-1)
+
+	close(events)
+}
+
+// Функция закрытия каналов всех подписчиков при закрытии кэша
+func (hub *eventHub[K, V]) close() {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.closed = true
+
+	for key, watchers := range hub.watchers {
+		for events := range watchers {
+			close(events)
+		}
+
+		delete(hub.watchers, key)
+	}
+
+	if hub.stream != nil {
+		close(hub.stream)
+	}
+
+	hub.watching.Store(0)
+}
+
+// Функция получения общего потока событий, который создается при первом обращении
+func (hub *eventHub[K, V]) events() chan Event[K, V] {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if hub.stream != nil {
+		return hub.stream
+	}
+
+	hub.stream = make(chan Event[K, V], streamBuffer)
+
+	if hub.closed {
+		close(hub.stream)
+	} else {
+		hub.watching.Add(1)
+	}
+
+	return hub.stream
+}
+
+/*
+ * Функция подписки на изменения значения по ключу (например для передачи обновлений профиля клиентам
+ * без опроса кэша). Канал получает события записи, удаления, истечения и вытеснения значения и закрывается при отмене
+ * `ctx` или закрытии кэша. Истечение передается, когда просроченное значение удаляет сборщик мусора.
+ * Канал буферизован: если подписчик не успевает читать, самые старые события отбрасываются, поэтому
+ * последнее событие всегда отражает текущее состояние ключа. Возвращает `ErrClosed` для закрытого кэша
+ */
+func (cache *Cache[K, V]) Watch(ctx context.Context, key K) (<-chan Event[K, V], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	events, err := cache.events.watch(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			cache.events.unwatch(key, events)
+		case <-cache.stop:
+		}
+	}()
+
+	return events, nil
+}
+
+/*
+ * Функция получения общего потока событий всех изменений кэша: записи, удаления, истечения и вытеснения
+ * значений (например для передачи в Kafka или журнал аудита). Все вызовы возвращают один и тот же канал,
+ * поэтому поток рассчитан на одного потребителя. События начинают поступать с первого вызова. Буфер
+ * потока ограничен: если потребитель не успевает читать, самые старые события отбрасываются. Канал
+ * закрывается при закрытии кэша
+ */
+func (cache *Cache[K, V]) Events() <-chan Event[K, V] {
+	return cache.events.events()
+}
@@ -0,0 +1,40 @@
+package cache
+
+import "fmt"
+
+// Причина удаления значения из кэш-хранилища, передаваемая в функцию из `WithOnEvicted`
+type EvictionReason int
+
+const (
+	// Значение удалено сборщиком мусора по истечении времени жизни
+	EvictedExpired EvictionReason = iota + 1
+
+	// Значение вытеснено из-за ограничения количества значений или памяти
+	EvictedCapacity
+
+	// Значение удалено явным вызовом `Delete`
+	EvictedDeleted
+
+	// Значение заменено новым значением по тому же ключу
+	EvictedReplaced
+
+	// Значение удалено полной очисткой кэша (`Clear`)
+	EvictedCleared
+)
+
+func (reason EvictionReason) String() string {
+	switch reason {
+	case EvictedExpired:
+		return "expired"
+	case EvictedCapacity:
+		return "capacity"
+	case EvictedDeleted:
+		return "deleted"
+	case EvictedReplaced:
+		return "replaced"
+	case EvictedCleared:
+		return "cleared"
+	default:
+		return fmt.Sprintf("EvictionReason(%d)", int(reason))
+	}
+}
@@ -0,0 +1,267 @@
+package cache_test
+
+import (
+	"fmt"
+	"testing"
+
+	cache "golang-cache"
+)
+
+func TestEvictionPolicies(t *testing.T) {
+	// Значение `a` читается после записи, поэтому политики, учитывающие обращения, вытесняют `b`
+	for policy, evicted := range map[cache.Policy]string{
+		cache.LRU:  "b",
+		cache.LFU:  "b",
+		cache.FIFO: "a",
+	} {
+		t.Run(policy.String(), func(t *testing.T) {
+			values := newValues(t, cache.WithMaxEntries(2), cache.WithPolicy(policy))
+
+			values.Set("a", 1)
+			values.Set("b", 2)
+			values.Get("a")
+			values.Get("a")
+			values.Set("c", 3)
+
+			if _, ok := values.Peek(evicted); ok {
+				t.Fatalf("expected %q to be evicted, keys %v", evicted, values.Keys())
+			}
+
+			if values.Len() != 2 || values.Stats().Evictions != 1 {
+				t.Fatalf("expected two entries and one eviction, got %d and %+v", values.Len(), values.Stats())
+			}
+		})
+	}
+}
+
+func TestEvictionPoliciesRespectCapacity(t *testing.T) {
+	for _, policy := range []cache.Policy{cache.LRU, cache.LFU, cache.FIFO, cache.ARC, cache.CLOCK, cache.SLRU, cache.TwoQueue, cache.Random} {
+		t.Run(policy.String(), func(t *testing.T) {
+			values := newValues(t, cache.WithMaxEntries(10), cache.WithPolicy(policy))
+
+			for i := range 100 {
+				values.Set(fmt.Sprint(i), i)
+				values.Get(fmt.Sprint(i / 2))
+			}
+
+			if values.Len() != 10 {
+				t.Fatalf("expected 10 entries, got %d", values.Len())
+			}
+
+			if evictions := values.Stats().Evictions; evictions != 90 {
+				t.Fatalf("expected 90 evictions, got %d", evictions)
+			}
+
+			// Последнее записанное значение не вытесняется при собственной записи
+			if _, ok := values.Peek("99"); !ok {
+				t.Fatalf("expected the last written key to stay, keys %v", values.Keys())
+			}
+		})
+	}
+}
+
+func TestUnknownPolicyIsRejected(t *testing.T) {
+	if _, err := cache.NewCache[string, int](cache.WithPolicy(cache.Random + 1)); err == nil {
+		t.Fatal("expected error for an unknown policy")
+	}
+}
+
+// Функция создания обобщенного кэша без фонового сборщика мусора, закрываемого по завершении теста
+func newValues(t *testing.T, opts ...cache.Option) *cache.Cache[string, int] {
+	t.Helper()
+
+	values, err := cache.NewCache[string, int](append(opts, cache.WithoutBackgroundGC())...)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(values.Close)
+
+	return values
+}
+
+// Функция записи нового значения в заполненный кэш с проверкой, что вытеснено ровно значение `evicted`
+func expectEviction(t *testing.T, values *cache.Cache[string, int], key, evicted string) {
+	t.Helper()
+
+	size := values.Len()
+
+	values.Set(key, 0)
+
+	if _, ok := values.Peek(evicted); ok || values.Len() != size {
+		t.Fatalf("expected writing %q to evict %q, keys %v", key, evicted, values.Keys())
+	}
+}
+
+func TestARCAdaptsToGhostHits(t *testing.T) {
+	values := newValues(t, cache.WithMaxEntries(4), cache.WithPolicy(cache.ARC))
+
+	// T1 = [d c b a], целевой размер T1 равен нулю
+	for _, key := range []string{"a", "b", "c", "d"} {
+		values.Set(key, 0)
+	}
+
+	// Повторное обращение переносит ключи в T2: T1 = [d c], T2 = [b a]
+	values.Get("a")
+	values.Get("b")
+
+	// T1 больше целевого размера, поэтому вытесняется давний ключ T1 и становится призраком в B1
+	expectEviction(t, values, "e", "c")
+
+	// Запись призрака из B1 увеличивает целевой размер T1 до одного, а ключ попадает сразу в T2.
+	// Место под него освобождает d, который уходит в B1: T1 = [e], T2 = [c b a], B1 = [d]
+	expectEviction(t, values, "c", "d")
+
+	if value, ok := values.Peek("c"); !ok || value != 0 {
+		t.Fatalf("expected the ghost hit to be cached, got %d, %v", value, ok)
+	}
+
+	// T1 не больше целевого размера, поэтому кандидат выбирается из T2, хотя T1 не пуст
+	expectEviction(t, values, "f", "a")
+
+	if _, ok := values.Peek("e"); !ok {
+		t.Fatalf("expected T1 to keep its target size, keys %v", values.Keys())
+	}
+
+	// Запись призрака из B2 уменьшает целевой размер T1 до нуля: T1 = [f], T2 = [a c b], B1 = [e d]
+	expectEviction(t, values, "a", "e")
+
+	// Теперь кандидат снова выбирается из T1, а T2 сохраняется целиком
+	expectEviction(t, values, "g", "f")
+
+	for _, key := range []string{"a", "b", "c", "g"} {
+		if _, ok := values.Peek(key); !ok {
+			t.Fatalf("expected %q to be cached, keys %v", key, values.Keys())
+		}
+	}
+
+	if evictions := values.Stats().Evictions; evictions != 5 {
+		t.Fatalf("expected 5 evictions, got %d", evictions)
+	}
+}
+
+func TestCLOCKGivesReferencedKeysSecondChance(t *testing.T) {
+	values := newValues(t, cache.WithMaxEntries(4), cache.WithPolicy(cache.CLOCK))
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		values.Set(key, 0)
+	}
+
+	values.Get("a")
+	values.Get("c")
+
+	// Стрелка сбрасывает бит обращения a и останавливается на b, к которому не обращались
+	expectEviction(t, values, "e", "b")
+
+	// Перезапись, как и чтение, устанавливает бит обращения
+	values.Get("e")
+	values.Set("d", 1)
+
+	// Стрелка сбрасывает биты e, c и d, а a уже израсходовал второй шанс
+	expectEviction(t, values, "f", "a")
+
+	for _, key := range []string{"c", "d", "e", "f"} {
+		if _, ok := values.Peek(key); !ok {
+			t.Fatalf("expected %q to be cached, keys %v", key, values.Keys())
+		}
+	}
+}
+
+func TestSLRUPromotesAndDemotes(t *testing.T) {
+	// Защищенный сегмент вмещает 80% емкости - четыре значения из пяти
+	values := newValues(t, cache.WithMaxEntries(5), cache.WithPolicy(cache.SLRU))
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		values.Set(key, 0)
+	}
+
+	// Повторное обращение переносит ключи в защищенный сегмент: испытательный = [e d c], защищенный = [b a]
+	values.Get("a")
+	values.Get("b")
+
+	// Новые значения вытесняют только испытательный сегмент, хотя к a и b обращались давно
+	for _, step := range [][2]string{{"f", "c"}, {"g", "d"}, {"h", "e"}, {"i", "f"}, {"j", "g"}} {
+		expectEviction(t, values, step[0], step[1])
+	}
+
+	// Переполнение защищенного сегмента возвращает давно не использованный a на испытание
+	values.Get("h")
+	values.Get("i")
+	values.Get("j")
+
+	expectEviction(t, values, "k", "a")
+
+	for _, key := range []string{"b", "h", "i", "j", "k"} {
+		if _, ok := values.Peek(key); !ok {
+			t.Fatalf("expected %q to be cached, keys %v", key, values.Keys())
+		}
+	}
+}
+
+func TestTwoQueueTransitions(t *testing.T) {
+	// A1in удерживает четверть емкости - одно значение, а A1out помнит половину - два ключа
+	values := newValues(t, cache.WithMaxEntries(4), cache.WithPolicy(cache.TwoQueue))
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		values.Set(key, 0)
+	}
+
+	// Обращения к значениям A1in не учитываются, поэтому a вытесняется первым и остается в A1out
+	values.Get("a")
+
+	expectEviction(t, values, "e", "a")
+
+	// Повторная запись ключа из A1out помещает значение в Am: A1in = [e d c], Am = [a], A1out = [b]
+	expectEviction(t, values, "a", "b")
+
+	// Явное удаление забывает ключ, а не переносит его в A1out, поэтому c снова попадает в A1in
+	values.Delete("c")
+	values.Set("c", 0)
+
+	if values.Len() != 4 {
+		t.Fatalf("expected 4 entries, got %d", values.Len())
+	}
+
+	// A1in вытесняется в порядке добавления, пока больше своей доли, а значение из Am остается
+	for _, step := range [][2]string{{"f", "d"}, {"g", "e"}, {"h", "c"}} {
+		expectEviction(t, values, step[0], step[1])
+	}
+
+	for _, key := range []string{"a", "f", "g", "h"} {
+		if _, ok := values.Peek(key); !ok {
+			t.Fatalf("expected %q to be cached, keys %v", key, values.Keys())
+		}
+	}
+}
+
+func TestEvictOldestMakesUpForUnevenShards(t *testing.T) {
+	values := newValues(t, cache.WithMaxEntries(8000), cache.WithShards(8))
+
+	// Закрепленные значения занимают сегменты, но не вытесняются, поэтому доли сегментов
+	// не совпадают с количеством доступных для вытеснения значений в них
+	for i := range 800 {
+		key := fmt.Sprint(i)
+
+		values.Set(key, i)
+
+		if i >= 8 {
+			values.Pin(key)
+		}
+	}
+
+	if evicted := values.EvictOldest(8); evicted != 8 {
+		t.Fatalf("expected 8 evictions, got %d", evicted)
+	}
+
+	for i := range 8 {
+		if _, ok := values.Peek(fmt.Sprint(i)); ok {
+			t.Fatalf("expected the unpinned key %d to be evicted", i)
+		}
+	}
+
+	// Вытеснять больше нечего
+	if evicted := values.EvictOldest(8); evicted != 0 {
+		t.Fatalf("expected no evictions, got %d", evicted)
+	}
+}
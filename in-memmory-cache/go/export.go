@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// Версия формата JSON-документа с содержимым кэша
+const exportVersion = 1
+
+/*
+ * JSON-документ с содержимым кэша. Значения хранятся списком, а не объектом, поскольку ключ
+ * кэша может быть не строкой. Список упорядочен по ключу, поэтому документ одного и того же
+ * содержимого кэша всегда одинаков и удобен для сравнения
+ */
+type exportDocument[K comparable, V any] struct {
+	Version int                 `json:"version"`
+	Entries []exportEntry[K, V] `json:"entries"`
+}
+
+type exportEntry[K comparable, V any] struct {
+	Key      K         `json:"key"`
+	Value    V         `json:"value"`
+	TTL      string    `json:"ttl"`
+	ExpireAt time.Time `json:"expire_at"`
+
+	// Значение записано с временем жизни по умолчанию (`SetDefaultTTL` изменяет его время жизни)
+	DefaultTTL bool `json:"default_ttl,omitempty"`
+
+	// JSON-представление ключа для упорядочивания документа
+	order []byte
+}
+
+/*
+ * Функция выгрузки актуальных значений кэша в JSON-документ вида
+ * `{"version": 1, "entries": [{"key": ..., "value": ..., "ttl": "1m0s", "expire_at": ...}]}`.
+ * Используется для отладочных дампов и переноса содержимого кэша между окружениями
+ */
+func (cache *Cache[K, V]) ExportJSON(w io.Writer) error {
+	snapshot := cache.entries()
+
+	document := exportDocument[K, V]{
+		Version: exportVersion,
+		Entries: make([]exportEntry[K, V], 0, len(snapshot)),
+	}
+
+	for _, entry := range snapshot {
+		order, err := json.Marshal(entry.Key)
+
+		if err != nil {
+			return fmt.Errorf("cache: encode key: %w", err)
+		}
+
+		document.Entries = append(document.Entries, exportEntry[K, V]{
+			Key:        entry.Key,
+			Value:      entry.Value,
+			TTL:        entry.TTL.String(),
+			ExpireAt:   entry.ExpireAt,
+			DefaultTTL: entry.DefaultTTL,
+			order:      order,
+		})
+	}
+
+	slices.SortFunc(document.Entries, func(a, b exportEntry[K, V]) int {
+		return bytes.Compare(a.order, b.order)
+	})
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("cache: encode json: %w", err)
+	}
+
+	return nil
+}
+
+/*
+ * Функция загрузки значений из JSON-документа, выгруженного `ExportJSON`. Значения записываются
+ * поверх текущих с сохраненным временем истечения, а уже истекшие значения пропускаются
+ */
+func (cache *Cache[K, V]) ImportJSON(r io.Reader) error {
+	var document exportDocument[K, V]
+
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return fmt.Errorf("cache: decode json: %w", err)
+	}
+
+	if document.Version != exportVersion {
+		return fmt.Errorf("cache: unsupported json version %d, want %d", document.Version, exportVersion)
+	}
+
+	now := cache.clock.Now()
+
+	for _, entry := range document.Entries {
+		ttl, err := time.ParseDuration(entry.TTL)
+
+		if err != nil {
+			return fmt.Errorf("cache: decode ttl of entry: %w", err)
+		}
+
+		if now.After(entry.ExpireAt) {
+			continue
+		}
+
+		if err := cache.restore(entry.Key, entry.Value, ttl, entry.ExpireAt, entry.DefaultTTL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"expvar"
+	"fmt"
+)
+
+/*
+ * Функция публикации статистики кэша в `expvar` под именем `name`. Статистика вычисляется при каждом
+ * обращении к `/debug/vars`, поэтому существующий сбор метрик получает долю попаданий и размер кэша
+ * без подключения библиотеки метрик. Возвращает ошибку, если переменная с таким именем уже опубликована
+ */
+func (cache *Cache[K, V]) PublishExpvar(name string) error {
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("cache: expvar %q is already published", name)
+	}
+
+	expvar.Publish(name, expvar.Func(func() any {
+		stats := cache.Stats()
+
+		return map[string]any{
+			"hits":                 stats.Hits,
+			"misses":               stats.Misses,
+			"hit_ratio":            stats.HitRatio(),
+			"expired":              stats.Expired,
+			"evictions":            stats.Evictions,
+			"replaced":             stats.Replaced,
+			"deleted":              stats.Deleted,
+			"cleared":              stats.Cleared,
+			"dead_letters":         stats.DeadLetters,
+			"entries":              stats.Entries,
+			"gc_sweeps":            stats.Sweeps,
+			"gc_sweep_duration_ns": stats.SweepDuration.Nanoseconds(),
+		}
+	}))
+
+	return nil
+}
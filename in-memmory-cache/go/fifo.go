@@ -0,0 +1,58 @@
+package cache
+
+import "container/list"
+
+/*
+ * Политика вытеснения значений в порядке их добавления (FIFO). Чтение и обновление значения
+ * не влияют на порядок вытеснения, поэтому учет обращений практически ничего не стоит
+ */
+type fifoPolicy[K comparable] struct {
+	queue    *list.List
+	elements map[K]*list.Element
+}
+
+func newFIFOPolicy[K comparable]() *fifoPolicy[K] {
+	return &fifoPolicy[K]{
+		queue:    list.New(),
+		elements: make(map[K]*list.Element),
+	}
+}
+
+func (policy *fifoPolicy[K]) OnGet(key K) {}
+
+func (policy *fifoPolicy[K]) OnSet(key K) {
+	if _, ok := policy.elements[key]; ok {
+		return
+	}
+
+	policy.elements[key] = policy.queue.PushBack(key)
+}
+
+func (policy *fifoPolicy[K]) OnDelete(key K) {
+	if element, ok := policy.elements[key]; ok {
+		policy.queue.Remove(element)
+		delete(policy.elements, key)
+	}
+}
+
+func (policy *fifoPolicy[K]) Victim() (K, bool) {
+	element := policy.queue.Front()
+
+	if element == nil {
+		var zero K
+
+		return zero, false
+	}
+
+	return element.Value.(K), true
+}
+
+func (policy *fifoPolicy[K]) victims(n int) []K {
+	var keys []K
+
+	for element := policy.queue.Front(); element != nil && len(keys) < n; element = element.Next() {
+		keys = append(keys, element.Value.(K))
+	}
+
+	return keys
+}
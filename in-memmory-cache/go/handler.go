@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+/*
+ * Функция создания отладочного HTTP-обработчика для просмотра кэша со строковыми ключами:
+ *
+ *   GET    /keys?pattern=test-*  - отсортированный список ключей актуальных значений, шаблон необязателен
+ *   GET    /keys/{key}           - значение с оставшимся временем жизни
+ *   DELETE /keys/{key}           - удаление значения
+ *   GET    /entries/{key}        - метаданные значения (`Entry`)
+ *   GET    /stats?window=5m      - статистика кэша, за последний период при заданном `window`
+ *   POST   /expired              - удаление просроченных значений, возвращает их количество
+ *   POST   /evict?n=100          - ручное вытеснение `n` значений (`EvictOldest`), возвращает их количество
+ *   GET    /hot?n=10             - наиболее читаемые ключи (`TopKeys`)
+ *   GET    /cold?n=10            - наименее читаемые ключи (`ColdKeys`)
+ *   GET    /ttl                  - время жизни значений по умолчанию
+ *   PUT    /ttl?value=5m         - изменение времени жизни по умолчанию, `existing=true` применяет его
+ *                                  и к уже записанным значениям
+ *
+ * Просмотр не продлевает время жизни и не учитывается в статистике. Обработчик раскрывает содержимое
+ * кэша, поэтому его следует подключать только к внутреннему отладочному порту, например через
+ * `http.StripPrefix("/debug/cache", cache.Handler(c))`
+ */
+func Handler[V any](c *Cache[string, V]) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		keys := c.Keys()
+
+		if pattern := r.URL.Query().Get("pattern"); pattern != "" {
+			var err error
+
+			if keys, err = c.KeysMatching(pattern); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+		}
+
+		if keys == nil {
+			keys = []string{}
+		}
+
+		slices.Sort(keys)
+
+		writeJSON(w, keys)
+	})
+
+	mux.HandleFunc("GET /keys/{key...}", func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+
+		value, expireAt, ok := c.shardFor(key).peekWithExpiration(key)
+
+		if !ok {
+			http.Error(w, ErrNotFound.Error(), http.StatusNotFound)
+
+			return
+		}
+
+		ttl := expireAt.Sub(c.clock.Now()).String()
+
+		if expireAt.Equal(neverExpires) {
+			ttl = "none"
+		}
+
+		writeJSON(w, debugEntry[V]{
+			Key:      key,
+			Value:    c.copyOut(value),
+			TTL:      ttl,
+			ExpireAt: expireAt,
+		})
+	})
+
+	mux.HandleFunc("DELETE /keys/{key...}", func(w http.ResponseWriter, r *http.Request) {
+		if !c.Delete(r.PathValue("key")) {
+			http.Error(w, ErrNotFound.Error(), http.StatusNotFound)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /entries/{key...}", func(w http.ResponseWriter, r *http.Request) {
+		info, ok := c.Entry(r.PathValue("key"))
+
+		if !ok {
+			http.Error(w, ErrNotFound.Error(), http.StatusNotFound)
+
+			return
+		}
+
+		writeJSON(w, info)
+	})
+
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		window := r.URL.Query().Get("window")
+
+		if window == "" {
+			writeJSON(w, c.Stats())
+
+			return
+		}
+
+		duration, err := time.ParseDuration(window)
+
+		if err != nil || duration <= 0 {
+			http.Error(w, "window must be a positive duration", http.StatusBadRequest)
+
+			return
+		}
+
+		if !c.windowed {
+			http.Error(w, "stats window is disabled, enable it with WithStatsWindow", http.StatusBadRequest)
+
+			return
+		}
+
+		writeJSON(w, c.StatsWindow(duration))
+	})
+
+	mux.HandleFunc("POST /expired", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]int{"removed": c.DeleteExpired()})
+	})
+
+	mux.HandleFunc("POST /evict", func(w http.ResponseWriter, r *http.Request) {
+		if n, ok := queryCount(w, r); ok {
+			writeJSON(w, map[string]int{"removed": c.EvictOldest(n)})
+		}
+	})
+
+	mux.HandleFunc("GET /hot", func(w http.ResponseWriter, r *http.Request) {
+		if n, ok := queryCount(w, r); ok {
+			writeJSON(w, c.TopKeys(n))
+		}
+	})
+
+	mux.HandleFunc("GET /cold", func(w http.ResponseWriter, r *http.Request) {
+		if n, ok := queryCount(w, r); ok {
+			writeJSON(w, c.ColdKeys(n))
+		}
+	})
+
+	mux.HandleFunc("GET /ttl", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"ttl": c.DefaultTTL().String()})
+	})
+
+	mux.HandleFunc("PUT /ttl", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		ttl, err := time.ParseDuration(query.Get("value"))
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := c.SetDefaultTTL(ttl, query.Get("existing") == "true"); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		writeJSON(w, map[string]string{"ttl": c.DefaultTTL().String()})
+	})
+
+	return mux
+}
+
+// Значение кэша в ответе отладочного обработчика
+type debugEntry[V any] struct {
+	Key      string    `json:"key"`
+	Value    V         `json:"value"`
+	TTL      string    `json:"ttl"`
+	ExpireAt time.Time `json:"expire_at"`
+}
+
+// Функция чтения количества из параметра `n` запроса. При некорректном значении отвечает ошибкой и возвращает `false`
+func queryCount(w http.ResponseWriter, r *http.Request) (int, bool) {
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+
+	if err != nil || n <= 0 {
+		http.Error(w, "n must be a positive integer", http.StatusBadRequest)
+
+		return 0, false
+	}
+
+	return n, true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	_ = encoder.Encode(v)
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"fmt"
+	"hash/maphash"
+)
+
+/*
+ * Функция хеширования ключа произвольного сравнимого типа. Для строковых и целочисленных ключей
+ * хеш вычисляется напрямую, для остальных типов - по строковому представлению ключа
+ */
+func hashKey[K comparable](seed maphash.Seed, key K) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		return maphash.String(seed, k)
+	case int:
+		return hashUint64(seed, uint64(k))
+	case int32:
+		return hashUint64(seed, uint64(k))
+	case int64:
+		return hashUint64(seed, uint64(k))
+	case uint:
+		return hashUint64(seed, uint64(k))
+	case uint32:
+		return hashUint64(seed, uint64(k))
+	case uint64:
+		return hashUint64(seed, k)
+	default:
+		return maphash.String(seed, fmt.Sprintf("%#v", key))
+	}
+}
+
+func hashUint64(seed maphash.Seed, value uint64) uint64 {
+	var buf [8]byte
+
+	for i := range buf {
+		buf[i] = byte(value >> (8 * i))
+	}
+
+	return maphash.Bytes(seed, buf[:])
+}
@@ -0,0 +1,94 @@
+package cache
+
+import "sync"
+
+/*
+ * Минимальный интерфейс кэша для внедрения зависимостей. Зависимый код принимает `Interface` вместо
+ * конкретного `*Cache`, поэтому в тестах кэш можно заменить на `MapCache` или отключить через `NopCache`.
+ * Кэш профилей реализует интерфейс через встроенный обобщенный кэш `ProfileCache.Cache`
+ */
+type Interface[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Set(key K, value V)
+	Delete(key K) bool
+	Close()
+}
+
+var (
+	_ Interface[string, *Profile] = (*Cache[string, *Profile])(nil)
+	_ Interface[string, *Profile] = NopCache[string, *Profile]{}
+	_ Interface[string, *Profile] = (*MapCache[string, *Profile])(nil)
+)
+
+/*
+ * Кэш, который ничего не хранит: `Get` всегда промахивается, а `Set` отбрасывает значение.
+ * Позволяет отключить кэширование без изменения зависимого кода
+ */
+type NopCache[K comparable, V any] struct{}
+
+func (NopCache[K, V]) Get(K) (V, bool) {
+	var zero V
+
+	return zero, false
+}
+
+func (NopCache[K, V]) Set(K, V) {}
+
+func (NopCache[K, V]) Delete(K) bool {
+	return false
+}
+
+func (NopCache[K, V]) Close() {}
+
+/*
+ * Тестовый двойник кэша: потокобезопасный словарь без времени жизни, вытеснения и фоновых горутин.
+ * Значения хранятся до явного удаления, поэтому поведение в тестах детерминировано. Нулевое значение
+ * готово к использованию
+ */
+type MapCache[K comparable, V any] struct {
+	mutex sync.RWMutex
+	data  map[K]V
+}
+
+func (cache *MapCache[K, V]) Get(key K) (V, bool) {
+	cache.mutex.RLock()
+
+	defer cache.mutex.RUnlock()
+
+	value, ok := cache.data[key]
+
+	return value, ok
+}
+
+func (cache *MapCache[K, V]) Set(key K, value V) {
+	cache.mutex.Lock()
+
+	defer cache.mutex.Unlock()
+
+	if cache.data == nil {
+		cache.data = make(map[K]V)
+	}
+
+	cache.data[key] = value
+}
+
+func (cache *MapCache[K, V]) Delete(key K) bool {
+	cache.mutex.Lock()
+
+	defer cache.mutex.Unlock()
+
+	_, ok := cache.data[key]
+
+	delete(cache.data, key)
+
+	return ok
+}
+
+// Функция закрытия тестового двойника удаляет все значения
+func (cache *MapCache[K, V]) Close() {
+	cache.mutex.Lock()
+
+	defer cache.mutex.Unlock()
+
+	cache.data = nil
+}
@@ -0,0 +1,85 @@
+/*
+ * Пакет кольца согласованного хеширования, общий для пула узлов (`cachepeer`) и клиента кластера (`cluster`)
+ */
+package hashring
+
+import (
+	"hash/crc32"
+	"slices"
+	"strconv"
+)
+
+/*
+ * Кольцо согласованного хеширования. Каждый узел занимает `replicas` виртуальных точек кольца, а ключ
+ * принадлежит узлу ближайшей по часовой стрелке точки, поэтому при добавлении или удалении узла меняют
+ * владельца только ключи его соседних участков. Кольцо неизменяемо: изменение состава узлов создает новое кольцо
+ */
+type Ring struct {
+	nodes  []string
+	points []uint32
+	owners map[uint32]string
+}
+
+// Функция-конструктор кольца из узлов с `replicas` виртуальными точками на узел
+func New(nodes []string, replicas int) *Ring {
+	ring := &Ring{
+		nodes:  slices.Clone(nodes),
+		owners: make(map[uint32]string, len(nodes)*replicas),
+	}
+
+	for _, node := range nodes {
+		for i := range replicas {
+			point := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + node))
+
+			ring.points = append(ring.points, point)
+			ring.owners[point] = node
+		}
+	}
+
+	slices.Sort(ring.points)
+
+	return ring
+}
+
+// Функция получения узлов кольца
+func (ring *Ring) Nodes() []string {
+	return slices.Clone(ring.nodes)
+}
+
+// Функция получения узла-владельца ключа. Возвращает пустую строку, если на кольце нет узлов
+func (ring *Ring) Owner(key string) string {
+	if owners := ring.Owners(key, 1); len(owners) > 0 {
+		return owners[0]
+	}
+
+	return ""
+}
+
+/*
+ * Функция получения `n` различных узлов, ответственных за ключ, в порядке обхода кольца от точки ключа.
+ * Первый узел - владелец ключа, остальные - его реплики. Если узлов меньше `n`, возвращаются все узлы
+ */
+func (ring *Ring) Owners(key string, n int) []string {
+	n = min(n, len(ring.nodes))
+
+	if n <= 0 {
+		return nil
+	}
+
+	hash := crc32.ChecksumIEEE([]byte(key))
+
+	i, _ := slices.BinarySearch(ring.points, hash)
+
+	owners := make([]string, 0, n)
+
+	for j := 0; len(owners) < n && j < len(ring.points); j++ {
+		// Ключ после последней точки принадлежит первой точке кольца
+		node := ring.owners[ring.points[(i+j)%len(ring.points)]]
+
+		if !slices.Contains(owners, node) {
+			owners = append(owners, node)
+		}
+	}
+
+	return owners
+}
@@ -0,0 +1,14 @@
+package cache
+
+import "iter"
+
+/*
+ * Функция получения итератора по актуальным значениям кэш-хранилища для `for key, value := range cache.All()`.
+ * Обход выполняется так же, как `Range`: тело цикла выполняется без удержания блокировки и может обращаться
+ * к кэшу, а значения, удаленные или истекшие во время обхода, пропускаются
+ */
+func (cache *Cache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		cache.Range(yield)
+	}
+}
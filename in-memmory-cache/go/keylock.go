@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"slices"
+	"sync"
+)
+
+// Количество блокировок, между которыми распределяются ключи в `Lock`
+const lockStripes = 256
+
+/*
+ * Функция захвата блокировки ключа. Блокировка не связана с блокировками хранилища и не мешает
+ * операциям кэша, она позволяет прикладному коду выполнить многошаговое изменение значения
+ * (например чтение, вычисление и запись профиля) без вмешательства других потоков, захватывающих
+ * ту же блокировку. Ключи распределяются между фиксированным набором блокировок, поэтому разные
+ * ключи изредка могут делить одну блокировку - одновременный захват нескольких ключей одним потоком
+ * может привести к взаимной блокировке
+ */
+func (cache *Cache[K, V]) Lock(key K) {
+	cache.lockFor(key).Lock()
+}
+
+// Функция попытки захвата блокировки ключа без ожидания. Возвращает `false`, если блокировка уже захвачена
+func (cache *Cache[K, V]) TryLock(key K) bool {
+	return cache.lockFor(key).TryLock()
+}
+
+// Функция освобождения блокировки ключа, захваченной `Lock` или `TryLock`
+func (cache *Cache[K, V]) Unlock(key K) {
+	cache.lockFor(key).Unlock()
+}
+
+func (cache *Cache[K, V]) lockFor(key K) *sync.Mutex {
+	return &cache.locks[hashKey(cache.seed, key)%lockStripes]
+}
+
+/*
+ * Функция захвата блокировки записи ключа в хранилище (`WithStore`). Возвращает функцию освобождения.
+ * Без хранилища изменение кэша атомарно под блокировкой сегмента, и блокировка не захватывается
+ */
+func (cache *Cache[K, V]) lockWrite(key K) func() {
+	return cache.lockWrites([]K{key})
+}
+
+/*
+ * Функция захвата блокировок записи нескольких ключей. Блокировки захватываются в порядке номеров,
+ * поэтому одновременные пакетные записи не блокируют друг друга взаимно
+ */
+func (cache *Cache[K, V]) lockWrites(keys []K) func() {
+	if cache.store == nil {
+		return func() {}
+	}
+
+	stripes := make([]uint64, 0, len(keys))
+
+	for _, key := range keys {
+		stripes = append(stripes, hashKey(cache.seed, key)%lockStripes)
+	}
+
+	slices.Sort(stripes)
+	stripes = slices.Compact(stripes)
+
+	for _, stripe := range stripes {
+		cache.writes[stripe].Lock()
+	}
+
+	return func() {
+		for _, stripe := range stripes {
+			cache.writes[stripe].Unlock()
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package cache
+
+import "container/list"
+
+/*
+ * Политика вытеснения редко используемых значений (LFU) с операциями за `O(1)`. Ключи сгруппированы
+ * в корзины по количеству обращений, а корзины хранятся в двусвязном списке по возрастанию частоты.
+ * При обращении ключ переносится в соседнюю корзину с частотой на единицу больше, поэтому ни учет
+ * обращения, ни поиск кандидата на вытеснение не требуют сортировки.
+ *
+ * Внутри корзины ключи упорядочены по времени последнего обращения, поэтому при равной частоте
+ * вытесняется ключ, к которому дольше всего не обращались
+ */
+type lfuPolicy[K comparable] struct {
+	// Список корзин `*lfuBucket` по возрастанию частоты обращений
+	buckets *list.List
+	entries map[K]*lfuEntry
+}
+
+// Корзина ключей с одинаковым количеством обращений
+type lfuBucket struct {
+	frequency uint64
+
+	// Ключи корзины: в начале находятся недавно использованные, в конце - кандидаты на вытеснение
+	keys *list.List
+}
+
+// Положение ключа: корзина в списке корзин и элемент в списке ключей корзины
+type lfuEntry struct {
+	bucket  *list.Element
+	element *list.Element
+}
+
+func newLFUPolicy[K comparable]() *lfuPolicy[K] {
+	return &lfuPolicy[K]{
+		buckets: list.New(),
+		entries: make(map[K]*lfuEntry),
+	}
+}
+
+func (policy *lfuPolicy[K]) OnGet(key K) {
+	if entry, ok := policy.entries[key]; ok {
+		policy.touch(key, entry)
+	}
+}
+
+func (policy *lfuPolicy[K]) OnSet(key K) {
+	if entry, ok := policy.entries[key]; ok {
+		policy.touch(key, entry)
+
+		return
+	}
+
+	// Новый ключ попадает в корзину с единичной частотой, которая всегда первая в списке
+	front := policy.buckets.Front()
+
+	if front == nil || front.Value.(*lfuBucket).frequency != 1 {
+		front = policy.buckets.PushFront(&lfuBucket{frequency: 1, keys: list.New()})
+	}
+
+	policy.entries[key] = &lfuEntry{
+		bucket:  front,
+		element: front.Value.(*lfuBucket).keys.PushFront(key),
+	}
+}
+
+func (policy *lfuPolicy[K]) OnDelete(key K) {
+	entry, ok := policy.entries[key]
+
+	if !ok {
+		return
+	}
+
+	policy.detach(entry)
+	delete(policy.entries, key)
+}
+
+func (policy *lfuPolicy[K]) Victim() (K, bool) {
+	front := policy.buckets.Front()
+
+	if front == nil {
+		var zero K
+
+		return zero, false
+	}
+
+	return front.Value.(*lfuBucket).keys.Back().Value.(K), true
+}
+
+// Кандидаты перечисляются по возрастанию частоты, а внутри корзины - от давно использованных ключей
+func (policy *lfuPolicy[K]) victims(n int) []K {
+	var keys []K
+
+	for bucket := policy.buckets.Front(); bucket != nil && len(keys) < n; bucket = bucket.Next() {
+		for element := bucket.Value.(*lfuBucket).keys.Back(); element != nil && len(keys) < n; element = element.Prev() {
+			keys = append(keys, element.Value.(K))
+		}
+	}
+
+	return keys
+}
+
+// Функция учета обращения к ключу: переносим ключ в корзину со следующей частотой
+func (policy *lfuPolicy[K]) touch(key K, entry *lfuEntry) {
+	current := entry.bucket
+	frequency := current.Value.(*lfuBucket).frequency + 1
+
+	next := current.Next()
+
+	if next == nil || next.Value.(*lfuBucket).frequency != frequency {
+		next = policy.buckets.InsertAfter(&lfuBucket{frequency: frequency, keys: list.New()}, current)
+	}
+
+	policy.detach(entry)
+
+	entry.bucket = next
+	entry.element = next.Value.(*lfuBucket).keys.PushFront(key)
+}
+
+// Функция удаления ключа из его корзины. Опустевшая корзина удаляется из списка
+func (policy *lfuPolicy[K]) detach(entry *lfuEntry) {
+	bucket := entry.bucket.Value.(*lfuBucket)
+	bucket.keys.Remove(entry.element)
+
+	if bucket.keys.Len() == 0 {
+		policy.buckets.Remove(entry.bucket)
+	}
+}
@@ -0,0 +1,257 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+/*
+ * Функция получения значения по ключу либо записи переданного значения, если актуальное значение
+ * отсутствует. Проверка и запись выполняются под одной блокировкой, поэтому между ними другой поток
+ * не может записать значение. Возвращает значение из кэша и `true`, если оно уже присутствовало
+ */
+func (cache *Cache[K, V]) GetOrSet(key K, value V) (V, bool) {
+	cache.awaitWrites()
+
+	stored := cache.copyIn(value)
+
+	var (
+		current V
+		found   bool
+	)
+
+	// Ошибка хранилища (`WithStore`) не возвращается: несохраненное значение удаляется из кэша
+	_, _ = cache.writeThrough(context.Background(), key, nil, func(shard *shard[K, V]) (V, bool, []evictedItem[K, V]) {
+		if item, ok := shard.data[key]; ok && !cache.clock.Now().After(item.expireAt) {
+			current, found = item.value, true
+
+			return value, false, nil
+		}
+
+		return value, true, shard.set(key, stored, useDefaultTTL)
+	})
+
+	if found {
+		return cache.copyOut(current), true
+	}
+
+	return value, false
+}
+
+/*
+ * Функция получения значения по ключу либо вычисления его с помощью функции-загрузчика. Загрузчик
+ * вызывается без удержания блокировки, чтобы медленная загрузка (например из базы данных) не блокировала
+ * весь кэш. Одновременные промахи по одному ключу объединяются: загрузчик вызывается только одним потоком,
+ * а остальные дожидаются и получают его результат. Ошибка загрузчика возвращается без записи в кэш
+ */
+func (cache *Cache[K, V]) GetOrCompute(key K, loader func() (V, error)) (V, error) {
+	value, ok := cache.get(key)
+
+	cache.recordRead(key, ok)
+
+	if ok {
+		return cache.copyOut(value), nil
+	}
+
+	value, err := cache.compute(key, loader)
+
+	if err != nil {
+		return value, err
+	}
+
+	return cache.copyOut(value), nil
+}
+
+/*
+ * Функция получения значения по ключу с загрузкой при промахе функцией из `WithLoader`. В отличие от `Get`
+ * возвращает ошибку загрузчика, а контекст передается в загрузчик для отмены медленной загрузки. Без
+ * загрузчика при промахе возвращает `ErrNotFound`. Одновременные промахи по одному ключу объединяются,
+ * при этом загрузчик получает контекст потока, начавшего загрузку
+ */
+func (cache *Cache[K, V]) GetContext(ctx context.Context, key K) (V, error) {
+	if cache.telemetry != nil && cache.loader != nil {
+		var span Span
+
+		ctx, span = cache.telemetry.start(ctx, "cache.get")
+		defer span.End()
+	}
+
+	value, _, err := cache.getContext(ctx, key)
+
+	return value, err
+}
+
+/*
+ * Функция получения значения с признаком устаревания (`WithStaleWhileRevalidate`). Просроченное значение,
+ * окно устаревания которого еще не истекло, возвращается немедленно с `stale == true`, а загрузчик обновляет
+ * его в фоне. Иначе работает как `Get`
+ */
+func (cache *Cache[K, V]) GetStale(key K) (value V, stale bool, ok bool) {
+	value, stale, err := cache.getContext(context.Background(), key)
+
+	return value, stale, err == nil
+}
+
+// Функция получения значения с загрузкой при промахе и возвратом устаревшего значения
+func (cache *Cache[K, V]) getContext(ctx context.Context, key K) (V, bool, error) {
+	value, ok := cache.get(key)
+
+	stale := false
+
+	if !ok && cache.grace > 0 {
+		if value, ok = cache.shardFor(key).stale(key); ok {
+			stale = true
+
+			cache.revalidate(key)
+		}
+	}
+
+	cache.recordRead(key, ok)
+
+	if ok {
+		return cache.copyOut(value), stale, nil
+	}
+
+	if cache.loader == nil {
+		return value, false, ErrNotFound
+	}
+
+	value, err := cache.compute(key, func() (V, error) {
+		return cache.loader(ctx, key)
+	})
+
+	if err != nil {
+		return value, false, err
+	}
+
+	return cache.copyOut(value), false, nil
+}
+
+/*
+ * Функция фонового обновления значения загрузчиком, устаревшего (`WithStaleWhileRevalidate`) либо близкого
+ * к истечению (`WithRefreshAhead`). Для каждого ключа одновременно выполняется не более одного обновления,
+ * а при ошибке загрузчика в кэше остается прежнее значение. Не блокирует вызывающий поток
+ */
+func (cache *Cache[K, V]) revalidate(key K) {
+	if _, loaded := cache.refreshing.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+
+	go func() {
+		defer cache.refreshing.Delete(key)
+
+		_, _ = cache.loads.do(key, func() (V, error) {
+			value, err := cache.loader(context.Background(), key)
+
+			if err != nil {
+				return value, err
+			}
+
+			cache.fill(key, value)
+
+			return value, nil
+		})
+	}()
+}
+
+/*
+ * Функция записи значения, загруженного в фоне, в кэш. Как и остальные записи, проходит через `writeThrough`:
+ * значение сохраняется в хранилище (`WithStore`), а другие экземпляры получают сообщение шины
+ */
+func (cache *Cache[K, V]) fill(key K, value V) {
+	cache.awaitWrites()
+
+	_ = cache.write(context.Background(), key, value, func(shard *shard[K, V]) []evictedItem[K, V] {
+		return shard.set(key, value, useDefaultTTL)
+	})
+}
+
+/*
+ * Функция вычисления значения загрузчиком с объединением одновременных загрузок по ключу
+ * и записью результата в кэш
+ */
+func (cache *Cache[K, V]) compute(key K, loader func() (V, error)) (V, error) {
+	return cache.loads.do(key, func() (V, error) {
+		// Пока данный поток ожидал очереди на загрузку, значение могло
+		// быть записано в кэш, поэтому проверяем хранилище повторно
+		if value, ok := cache.peek(key); ok {
+			return value, nil
+		}
+
+		value, err := loader()
+
+		if err != nil {
+			return value, err
+		}
+
+		// Если другой поток успел записать значение через `Set`, возвращаем его, а не перезаписываем
+		value, _ = cache.GetOrSet(key, value)
+
+		return value, nil
+	})
+}
+
+/*
+ * Механизм объединения одновременных загрузок по одному ключу (singleflight). Первый поток,
+ * промахнувшийся по ключу, выполняет загрузку, а остальные ожидают завершения и получают тот же результат.
+ * Если загрузчик паникует, паника продолжается в потоке, выполнявшем загрузку, а ожидающие потоки
+ * получают ошибку `ErrLoaderPanicked`, а не нулевое значение как результат успешной загрузки
+ */
+type singleflight[K comparable, V any] struct {
+	mutex sync.Mutex
+	calls map[K]*call[V]
+}
+
+// Загрузка значения, выполняющаяся в данный момент
+type call[V any] struct {
+	wg    sync.WaitGroup
+	value V
+	err   error
+}
+
+func (group *singleflight[K, V]) do(key K, fn func() (V, error)) (V, error) {
+	group.mutex.Lock()
+
+	if group.calls == nil {
+		group.calls = make(map[K]*call[V])
+	}
+
+	// Загрузка по ключу уже выполняется - дожидаемся ее результата
+	if c, ok := group.calls[key]; ok {
+		group.mutex.Unlock()
+
+		c.wg.Wait()
+
+		return c.value, c.err
+	}
+
+	c := &call[V]{}
+	c.wg.Add(1)
+	group.calls[key] = c
+
+	group.mutex.Unlock()
+
+	// Снимаем загрузку с учета даже при панике загрузчика, иначе
+	// ожидающие потоки и последующие загрузки по ключу зависнут навсегда
+	defer func() {
+		group.mutex.Lock()
+		delete(group.calls, key)
+		group.mutex.Unlock()
+
+		c.wg.Done()
+	}()
+
+	// Ошибка паники записывается до снятия загрузки с учета, поэтому ожидающие потоки ее увидят
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("%w: %v", ErrLoaderPanicked, r)
+
+			panic(r)
+		}
+	}()
+
+	c.value, c.err = fn()
+
+	return c.value, c.err
+}
@@ -0,0 +1,251 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachetest"
+)
+
+func TestLoaderPanicIsReportedToWaiters(t *testing.T) {
+	values := newValues(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	leader := make(chan any, 1)
+
+	go func() {
+		defer func() { leader <- recover() }()
+
+		values.GetOrCompute("a", func() (int, error) {
+			close(started)
+			<-release
+
+			panic("boom")
+		})
+	}()
+
+	<-started
+
+	const waiters = 4
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, waiters)
+
+	for range waiters {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// Поток, опоздавший к загрузке, выполняет свою, поэтому ошибка ожидается только от ожидавших
+			value, err := values.GetOrCompute("a", func() (int, error) { return 0, errors.New("late") })
+
+			if value != 0 {
+				t.Errorf("expected zero value from a failed load, got %d", value)
+			}
+
+			errs <- err
+		}()
+	}
+
+	// Ожидающие потоки подключаются к загрузке, пока загрузчик заблокирован
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	wg.Wait()
+	close(errs)
+
+	if r := <-leader; r != "boom" {
+		t.Fatalf("expected the panic to reach the loading goroutine, got %v", r)
+	}
+
+	for err := range errs {
+		if !errors.Is(err, cache.ErrLoaderPanicked) {
+			t.Fatalf("expected ErrLoaderPanicked, got %v", err)
+		}
+	}
+
+	if _, ok := values.Peek("a"); ok {
+		t.Fatal("expected nothing to be cached after a panic")
+	}
+
+	// Следующая загрузка по ключу выполняется заново
+	if value, err := values.GetOrCompute("a", func() (int, error) { return 1, nil }); err != nil || value != 1 {
+		t.Fatalf("expected a fresh load after a panic, got %d, %v", value, err)
+	}
+}
+
+// Результат загрузки, который тест передает заблокированному загрузчику
+type loadResult struct {
+	value int
+	err   error
+}
+
+/*
+ * Загрузчик, который считает вызовы и ожидает результат каждой загрузки из канала, поэтому тест
+ * управляет моментом завершения фонового обновления
+ */
+type gatedLoader struct {
+	mutex   sync.Mutex
+	calls   int
+	results chan loadResult
+}
+
+func newGatedLoader() *gatedLoader {
+	return &gatedLoader{results: make(chan loadResult)}
+}
+
+func (loader *gatedLoader) load(ctx context.Context, key string) (int, error) {
+	loader.mutex.Lock()
+	loader.calls++
+	loader.mutex.Unlock()
+
+	result := <-loader.results
+
+	return result.value, result.err
+}
+
+func (loader *gatedLoader) called() int {
+	loader.mutex.Lock()
+
+	defer loader.mutex.Unlock()
+
+	return loader.calls
+}
+
+// Функция завершения очередной загрузки значением `value`
+func (loader *gatedLoader) complete(value int) {
+	loader.results <- loadResult{value: value}
+}
+
+// Функция чтения значения, которое загружается синхронно, с передачей результата загрузчику из другого потока
+func loadThrough(t *testing.T, values *cache.Cache[string, int], loader *gatedLoader, key string, value int) {
+	t.Helper()
+
+	go loader.complete(value)
+
+	if loaded, err := values.GetContext(context.Background(), key); err != nil || loaded != value {
+		t.Fatalf("expected %q to load %d, got %d, %v", key, value, loaded, err)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	clock := cachetest.NewFakeClock(time.Now())
+	loader := newGatedLoader()
+
+	values := newValues(t, cache.WithClock(clock), cache.WithTTL(time.Minute), cache.WithStaleWhileRevalidate(time.Minute), cache.WithLoader(loader.load))
+
+	loadThrough(t, values, loader, "a", 1)
+
+	clock.Advance(time.Minute + time.Second)
+
+	// Пока загрузчик заблокирован, все чтения немедленно получают устаревшее значение и не запускают
+	// дополнительных обновлений
+	for range 10 {
+		if value, stale, ok := values.GetStale("a"); !ok || !stale || value != 1 {
+			t.Fatalf("expected the stale value while refreshing, got %d, %v, %v", value, stale, ok)
+		}
+	}
+
+	if calls := loader.called(); calls > 2 {
+		t.Fatalf("expected exactly one background refresh, got %d loads", calls)
+	}
+
+	loader.complete(2)
+
+	eventually(t, func() bool {
+		value, stale, ok := values.GetStale("a")
+
+		return ok && !stale && value == 2
+	})
+
+	if calls := loader.called(); calls != 2 {
+		t.Fatalf("expected exactly one background refresh, got %d loads", calls)
+	}
+
+	// Ошибка обновления оставляет устаревшее значение, и следующее чтение пробует обновить его снова
+	clock.Advance(time.Minute + time.Second)
+
+	if value, stale, ok := values.GetStale("a"); !ok || !stale || value != 2 {
+		t.Fatalf("expected the stale value before the failed refresh, got %d, %v, %v", value, stale, ok)
+	}
+
+	loader.results <- loadResult{err: errors.New("database is down")}
+
+	eventually(t, func() bool {
+		value, stale, ok := values.GetStale("a")
+
+		return ok && stale && value == 2 && loader.called() == 4
+	})
+
+	loader.complete(3)
+
+	eventually(t, func() bool {
+		value, stale, ok := values.GetStale("a")
+
+		return ok && !stale && value == 3
+	})
+}
+
+func TestRefreshAhead(t *testing.T) {
+	clock := cachetest.NewFakeClock(time.Now())
+	loader := newGatedLoader()
+
+	values := newValues(t, cache.WithClock(clock), cache.WithTTL(time.Minute), cache.WithRefreshAhead(10*time.Second, 3), cache.WithLoader(loader.load))
+
+	loadThrough(t, values, loader, "hot", 1)
+	loadThrough(t, values, loader, "cold", 1)
+
+	// Достаточно частые чтения не обновляют значение, пока до истечения больше окна
+	for range 3 {
+		values.Get("hot")
+	}
+
+	values.Get("cold")
+
+	clock.Advance(55 * time.Second)
+
+	// Редко читаемое значение не обновляется и в окне перед истечением
+	values.Get("cold")
+
+	if calls := loader.called(); calls != 2 {
+		t.Fatalf("expected no refresh before the hotness threshold and the window, got %d loads", calls)
+	}
+
+	// Чтения во время обновления возвращают прежнее значение и не запускают повторных обновлений
+	for range 5 {
+		if value, ok := values.Get("hot"); !ok || value != 1 {
+			t.Fatalf("expected the current value while refreshing, got %d, %v", value, ok)
+		}
+	}
+
+	loader.complete(2)
+
+	eventually(t, func() bool {
+		value, ok := values.Peek("hot")
+
+		return ok && value == 2
+	})
+
+	if calls := loader.called(); calls != 3 {
+		t.Fatalf("expected exactly one refresh of the hot key, got %d loads", calls)
+	}
+
+	// Обновленное значение живет полный TTL от момента обновления, а холодное истекает
+	clock.Advance(10 * time.Second)
+
+	if value, ok := values.Peek("hot"); !ok || value != 2 {
+		t.Fatalf("expected the refreshed value to outlive the original ttl, got %d, %v", value, ok)
+	}
+
+	if _, ok := values.Peek("cold"); ok {
+		t.Fatal("expected the cold key to expire without a refresh")
+	}
+}
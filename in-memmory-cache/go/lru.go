@@ -0,0 +1,65 @@
+package cache
+
+import "container/list"
+
+/*
+ * Политика вытеснения давно не использованных значений (LRU). Ключи хранятся в двусвязном списке
+ * в порядке использования: в начале находятся недавно использованные, в конце - кандидаты на вытеснение.
+ * Перемещение ключа и поиск кандидата выполняются за `O(1)`
+ */
+type lruPolicy[K comparable] struct {
+	recency  *list.List
+	elements map[K]*list.Element
+}
+
+func newLRUPolicy[K comparable]() *lruPolicy[K] {
+	return &lruPolicy[K]{
+		recency:  list.New(),
+		elements: make(map[K]*list.Element),
+	}
+}
+
+func (policy *lruPolicy[K]) OnGet(key K) {
+	if element, ok := policy.elements[key]; ok {
+		policy.recency.MoveToFront(element)
+	}
+}
+
+func (policy *lruPolicy[K]) OnSet(key K) {
+	if element, ok := policy.elements[key]; ok {
+		policy.recency.MoveToFront(element)
+
+		return
+	}
+
+	policy.elements[key] = policy.recency.PushFront(key)
+}
+
+func (policy *lruPolicy[K]) OnDelete(key K) {
+	if element, ok := policy.elements[key]; ok {
+		policy.recency.Remove(element)
+		delete(policy.elements, key)
+	}
+}
+
+func (policy *lruPolicy[K]) Victim() (K, bool) {
+	element := policy.recency.Back()
+
+	if element == nil {
+		var zero K
+
+		return zero, false
+	}
+
+	return element.Value.(K), true
+}
+
+func (policy *lruPolicy[K]) victims(n int) []K {
+	var keys []K
+
+	for element := policy.recency.Back(); element != nil && len(keys) < n; element = element.Prev() {
+		keys = append(keys, element.Value.(K))
+	}
+
+	return keys
+}
@@ -1,7 +1,8 @@
 package cache
 
 import (
-	"sync"
+	"context"
+	"slices"
 	"time"
 )
 
@@ -29,132 +30,123 @@ type Order struct {
 	UpdatedAt time.Time
 }
 
-type Cache struct {
-	ttl   time.Duration
-	data  map[string]*CacheItem
-	mutex sync.RWMutex
+/*
+ * Кэш профилей пользователей. Является тонкой оберткой над обобщенным кэшем `Cache[string, *Profile]`,
+ * ключом которого выступает `UUID` профиля. Все методы, работающие с ключом (например `Get`), доступны
+ * напрямую через встроенный обобщенный кэш, а методы, принимающие профиль, берут ключ из `Profile.UUID`.
+ */
+type ProfileCache struct {
+	*Cache[string, *Profile]
 }
 
-type CacheItem struct {
-	profile  *Profile
-	expireAt time.Time
-}
+// Функция-конструктор для создания кэша профилей. Параметры кэша задаются функциональными
+// опциями (`WithTTL`, `WithCleanupInterval`, `WithMaxEntries`, `WithOnEvicted`), при некорректных
+// значениях опций возвращается ошибка
+func New(opts ...Option) (*ProfileCache, error) {
+	// Индекс заказов, удаление устаревших заказов и функция копирования профиля добавляются последними, поэтому
+	// индекс не может быть переопределен пользователем, а копирование - только через `WithCloner`
+	cache, err := NewCache[string, *Profile](append(slices.Clip(opts), withIndex(orderKeys), withPrune(pruneOrders), withDefaultCloner(cloneProfile))...)
 
-// Функция-конструктор для создания единицы кэш-хранилища. Параллельно с созданием кэша
-// запускаем сборщик мусора, который каждые K-секунд очищает хранилище от протухших значений.
-func New(ttl time.Duration) *Cache {
-	cache := &Cache{
-		data:  make(map[string]*CacheItem),
-		ttl:   ttl,
-		mutex: sync.RWMutex{},
+	if err != nil {
+		return nil, err
 	}
 
-	go cache.GarbageCollector()
-
-	return cache
+	return &ProfileCache{Cache: cache}, nil
 }
 
 /*
- * Функция получения значения кэша по уникальному идентификатору `UUID`
+ * Функция записи профиля в кэш-хранилище. Ключом выступает `UUID` профиля
  */
-func (cache *Cache) Get(UUID string) (*Profile, bool) {
-	// На время действия функции получения значения
-	// блокируем мьютекс на чтение кэш-хранилища
-	cache.mutex.RLock()
-
-	// При завершении функции получения значения снимаем
-	// блокировку с мьютекса на чтения хранилища
-	defer cache.mutex.RUnlock()
-
-	item, ok := cache.data[UUID]
-
-	if !ok {
-		return nil, false
-	}
+func (cache *ProfileCache) Set(profile *Profile) {
+	cache.Cache.Set(profile.UUID, profile)
+}
 
-	// В случае если значение кэша просрочено возвращаем нулево значение
-	if time.Now().After(item.expireAt) {
-		return nil, false
-	}
+/*
+ * Функция записи профиля в кэш-хранилище с индивидуальным временем жизни
+ */
+func (cache *ProfileCache) SetWithTTL(profile *Profile, ttl time.Duration) {
+	cache.Cache.SetWithTTL(profile.UUID, profile, ttl)
+}
 
-	return item.profile, true
+/*
+ * Функция записи профиля в кэш-хранилище с абсолютным временем истечения, например окончанием акции
+ */
+func (cache *ProfileCache) SetWithExpireAt(profile *Profile, at time.Time) {
+	cache.Cache.SetWithExpireAt(profile.UUID, profile, at)
 }
 
 /*
- * Функция записи значения в кэш-хранилище
+ * Функция записи профиля в кэш-хранилище с приоритетом и стоимостью при вытеснении (`WithPriority`, `WithCost`)
  */
-func (cache *Cache) Set(profile *Profile) {
-	// На время действия функции записи значения
-	// блокируем мьютекс на запись в кэш-хранилище
-	cache.mutex.Lock()
+func (cache *ProfileCache) SetWithOptions(profile *Profile, opts ...SetOption) error {
+	return cache.Cache.SetWithOptions(profile.UUID, profile, opts...)
+}
 
-	// При завершении функции снимаем блокировку с мьютекса
-	// на запись значений в кэш-хранилище
-	defer cache.mutex.Unlock()
+/*
+ * Функция получения профиля по `UUID` либо записи переданного профиля, если актуальное значение отсутствует.
+ * Возвращает профиль из кэша и `true`, если он уже присутствовал
+ */
+func (cache *ProfileCache) GetOrSet(profile *Profile) (*Profile, bool) {
+	return cache.Cache.GetOrSet(profile.UUID, profile)
+}
 
-	// Устанавливаем/обновляем время истечения кэша
-	expireAt := time.Now().Add(cache.ttl)
+/*
+ * Функция записи профиля, только если профиля с таким `UUID` нет в кэш-хранилище.
+ * Возвращает `ErrExists`, если актуальный профиль уже присутствует
+ */
+func (cache *ProfileCache) Add(profile *Profile) error {
+	return cache.Cache.Add(profile.UUID, profile)
+}
 
-	cache.data[profile.UUID] = &CacheItem{
-		profile:  profile,
-		expireAt: expireAt,
-	}
+/*
+ * Функция перезаписи профиля, только если профиль с таким `UUID` присутствует в кэш-хранилище.
+ * Возвращает `ErrNotFound`, если профиль отсутствует или просрочен
+ */
+func (cache *ProfileCache) Replace(profile *Profile) error {
+	return cache.Cache.Replace(profile.UUID, profile)
 }
 
 /*
- * Оптимизация функции: Есть возможность оптимизировать время для взаимодействия с хэш-хранилищем во время
- * выполнения процедуры следующим образом - Вместо блокировки мьютекса на запись, блокируем мьютекс на чтение
- * и собираем ID каждой просроченной записи кэша в отделный срез с помощью метода `append`. После окончательного
- * сбора всех идентификаторов просроченных записей начинаем очистку и паралелльно блокируем мьютекс на запись значений.
- *
- * Путем подобной оптимизации можем позволить другим тредам
+ * Функция записи профиля с сохранением в хранилище (`WithStore`). Возвращает ошибку хранилища
  */
-func cleanCacheItems(cache *Cache) {
-	// До момента сбора идентификаторов протухших кэш-значений блокируем мьютекс на чтение
-	// из кэш-хранилища, поскольку может возникнуть конфликт при прочтении удаляемого значения
-	cache.mutex.RLock()
+func (cache *ProfileCache) SetContext(ctx context.Context, profile *Profile) error {
+	return cache.Cache.SetContext(ctx, profile.UUID, profile)
+}
 
-	// При завершении выполении функции снимаем блокировку с мьютекса и разрешаем
-	// запись и создание новых кэш-значений
-	defer cache.mutex.Unlock()
+/*
+ * Функция записи профиля со слиянием заказов с профилем в кэше вместо замены всего списка заказов,
+ * поэтому заказы, добавленные другими потоками, сохраняются. Заказы с одинаковым `UUID` объединяются,
+ * и остается заказ с более поздним `UpdatedAt`, а при равном времени - переданный. Остальные поля профиля
+ * берутся из переданного профиля. Чтение и запись выполняются под одной блокировкой. Если профиля нет
+ * в кэше, записывает переданный профиль. Возвращает ошибку хранилища (`WithStore`)
+ */
+func (cache *ProfileCache) SetMerge(profile *Profile) error {
+	profile = cache.copyIn(profile)
 
-	// Срез идентификаторов истекших по времени кэш-значений.
-	expiredCacheItemIds := make([]string, len(cache.data))
+	return cache.upsert(profile.UUID, func(current *Profile, ok bool) *Profile {
+		if !ok {
+			return profile
+		}
 
-	// В данном цикле исключител ьно ищем истекшие по времени хэш-значения и
-	// помещаем и в срез для последующего удаления
-	for id, item := range cache.data {
-		isCacheItemExpired := time.Now().After(item.expireAt)
+		orders := mergeOrders(current.Orders, profile.Orders)
 
-		if isCacheItemExpired {
-			expiredCacheItemIds = append(expiredCacheItemIds, id)
+		if cache.maxOrders > 0 {
+			orders = trimOrders(orders, cache.maxOrders)
 		}
-	}
-
-	// Снимаем блокировку мьютекса после сбора всех идентификаторов протухших
-	// кэш-значений и обновляем его на чтение до момента удаления всех собранных кэшей
-	cache.mutex.RUnlock()
-	cache.mutex.Lock()
 
-	// Удаляем из кэша все истекшие по времени значения
-	for _, id := range expiredCacheItemIds {
-		delete(cache.data, id)
-	}
+		return withOrders(profile, orders)
+	})
 }
 
-func (cache *Cache) GarbageCollector() {
-	// Запускаем сборщик мусора, который срабатывает каждые N-секунд
-	// по интервалу и удаляет значения из кэш-хранилища. В данном случае интервал
-	// срабатывает каждую минуту. Чем больше интервал по очистке хранилища, тем больше памяти оно начинает занимать
-	ticker := time.NewTicker(time.Minute)
-
-	// При завершении очистки закрываем интервал
-	defer ticker.Stop()
+/*
+ * Функция записи нескольких профилей за одну блокировку каждого затронутого сегмента
+ */
+func (cache *ProfileCache) SetMany(profiles []*Profile) {
+	items := make(map[string]*Profile, len(profiles))
 
-	for {
-		select {
-		case <-ticker.C:
-			cleanCacheItems(cache)
-		}
+	for _, profile := range profiles {
+		items[profile.UUID] = profile
 	}
+
+	cache.Cache.SetMany(items)
 }
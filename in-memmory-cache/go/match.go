@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+/*
+ * Функция удаления всех значений, строковое представление ключа которых начинается с `prefix`
+ * (например всех тестовых пользователей). Каждый сегмент блокируется один раз. При заданном хранилище
+ * (`WithStore`) значения удаляются и из него. Возвращает количество удаленных значений
+ */
+func (cache *Cache[K, V]) DeleteByPrefix(prefix string) int {
+	cache.awaitWrites()
+
+	var evicted []evictedItem[K, V]
+
+	for _, shard := range cache.shards {
+		shard.locked(func() {
+			for key, item := range shard.data {
+				if strings.HasPrefix(keyString(key), prefix) {
+					shard.remove(key, EvictedDeleted)
+
+					evicted = append(evicted, evictedItem[K, V]{key: key, value: item.value, reason: EvictedDeleted})
+				}
+			}
+		})
+	}
+
+	cache.notifyEvicted(evicted)
+
+	for _, item := range evicted {
+		cache.broadcast(item.key)
+	}
+
+	if cache.store != nil {
+		for _, item := range evicted {
+			_ = cache.drop(context.Background(), item.key)
+		}
+	}
+
+	return len(evicted)
+}
+
+/*
+ * Функция получения ключей актуальных значений, строковое представление которых соответствует
+ * шаблону `pattern` в синтаксисе `path.Match` (`*`, `?`, `[a-z]`). Символ `*` не совпадает с `/`.
+ * Для некорректного шаблона возвращает `path.ErrBadPattern`. Порядок ключей не гарантируется
+ */
+func (cache *Cache[K, V]) KeysMatching(pattern string) ([]K, error) {
+	// Проверяем шаблон заранее, иначе ошибка обнаружится только на первом подходящем ключе
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	var keys []K
+
+	for _, key := range cache.Keys() {
+		if ok, _ := path.Match(pattern, keyString(key)); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// Функция получения строкового представления ключа для сопоставления с префиксом и шаблоном
+func keyString[K comparable](key K) string {
+	if k, ok := any(key).(string); ok {
+		return k
+	}
+
+	return fmt.Sprint(key)
+}
@@ -0,0 +1,942 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// Время жизни значений по умолчанию, если `WithTTL` не передан в конструктор
+	DefaultTTL = time.Minute
+
+	// Интервал работы сборщика мусора по умолчанию
+	DefaultCleanupInterval = time.Minute
+
+	// Время жизни значения, которое не истекает и хранится до удаления или вытеснения:
+	// `SetWithTTL(key, value, NoExpiration)`
+	NoExpiration time.Duration = -1
+)
+
+/*
+ * Время истечения значений без истечения (`NoExpiration`). Дальше любого реального времени, поэтому
+ * проверки истечения не требуют отдельного условия, но остается в пределах, допустимых для JSON
+ */
+var neverExpires = time.Date(9999, time.December, 31, 23, 59, 59, 0, time.UTC)
+
+/*
+ * Функциональная опция конструктора кэш-хранилища. Опция проверяет переданные значения
+ * и возвращает ошибку, если они некорректны
+ */
+type Option func(*options) error
+
+// Набор параметров, из которого конструктор собирает кэш-хранилище
+type options struct {
+	ttl             time.Duration
+	cleanupInterval time.Duration
+	clock           Clock
+	capacity        int
+	maxBytes        int64
+	lowWatermark    float64
+	shards          int
+	initialCapacity int
+	sliding         bool
+	expiration      ExpirationEngine
+
+	// Пользовательская функция оценки размера значения, приводится к типу значения кэша в конструкторе
+	sizer any
+
+	// Встроенная политика вытеснения либо фабрика пользовательской политики. Фабрика
+	// хранится без типа и приводится к типу ключа кэша в конструкторе
+	policy       *Policy
+	customPolicy any
+	tinyLFU      bool
+
+	// Функция обратного вызова хранится без типа, поскольку опции не параметризованы
+	// типами ключа и значения. Соответствие типов проверяется в конструкторе
+	onEvicted any
+
+	// Загрузчик значения при промахе и хранилище для сквозной записи,
+	// приводятся к типам ключа и значения кэша в конструкторе
+	loader any
+	store  any
+
+	// Окно, в течение которого просроченное значение возвращается как устаревшее
+	staleWindow time.Duration
+
+	// Окно до истечения значения и минимальное количество чтений для фонового обновления
+	refreshWindow time.Duration
+	refreshHits   int
+
+	// Файл и интервал периодических снимков кэша
+	snapshotPath     string
+	snapshotInterval time.Duration
+
+	// Копирование значений на границе API и функция копирования,
+	// приводимая к типу значения кэша в конструкторе
+	copyOnRead  bool
+	copyOnWrite bool
+	cloner      any
+
+	// Файл журнала изменений
+	logPath string
+
+	// Отложенная запись в хранилище: интервал сброса очереди и размер очереди, при котором она сбрасывается досрочно
+	writeBehind   bool
+	flushInterval time.Duration
+	flushSize     int
+
+	// Журнал событий кэша
+	logger *slog.Logger
+
+	// Шина сообщений об изменении значений между экземплярами сервиса
+	bus InvalidationBus
+
+	// Провайдеры трассировки и метрик OpenTelemetry
+	tracerProvider TracerProvider
+	meterProvider  MeterProvider
+
+	// Функция получения вторичных ключей значения для внутреннего индекса (`withIndex`)
+	indexKeys any
+
+	// Время жизни заказов профиля и функция удаления устаревших частей значения (`withPrune`)
+	orderTTL time.Duration
+	prune    any
+
+	// Максимальное количество заказов профиля
+	maxOrders int
+
+	// Чтение из неизменяемых копий сегментов без блокировки
+	readOptimized bool
+
+	// Учет времени последнего чтения и количества чтений каждого значения, учитывается одно из `accessSampling` чтений
+	accessTracking bool
+	accessSampling int
+
+	// Учет статистики за последние минуты по интервалам (`StatsWindow`)
+	statsWindow bool
+
+	// Размер буфера записи, применяемой фоновой горутиной
+	writeBuffer int
+
+	// Шаг обновления грубых часов
+	coarseClock time.Duration
+
+	// Доля времени жизни, в пределах которой случайно смещается время истечения значения
+	ttlJitter float64
+
+	// Время простоя, после которого значение истекает независимо от времени жизни
+	maxIdle time.Duration
+
+	// Удаление просроченных значений без фоновой горутины сборщика мусора
+	withoutBackgroundGC bool
+
+	// Границы интервала сборщика мусора, подстраиваемого под долю истекающих значений
+	minCleanupInterval time.Duration
+	maxCleanupInterval time.Duration
+}
+
+func defaultOptions() *options {
+	return &options{
+		ttl:             DefaultTTL,
+		cleanupInterval: DefaultCleanupInterval,
+		clock:           systemClock{},
+		shards:          1,
+		lowWatermark:    1,
+	}
+}
+
+// Функция применения опций к параметрам по умолчанию
+func newOptions(opts []Option) (*options, error) {
+	o := defaultOptions()
+
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+
+	return o, nil
+}
+
+/*
+ * Опция времени жизни значений кэша. Время жизни должно быть положительным
+ */
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) error {
+		if ttl <= 0 {
+			return fmt.Errorf("cache: ttl must be positive, got %s", ttl)
+		}
+
+		o.ttl = ttl
+
+		return nil
+	}
+}
+
+/*
+ * Опция случайного разброса времени жизни. Время истечения каждого значения смещается случайно
+ * в пределах `±fraction` его TTL (например 0.1 - ±10%), поэтому профили, загруженные при прогреве
+ * кэша одновременно, истекают в разное время и не приходят в базу данных одной волной. Разброс
+ * применяется при каждой записи и продлении времени жизни. Доля должна быть в пределах `[0, 1)`
+ */
+func WithTTLJitter(fraction float64) Option {
+	return func(o *options) error {
+		if !(fraction >= 0 && fraction < 1) {
+			return fmt.Errorf("cache: ttl jitter must be in [0, 1), got %v", fraction)
+		}
+
+		o.ttlJitter = fraction
+
+		return nil
+	}
+}
+
+/*
+ * Опция максимального времени простоя. Значение, которое не читалось через `Get` (и не продлевалось
+ * `Touch`) в течение `d`, истекает, даже если его время жизни еще не прошло. Например, профили
+ * пользователей, которые вышли из системы, освобождают память раньше TTL. Время жизни значения
+ * по-прежнему ограничивает его сверху. Чтение изменяет время истечения, поэтому `Get` блокирует
+ * сегмент на запись, как при `WithSlidingExpiration`
+ */
+func WithMaxIdle(d time.Duration) Option {
+	return func(o *options) error {
+		if d <= 0 {
+			return fmt.Errorf("cache: max idle must be positive, got %s", d)
+		}
+
+		o.maxIdle = d
+
+		return nil
+	}
+}
+
+/*
+ * Опция интервала работы сборщика мусора. Интервал должен быть положительным
+ */
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(o *options) error {
+		if interval <= 0 {
+			return fmt.Errorf("cache: cleanup interval must be positive, got %s", interval)
+		}
+
+		o.cleanupInterval = interval
+
+		return nil
+	}
+}
+
+/*
+ * Опция работы без фонового сборщика мусора для кэшей в короткоживущих процессах (CLI, функции,
+ * тесты). Горутина сборщика мусора не запускается, а просроченные значения удаляются при обращении:
+ * промах `Get` по просроченному значению удаляет его, а каждая запись проверяет выборку значений
+ * сегмента, как `WithExpirationEngine(Sampling)`. Остальное освобождается вызовом `DeleteExpired`
+ */
+func WithoutBackgroundGC() Option {
+	return func(o *options) error {
+		o.withoutBackgroundGC = true
+
+		return nil
+	}
+}
+
+/*
+ * Опция адаптивного интервала сборщика мусора. После каждого прохода интервал подстраивается под
+ * долю удаленных значений: если истекла заметная часть хранилища, интервал сокращается вдвое, чтобы
+ * истекшие значения не занимали память, а если проход ничего не удалил или хранилище пусто - удваивается,
+ * чтобы простаивающий кэш не просматривался впустую. Интервал не выходит за границы `[min, max]`,
+ * а начинается с `WithCleanupInterval`, приведенного к этим границам
+ */
+func WithAdaptiveCleanup(min, max time.Duration) Option {
+	return func(o *options) error {
+		if min <= 0 || max < min {
+			return fmt.Errorf("cache: adaptive cleanup bounds must satisfy 0 < min <= max, got %s and %s", min, max)
+		}
+
+		o.minCleanupInterval = min
+		o.maxCleanupInterval = max
+
+		return nil
+	}
+}
+
+/*
+ * Опция источника времени кэша. По умолчанию используются системные часы. Управляемые часы
+ * (`cachetest.FakeClock`) позволяют детерминированно проверять истечение значений в тестах
+ */
+func WithClock(clock Clock) Option {
+	return func(o *options) error {
+		if clock == nil {
+			return fmt.Errorf("cache: clock must not be nil")
+		}
+
+		o.clock = clock
+
+		return nil
+	}
+}
+
+/*
+ * Опция максимального количества значений в кэш-хранилище. При достижении предела запись нового
+ * значения вытесняет значение, выбранное политикой вытеснения (по умолчанию `LRU`).
+ * Нулевое значение снимает ограничение
+ */
+func WithMaxEntries(n int) Option {
+	return func(o *options) error {
+		if n < 0 {
+			return fmt.Errorf("cache: max entries must not be negative, got %d", n)
+		}
+
+		o.capacity = n
+
+		return nil
+	}
+}
+
+/*
+ * Опция бюджета памяти кэш-хранилища в байтах. Размер каждого значения оценивается при записи
+ * (по умолчанию с помощью рефлексии, либо функцией из `WithSizer`), и при превышении бюджета
+ * вытесняются значения, выбранные политикой вытеснения. Нулевое значение снимает ограничение
+ */
+func WithMaxBytes(n int64) Option {
+	return func(o *options) error {
+		if n < 0 {
+			return fmt.Errorf("cache: max bytes must not be negative, got %d", n)
+		}
+
+		o.maxBytes = n
+
+		return nil
+	}
+}
+
+/*
+ * Опция нижней границы вытеснения в долях ограничения емкости (`WithMaxEntries`) и памяти (`WithMaxBytes`).
+ * При достижении ограничения значения вытесняются за один проход, пока заполнение не опустится до нижней
+ * границы, поэтому последующие записи не вытесняют значения по одному. Например, `WithLowWatermark(0.85)`
+ * освобождает 15% емкости. Допустимы значения в интервале `(0, 1]`, по умолчанию `1`
+ */
+func WithLowWatermark(fraction float64) Option {
+	return func(o *options) error {
+		if !(fraction > 0 && fraction <= 1) {
+			return fmt.Errorf("cache: low watermark must be in (0, 1], got %v", fraction)
+		}
+
+		o.lowWatermark = fraction
+
+		return nil
+	}
+}
+
+/*
+ * Опция функции оценки размера значения в байтах для `WithMaxBytes`. Позволяет заменить
+ * медленную оценку с помощью рефлексии точным и быстрым подсчетом для конкретного типа значения
+ */
+func WithSizer[V any](sizer Sizer[V]) Option {
+	return func(o *options) error {
+		if sizer == nil {
+			return fmt.Errorf("cache: sizer must not be nil")
+		}
+
+		o.sizer = sizer
+
+		return nil
+	}
+}
+
+// Функция выбора функции оценки размера для кэша с ключами типа `K` и значениями типа `V`
+func sizerFunc[K comparable, V any](o *options) (func(K, V) int64, error) {
+	if o.sizer == nil {
+		return func(key K, value V) int64 {
+			return EstimateSize(key) + EstimateSize(value)
+		}, nil
+	}
+
+	sizer, ok := o.sizer.(Sizer[V])
+
+	if !ok {
+		return nil, fmt.Errorf("cache: sizer has type %T, want %T", o.sizer, sizer)
+	}
+
+	return func(_ K, value V) int64 {
+		return sizer(value)
+	}, nil
+}
+
+/*
+ * Опция максимального количества значений в кэш-хранилище.
+ *
+ * Deprecated: используйте `WithMaxEntries`
+ */
+func WithCapacity(capacity int) Option {
+	return WithMaxEntries(capacity)
+}
+
+/*
+ * Опция встроенной политики вытеснения (`LRU`, `LFU`, `FIFO`, `ARC`, `CLOCK`, `SLRU`, `TwoQueue`, `Random`).
+ * Применяется только вместе с `WithMaxEntries` или `WithMaxBytes`, по умолчанию используется `LRU`
+ */
+func WithPolicy(policy Policy) Option {
+	return func(o *options) error {
+		if policy < LRU || policy > Random {
+			return fmt.Errorf("cache: unknown eviction policy %s", policy)
+		}
+
+		o.policy = &policy
+		o.customPolicy = nil
+
+		return nil
+	}
+}
+
+/*
+ * Опция пользовательской политики вытеснения. Фабрика вызывается при создании кэша и при
+ * его полной очистке, поэтому каждый раз должна возвращать новый экземпляр политики.
+ * Применяется только вместе с `WithMaxEntries` или `WithMaxBytes`
+ */
+func WithEvictionPolicy[K comparable](factory func() EvictionPolicy[K]) Option {
+	return func(o *options) error {
+		if factory == nil {
+			return fmt.Errorf("cache: eviction policy factory must not be nil")
+		}
+
+		o.policy = nil
+		o.customPolicy = factory
+
+		return nil
+	}
+}
+
+/*
+ * Опция вытеснения W-TinyLFU. Новые значения попадают в окно LRU размером 1% емкости, а при вытеснении
+ * из окна фильтр допуска сравнивает приближенные частоты обращений к ключу окна и к кандидату политики
+ * вытеснения и вытесняет тот, к которому обращались реже. Защищает "горячие" значения от вытеснения при
+ * сканировании. Применяется только вместе с `WithMaxEntries`
+ */
+func WithTinyLFU(enabled bool) Option {
+	return func(o *options) error {
+		o.tinyLFU = enabled
+
+		return nil
+	}
+}
+
+// Функция выбора фабрики политики вытеснения для кэша с ключами типа `K`
+func policyFactory[K comparable](o *options) (func() EvictionPolicy[K], error) {
+	if o.customPolicy != nil {
+		factory, ok := o.customPolicy.(func() EvictionPolicy[K])
+
+		if !ok {
+			return nil, fmt.Errorf("cache: eviction policy factory has type %T, want %T", o.customPolicy, factory)
+		}
+
+		return factory, nil
+	}
+
+	policy := LRU
+
+	if o.policy != nil {
+		policy = *o.policy
+	}
+
+	// Проверяем политику заранее, чтобы фабрика не могла вернуть ошибку
+	if _, err := newPolicy[K](policy); err != nil {
+		return nil, err
+	}
+
+	return func() EvictionPolicy[K] {
+		p, _ := newPolicy[K](policy)
+
+		return p
+	}, nil
+}
+
+/*
+ * Опция количества сегментов кэш-хранилища. Ключи распределяются по сегментам по хешу, и каждый
+ * сегмент имеет собственную блокировку, поэтому запись в разные сегменты не конкурирует между собой.
+ * Ограничения `WithMaxEntries` и `WithMaxBytes` делятся между сегментами поровну, а сборщик мусора
+ * очищает сегменты по очереди. По умолчанию используется один сегмент
+ */
+func WithShards(n int) Option {
+	return func(o *options) error {
+		if n < 1 {
+			return fmt.Errorf("cache: shard count must be positive, got %d", n)
+		}
+
+		o.shards = n
+
+		return nil
+	}
+}
+
+/*
+ * Опция начальной емкости кэш-хранилища. Словари сегментов сразу выделяются под `n` значений (поровну
+ * между сегментами), поэтому заполнение кэша сотнями тысяч профилей при запуске не перестраивает
+ * словари по мере роста. Емкость сохраняется и после `Clear`. Не ограничивает количество значений
+ */
+func WithInitialCapacity(n int) Option {
+	return func(o *options) error {
+		if n < 0 {
+			return fmt.Errorf("cache: initial capacity must not be negative, got %d", n)
+		}
+
+		o.initialCapacity = n
+
+		return nil
+	}
+}
+
+/*
+ * Опция скользящего времени жизни. При включении каждое успешное чтение значения через `Get`
+ * заново отсчитывает его TTL, а для чтения без продления используется `Peek`
+ */
+func WithSlidingExpiration(enabled bool) Option {
+	return func(o *options) error {
+		o.sliding = enabled
+
+		return nil
+	}
+}
+
+/*
+ * Опция чтения без блокировки для нагрузки с преобладанием чтений. `Get` читает значения из копии словаря
+ * сегмента, ячейки ключей которой запись заменяет атомарно, поэтому запись видна `Get` сразу. Новые ключи
+ * до публикации следующей копии (раз в 10 мс) читаются под блокировкой, а публикация копирует набор ключей
+ * сегмента, поэтому при частом добавлении ключей кэш стоит разбить на сегменты (`WithShards`). Несовместима
+ * с опциями, изменяющими кэш при чтении: `WithSlidingExpiration`, `WithMaxIdle`, `WithMaxEntries`, `WithMaxBytes`,
+ * `WithRefreshAhead` и `WithAccessTracking`
+ */
+func WithReadOptimized() Option {
+	return func(o *options) error {
+		o.readOptimized = true
+
+		return nil
+	}
+}
+
+/*
+ * Опция учета чтений значений: время последнего чтения и количество чтений каждого значения доступны
+ * в `Entry`. Учет записывает в значение при каждом чтении, поэтому замедляет `Get` и по умолчанию выключен.
+ * Несовместима с `WithReadOptimized`
+ */
+func WithAccessTracking() Option {
+	return func(o *options) error {
+		o.accessTracking = true
+
+		return nil
+	}
+}
+
+/*
+ * Опция выборочного учета чтений для кэшей с высокой частотой чтений: включает `WithAccessTracking`,
+ * но учитывается в среднем одно из `n` чтений, а количество чтений увеличивается сразу на `n`. Количество
+ * чтений в `Entry`, `TopKeys` и `ColdKeys` становится приближенным, а время последнего чтения - временем
+ * последнего учтенного чтения
+ */
+func WithAccessSampling(n int) Option {
+	return func(o *options) error {
+		if n < 1 {
+			return fmt.Errorf("cache: access sampling must be positive, got %d", n)
+		}
+
+		o.accessTracking = true
+		o.accessSampling = n
+
+		return nil
+	}
+}
+
+/*
+ * Опция статистики за последние минуты (`StatsWindow`). Каждый сегмент ведет кольцевой буфер счетчиков
+ * по интервалам 10 секунд за последний час, а интервалы сменяет отдельная фоновая горутина, поэтому
+ * опция выключена по умолчанию и запускает горутину и при `WithoutBackgroundGC`
+ */
+func WithStatsWindow() Option {
+	return func(o *options) error {
+		o.statsWindow = true
+
+		return nil
+	}
+}
+
+/*
+ * Опция грубых часов для кэшей с высокой частотой операций. Текущее время обновляется фоновой горутиной
+ * раз в `resolution` (например 1-5 мс), а проверки времени жизни при `Get`, `Set` и в сборщике мусора
+ * читают сохраненное значение вместо обращения к системным часам. Время истечения значений при этом
+ * отсчитывается с точностью до `resolution`. Применяется и к часам из `WithClock`
+ */
+func WithCoarseClock(resolution time.Duration) Option {
+	return func(o *options) error {
+		if resolution <= 0 {
+			return fmt.Errorf("cache: coarse clock resolution must be positive, got %v", resolution)
+		}
+
+		o.coarseClock = resolution
+
+		return nil
+	}
+}
+
+/*
+ * Опция буферизованной записи. `Set` и `SetWithTTL` помещают значение в буфер на `size` записей и сразу
+ * возвращаются, а единственная фоновая горутина применяет записи в порядке поступления. Запись становится
+ * видна `Get` не сразу, для ожидания используется `Wait`. При заполненном буфере `Set` ожидает освобождения
+ * места, поэтому записи не теряются. Остальные изменяющие операции (`Delete`, `Update`, `Add` и т.д.)
+ * выполняются в обход буфера после применения уже помещенных в него записей. Функция `WithOnEvicted` для
+ * значений, замененных или вытесненных буферизованной записью, вызывается фоновой горутиной и не должна
+ * изменять кэш, иначе она ожидала бы саму себя. Несовместима с `WithStore`
+ */
+func WithBufferedWrites(size int) Option {
+	return func(o *options) error {
+		if size <= 0 {
+			return fmt.Errorf("cache: write buffer size must be positive, got %d", size)
+		}
+
+		o.writeBuffer = size
+
+		return nil
+	}
+}
+
+/*
+ * Опция механизма удаления просроченных значений. По умолчанию (`Scan`) сборщик мусора с интервалом
+ * `WithCleanupInterval` просматривает все значения. `TimingWheel` планирует истечение каждого значения
+ * в колесе таймеров с шагом, равным интервалу очистки, и сборщик мусора обрабатывает только истекшие
+ * значения. Подходит для кэшей с миллионами значений и разным временем жизни. `Sampling` на каждом
+ * проходе проверяет ограниченное количество случайных значений и повторяет выборку, пока в ней много
+ * истекших, поэтому проход не замедляется с ростом кэша, но часть истекших значений удаляется позже
+ */
+func WithExpirationEngine(engine ExpirationEngine) Option {
+	return func(o *options) error {
+		if engine < Scan || engine > Sampling {
+			return fmt.Errorf("cache: unknown expiration engine %s", engine)
+		}
+
+		o.expiration = engine
+
+		return nil
+	}
+}
+
+/*
+ * Опция функции обратного вызова, которая вызывается при удалении значения из хранилища: сборщиком
+ * мусора, при вытеснении из-за ограничения емкости, при замене новым значением, явным вызовом `Delete`
+ * или очисткой кэша. Причина удаления
+ * передается в функцию. Типы ключа и значения выводятся из переданной функции и должны совпадать
+ * с типами кэша. Функция вызывается без удержания блокировки кэша
+ */
+func WithOnEvicted[K comparable, V any](fn func(key K, value V, reason EvictionReason)) Option {
+	return func(o *options) error {
+		if fn == nil {
+			return fmt.Errorf("cache: on evicted callback must not be nil")
+		}
+
+		o.onEvicted = fn
+
+		return nil
+	}
+}
+
+/*
+ * Опция загрузчика значений при промахе. С загрузчиком кэш работает как read-through: `Get` при
+ * отсутствии значения вызывает загрузчик, записывает результат в кэш и возвращает его, а одновременные
+ * промахи по одному ключу объединяются в одну загрузку. Ошибка загрузчика доступна через `GetContext`
+ */
+func WithLoader[K comparable, V any](loader func(ctx context.Context, key K) (V, error)) Option {
+	return func(o *options) error {
+		if loader == nil {
+			return fmt.Errorf("cache: loader must not be nil")
+		}
+
+		o.loader = loader
+
+		return nil
+	}
+}
+
+/*
+ * Опция хранилища для сквозной записи (write-through). Каждая запись через `Set` сначала сохраняется
+ * в хранилище, а каждое удаление через `Delete` удаляет значение и из хранилища, поэтому кэш остается
+ * согласованным с источником данных. Ошибки хранилища доступны через `SetContext` и `DeleteContext`
+ */
+func WithStore[K comparable, V any](store Store[K, V]) Option {
+	return func(o *options) error {
+		if store == nil {
+			return fmt.Errorf("cache: store must not be nil")
+		}
+
+		o.store = store
+
+		return nil
+	}
+}
+
+/*
+ * Опция режима stale-while-revalidate. Просроченное значение хранится еще `window` после истечения:
+ * в это время `Get` немедленно возвращает устаревшее значение и запускает его обновление загрузчиком
+ * из `WithLoader` в фоне, поэтому истечение TTL не приводит к задержке чтения. Признак устаревания
+ * возвращает `GetStale`. Применяется только вместе с `WithLoader`
+ */
+func WithStaleWhileRevalidate(window time.Duration) Option {
+	return func(o *options) error {
+		if window <= 0 {
+			return fmt.Errorf("cache: stale window must be positive, got %s", window)
+		}
+
+		o.staleWindow = window
+
+		return nil
+	}
+}
+
+/*
+ * Опция заблаговременного обновления (refresh-ahead) часто читаемых значений. Если значение прочитано через
+ * `Get` не менее `minHits` раз с момента записи и до его истечения осталось не больше `window`, загрузчик
+ * из `WithLoader` обновляет значение в фоне, поэтому "горячие" значения не истекают и чтение не промахивается.
+ * Применяется только вместе с `WithLoader`
+ */
+func WithRefreshAhead(window time.Duration, minHits int) Option {
+	return func(o *options) error {
+		if window <= 0 {
+			return fmt.Errorf("cache: refresh-ahead window must be positive, got %s", window)
+		}
+
+		if minHits < 1 {
+			return fmt.Errorf("cache: refresh-ahead min hits must be positive, got %d", minHits)
+		}
+
+		o.refreshWindow = window
+		o.refreshHits = minHits
+
+		return nil
+	}
+}
+
+/*
+ * Опция периодических снимков кэша на диск. Раз в `interval` актуальные значения сохраняются в файл `path`
+ * атомарно: снимок записывается во временный файл в том же каталоге и переименовывается. При создании кэша
+ * значения восстанавливаются из существующего снимка, а при `Close` сохраняется последний снимок
+ */
+func WithSnapshot(path string, interval time.Duration) Option {
+	return func(o *options) error {
+		if path == "" {
+			return fmt.Errorf("cache: snapshot path must not be empty")
+		}
+
+		if interval <= 0 {
+			return fmt.Errorf("cache: snapshot interval must be positive, got %s", interval)
+		}
+
+		o.snapshotPath = path
+		o.snapshotInterval = interval
+
+		return nil
+	}
+}
+
+/*
+ * Опция журнала изменений (append-only log). Каждая запись и удаление значения дописываются в файл `path`,
+ * а при создании кэша журнал воспроизводится, поэтому после сбоя теряются изменения не более чем за секунду.
+ * Журнал периодически сжимается до записей актуальных значений. Обеспечивает более высокую надежность,
+ * чем `WithSnapshot`, ценой записи на диск при каждом изменении
+ */
+func WithPersistenceLog(path string) Option {
+	return func(o *options) error {
+		if path == "" {
+			return fmt.Errorf("cache: persistence log path must not be empty")
+		}
+
+		o.logPath = path
+
+		return nil
+	}
+}
+
+/*
+ * Опция отложенной записи (write-behind) в хранилище из `WithStore`. Изменения не записываются в хранилище
+ * синхронно, а накапливаются в очереди и сбрасываются в фоне раз в `interval` либо досрочно, когда в очереди
+ * накопится `size` ключей. Повторные изменения одного ключа объединяются, и в хранилище попадает только
+ * последнее. Метод `Flush` сбрасывает очередь немедленно, а `Close` сбрасывает оставшиеся изменения
+ */
+func WithWriteBehind(interval time.Duration, size int) Option {
+	return func(o *options) error {
+		if interval <= 0 {
+			return fmt.Errorf("cache: write-behind interval must be positive, got %s", interval)
+		}
+
+		if size < 1 {
+			return fmt.Errorf("cache: write-behind queue size must be positive, got %d", size)
+		}
+
+		o.writeBehind = true
+		o.flushInterval = interval
+		o.flushSize = size
+
+		return nil
+	}
+}
+
+/*
+ * Опция копирования значений при чтении. `Get` и остальные методы чтения возвращают копию значения,
+ * поэтому изменение полученного значения вызывающим кодом не затрагивает кэш и других читателей.
+ * Для `ProfileCache` копируются профиль и его заказы, для обобщенного кэша необходима `WithCloner`
+ */
+func WithCopyOnRead(enabled bool) Option {
+	return func(o *options) error {
+		o.copyOnRead = enabled
+
+		return nil
+	}
+}
+
+/*
+ * Опция копирования значений при записи. `Set` и остальные методы записи сохраняют копию значения,
+ * поэтому последующее изменение переданного значения вызывающим кодом не затрагивает кэш.
+ * Для `ProfileCache` копируются профиль и его заказы, для обобщенного кэша необходима `WithCloner`
+ */
+func WithCopyOnWrite(enabled bool) Option {
+	return func(o *options) error {
+		o.copyOnWrite = enabled
+
+		return nil
+	}
+}
+
+/*
+ * Опция функции глубокого копирования значения для `WithCopyOnRead` и `WithCopyOnWrite`
+ */
+func WithCloner[V any](clone func(V) V) Option {
+	return func(o *options) error {
+		if clone == nil {
+			return fmt.Errorf("cache: cloner must not be nil")
+		}
+
+		o.cloner = clone
+
+		return nil
+	}
+}
+
+/*
+ * Опция телеметрии OpenTelemetry. Чтения, загружающие значение загрузчиком (`WithLoader`), записываются
+ * в span-ы `cache.get` и `cache.load`, а попадания, промахи и длительность загрузки - в метрики `cache.hits`,
+ * `cache.misses` и `cache.load.duration`. Один из провайдеров может быть `nil`, чтобы отключить трассировку
+ * или метрики, но не оба сразу. Провайдеры OpenTelemetry передаются через `cacheotel.WithTelemetry`
+ */
+func WithTelemetry(tp TracerProvider, mp MeterProvider) Option {
+	return func(o *options) error {
+		if tp == nil && mp == nil {
+			return fmt.Errorf("cache: telemetry requires a tracer provider or a meter provider")
+		}
+
+		o.tracerProvider = tp
+		o.meterProvider = mp
+
+		return nil
+	}
+}
+
+/*
+ * Опция журнала событий кэша. На уровне Debug записываются проходы сборщика мусора (количество удаленных
+ * значений и длительность), сохранение и восстановление снимков, на уровне Warn - ошибки загрузчика
+ * и снимков, а также массовое вытеснение значений, при котором за интервал сборщика мусора вытесняется
+ * не меньше значений, чем осталось в кэше
+ */
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) error {
+		if logger == nil {
+			return fmt.Errorf("cache: logger must not be nil")
+		}
+
+		o.logger = logger
+
+		return nil
+	}
+}
+
+/*
+ * Опция шины сообщений об изменении значений между экземплярами сервиса. Запись и удаление значения
+ * на одном экземпляре отправляют сообщение в шину, и остальные экземпляры удаляют свои копии значения.
+ * Шина подключается только к кэшу со строковыми ключами
+ */
+func WithInvalidationBus(bus InvalidationBus) Option {
+	return func(o *options) error {
+		if bus == nil {
+			return fmt.Errorf("cache: invalidation bus must not be nil")
+		}
+
+		o.bus = bus
+
+		return nil
+	}
+}
+
+/*
+ * Опция времени жизни заказов профиля, отдельного от времени жизни самого профиля. Заказы, созданные
+ * раньше `ttl` назад (по `Order.CreatedAt`), не возвращаются при чтении профиля и удаляются из профиля
+ * сборщиком мусора, а профиль остается в кэше. Применяется только к кэшу профилей (`New`)
+ */
+func WithOrderTTL(ttl time.Duration) Option {
+	return func(o *options) error {
+		if ttl <= 0 {
+			return fmt.Errorf("cache: order ttl must be positive, got %v", ttl)
+		}
+
+		o.orderTTL = ttl
+
+		return nil
+	}
+}
+
+/*
+ * Опция максимального количества заказов профиля. При добавлении заказа через `AddOrder` или `SetMerge`
+ * в профиле остаются только `n` самых новых заказов по `Order.CreatedAt`, поэтому один активный пользователь
+ * не может неограниченно увеличивать размер значения. Применяется только к кэшу профилей (`New`)
+ */
+func WithMaxOrdersPerProfile(n int) Option {
+	return func(o *options) error {
+		if n <= 0 {
+			return fmt.Errorf("cache: max orders per profile must be positive, got %d", n)
+		}
+
+		o.maxOrders = n
+
+		return nil
+	}
+}
+
+// Опция функции копирования по умолчанию, не заменяющая переданную через `WithCloner`
+func withDefaultCloner[V any](clone func(V) V) Option {
+	return func(o *options) error {
+		if o.cloner == nil {
+			o.cloner = clone
+		}
+
+		return nil
+	}
+}
+
+/*
+ * Опция вторичного индекса значений. Функция возвращает вторичные ключи значения, по которым
+ * значение можно найти без основного ключа. Используется кэшем профилей для поиска по `UUID` заказа
+ */
+func withIndex[V any](keys func(V) []string) Option {
+	return func(o *options) error {
+		o.indexKeys = keys
+
+		return nil
+	}
+}
+
+/*
+ * Опция функции удаления устаревших частей значения для `WithOrderTTL`. Функция получает значение и время,
+ * созданное раньше которого считается устаревшим. Используется кэшем профилей для удаления старых заказов
+ */
+func withPrune[V any](prune func(V, time.Time) (V, bool)) Option {
+	return func(o *options) error {
+		o.prune = prune
+
+		return nil
+	}
+}
@@ -0,0 +1,393 @@
+package cache
+
+import (
+	"slices"
+	"time"
+)
+
+/*
+ * Функции работы с заказами профиля. Профиль находится по `UUID` пользователя, а изменение списка
+ * заказов выполняется атомарно под блокировкой на запись с продлением времени жизни профиля.
+ *
+ * Профиль и список заказов не изменяются на месте: в кэш записывается копия профиля с новым срезом
+ * заказов. Поэтому профиль, полученный ранее через `Get`, остается согласованным снимком и может
+ * читаться без блокировки одновременно с изменением заказов
+ */
+
+/*
+ * Функция добавления заказа в профиль пользователя. В профиль записывается копия заказа (`copyOrder`),
+ * и если время создания заказа не задано, у копии оно устанавливается равным текущему времени.
+ * При `WithMaxOrdersPerProfile` из профиля удаляются самые старые заказы сверх ограничения, включая
+ * добавляемый, если он старше остальных. Возвращает `false`, если профиль отсутствует или просрочен,
+ * или заказ пуст
+ */
+func (cache *ProfileCache) AddOrder(UUID string, order *Order) bool {
+	if order == nil {
+		return false
+	}
+
+	order = cache.copyOrder(order)
+
+	now := cache.clock.Now()
+
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = now
+	}
+
+	order.UpdatedAt = now
+
+	return cache.update(UUID, func(profile *Profile) (*Profile, bool) {
+		orders := append(slices.Clip(profile.Orders), order)
+
+		if cache.maxOrders > 0 {
+			orders = trimOrders(orders, cache.maxOrders)
+		}
+
+		return withOrders(profile, orders), true
+	})
+}
+
+/*
+ * Функция замены заказа профиля копией заказа с тем же `UUID` (`copyOrder`). Время изменения копии
+ * устанавливается равным текущему времени. Возвращает `false`, если профиль или заказ отсутствует
+ */
+func (cache *ProfileCache) UpdateOrder(UUID string, order *Order) bool {
+	if order == nil {
+		return false
+	}
+
+	order = cache.copyOrder(order)
+	order.UpdatedAt = cache.clock.Now()
+
+	return cache.update(UUID, func(profile *Profile) (*Profile, bool) {
+		i := orderIndex(profile, order.UUID)
+
+		if i < 0 {
+			return profile, false
+		}
+
+		orders := slices.Clone(profile.Orders)
+		orders[i] = order
+
+		return withOrders(profile, orders), true
+	})
+}
+
+/*
+ * Функция удаления заказа из профиля по `UUID` заказа. Возвращает `false`, если профиль или заказ отсутствует
+ */
+func (cache *ProfileCache) DeleteOrder(UUID string, orderUUID string) bool {
+	return cache.update(UUID, func(profile *Profile) (*Profile, bool) {
+		i := orderIndex(profile, orderUUID)
+
+		if i < 0 {
+			return profile, false
+		}
+
+		return withOrders(profile, slices.Delete(slices.Clone(profile.Orders), i, i+1)), true
+	})
+}
+
+/*
+ * Функция поиска профиля и заказа по `UUID` заказа без `UUID` пользователя. Поиск выполняется по
+ * индексу заказов, который обновляется при записи профиля и изменении его заказов через `AddOrder`,
+ * `UpdateOrder` и `DeleteOrder`. Заказы, добавленные в профиль на месте в обход кэша, в индекс не попадают.
+ * Время жизни профиля при поиске не продлевается
+ */
+func (cache *ProfileCache) GetByOrderUUID(orderUUID string) (*Profile, *Order, bool) {
+	key, profile, ok := cache.lookup(orderUUID)
+
+	// Заказ мог быть удален из профиля на месте после записи в кэш
+	i := -1
+
+	if ok {
+		i = orderIndex(profile, orderUUID)
+	}
+
+	cache.recordRead(key, i >= 0)
+
+	if i < 0 {
+		return nil, nil, false
+	}
+
+	profile = cache.copyOut(profile)
+
+	return profile, profile.Orders[i], true
+}
+
+/*
+ * Функция получения заказов профиля, созданных (`Order.CreatedAt`) в промежутке `[from, to)`. Заказы
+ * отбираются под блокировкой на чтение, поэтому копирование всего списка заказов для фильтрации не требуется.
+ * Время жизни профиля не продлевается. Возвращает `false`, если профиль отсутствует или просрочен
+ */
+func (cache *ProfileCache) OrdersBetween(UUID string, from, to time.Time) ([]*Order, bool) {
+	shard := cache.shardFor(UUID)
+
+	shard.mutex.RLock()
+
+	profile, _, ok := shard.peekLocked(UUID)
+
+	var orders []*Order
+
+	if ok {
+		for _, order := range profile.Orders {
+			if order != nil && !order.CreatedAt.Before(from) && order.CreatedAt.Before(to) {
+				orders = append(orders, order)
+			}
+		}
+	}
+
+	shard.mutex.RUnlock()
+
+	cache.recordRead(UUID, ok)
+
+	if !ok {
+		return nil, false
+	}
+
+	// Отобранные заказы копируются функцией копирования профиля (`WithCopyOnRead`)
+	if cache.copyOnRead {
+		orders = cache.clone(&Profile{Orders: orders}).Orders
+	}
+
+	return orders, true
+}
+
+// Порядок сортировки заказов для `OrdersPage`
+type OrderSort int
+
+const (
+	// По времени создания, от старых к новым
+	OrderByCreatedAt OrderSort = iota
+
+	// По времени создания, от новых к старым
+	OrderByCreatedAtDesc
+
+	// По времени изменения, от старых к новым
+	OrderByUpdatedAt
+
+	// По времени изменения, от новых к старым
+	OrderByUpdatedAtDesc
+)
+
+/*
+ * Функция получения страницы заказов профиля: `limit` заказов начиная с позиции `offset` в порядке `sortBy`.
+ * Сортировка устойчивая, поэтому заказы с одинаковым временем сохраняют порядок добавления и не переходят
+ * между страницами. Возвращает страницу и общее количество заказов профиля. Отрицательный `offset` считается
+ * нулевым, а при `limit <= 0` страница пуста. Время жизни профиля не продлевается. Возвращает `false`,
+ * если профиль отсутствует или просрочен
+ */
+func (cache *ProfileCache) OrdersPage(UUID string, offset, limit int, sortBy OrderSort) ([]*Order, int, bool) {
+	shard := cache.shardFor(UUID)
+
+	shard.mutex.RLock()
+
+	profile, _, ok := shard.peekLocked(UUID)
+
+	var orders []*Order
+
+	if ok {
+		orders = slices.Clone(profile.Orders)
+	}
+
+	shard.mutex.RUnlock()
+
+	cache.recordRead(UUID, ok)
+
+	if !ok {
+		return nil, 0, false
+	}
+
+	slices.SortStableFunc(orders, func(a, b *Order) int {
+		return compareOrders(a, b, sortBy)
+	})
+
+	total := len(orders)
+
+	offset = min(max(offset, 0), total)
+	orders = orders[offset:min(offset+max(limit, 0), total)]
+
+	if cache.copyOnRead {
+		orders = cache.clone(&Profile{Orders: orders}).Orders
+	}
+
+	return orders, total, true
+}
+
+// Функция сравнения заказов для сортировки. Пустые заказы располагаются в конце
+func compareOrders(a, b *Order, sortBy OrderSort) int {
+	if a == nil || b == nil {
+		switch {
+		case a == b:
+			return 0
+		case a == nil:
+			return 1
+		default:
+			return -1
+		}
+	}
+
+	switch sortBy {
+	case OrderByCreatedAtDesc:
+		return b.CreatedAt.Compare(a.CreatedAt)
+	case OrderByUpdatedAt:
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	case OrderByUpdatedAtDesc:
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	default:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+}
+
+// Функция получения вторичных ключей профиля для индекса заказов. Пустые заказы пропускаются
+func orderKeys(profile *Profile) []string {
+	if profile == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(profile.Orders))
+
+	for _, order := range profile.Orders {
+		if order != nil {
+			keys = append(keys, order.UUID)
+		}
+	}
+
+	return keys
+}
+
+/*
+ * Функция удаления из профиля заказов, созданных раньше `before` (`WithOrderTTL`). Профиль не изменяется
+ * на месте: при наличии устаревших заказов возвращается копия профиля с новым срезом заказов
+ */
+func pruneOrders(profile *Profile, before time.Time) (*Profile, bool) {
+	stale := func(order *Order) bool {
+		return order != nil && order.CreatedAt.Before(before)
+	}
+
+	if profile == nil || !slices.ContainsFunc(profile.Orders, stale) {
+		return profile, false
+	}
+
+	return withOrders(profile, slices.DeleteFunc(slices.Clone(profile.Orders), stale)), true
+}
+
+/*
+ * Функция слияния заказов профиля в кэше с переданными заказами. Порядок заказов из кэша сохраняется,
+ * заказ с тем же `UUID` заменяется переданным, если тот изменен не раньше, а новые заказы добавляются в конец
+ */
+func mergeOrders(current, incoming []*Order) []*Order {
+	orders := slices.Clone(current)
+
+	positions := make(map[string]int, len(orders))
+
+	for i, order := range orders {
+		if order != nil {
+			positions[order.UUID] = i
+		}
+	}
+
+	for _, order := range incoming {
+		if order == nil {
+			continue
+		}
+
+		i, ok := positions[order.UUID]
+
+		if !ok {
+			positions[order.UUID] = len(orders)
+			orders = append(orders, order)
+
+			continue
+		}
+
+		if !orders[i].UpdatedAt.After(order.UpdatedAt) {
+			orders[i] = order
+		}
+	}
+
+	return orders
+}
+
+/*
+ * Функция удаления самых старых по `Order.CreatedAt` заказов сверх `n`. Порядок оставшихся заказов
+ * сохраняется, а из заказов с одинаковым временем создания первыми удаляются добавленные раньше
+ */
+func trimOrders(orders []*Order, n int) []*Order {
+	if len(orders) <= n {
+		return orders
+	}
+
+	oldest := slices.Clone(orders)
+
+	slices.SortStableFunc(oldest, func(a, b *Order) int {
+		return compareOrders(a, b, OrderByCreatedAt)
+	})
+
+	dropped := make(map[*Order]struct{}, len(orders)-n)
+
+	for _, order := range oldest[:len(orders)-n] {
+		dropped[order] = struct{}{}
+	}
+
+	return slices.DeleteFunc(slices.Clone(orders), func(order *Order) bool {
+		_, ok := dropped[order]
+
+		return ok
+	})
+}
+
+// Функция поиска позиции заказа в профиле по `UUID` заказа. Пустые заказы пропускаются
+func orderIndex(profile *Profile, orderUUID string) int {
+	return slices.IndexFunc(profile.Orders, func(order *Order) bool {
+		return order != nil && order.UUID == orderUUID
+	})
+}
+
+/*
+ * Функция глубокого копирования профиля для `WithCopyOnRead` и `WithCopyOnWrite`. Копируются профиль
+ * и каждый заказ, а значение заказа `Order.Value` копируется поверхностно, поскольку его тип неизвестен
+ */
+func cloneProfile(profile *Profile) *Profile {
+	if profile == nil {
+		return nil
+	}
+
+	clone := *profile
+
+	if profile.Orders != nil {
+		clone.Orders = make([]*Order, len(profile.Orders))
+
+		for i, order := range profile.Orders {
+			if order != nil {
+				orderClone := *order
+				clone.Orders[i] = &orderClone
+			}
+		}
+	}
+
+	return &clone
+}
+
+/*
+ * Функция копирования заказа, записываемого в профиль через `AddOrder` и `UpdateOrder`. Время создания
+ * и изменения устанавливается у копии, поэтому заказ вызывающего кода не изменяется. При `WithCopyOnWrite`
+ * заказ копируется функцией копирования профиля, иначе копируется только структура заказа
+ */
+func (cache *ProfileCache) copyOrder(order *Order) *Order {
+	if cache.copyOnWrite {
+		return cache.clone(&Profile{Orders: []*Order{order}}).Orders[0]
+	}
+
+	clone := *order
+
+	return &clone
+}
+
+// Функция создания копии профиля с новым списком заказов
+func withOrders(profile *Profile, orders []*Order) *Profile {
+	clone := *profile
+	clone.Orders = orders
+
+	return &clone
+}
@@ -0,0 +1,183 @@
+package cache_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachetest"
+)
+
+func TestOrderChangesRenewTTL(t *testing.T) {
+	clock := cachetest.NewFakeClock(time.Now())
+
+	profiles := newProfiles(t, cache.WithClock(clock), cache.WithTTL(time.Minute), cache.WithoutBackgroundGC())
+
+	profiles.Set(&cache.Profile{UUID: "user-1"})
+
+	for _, change := range []func() bool{
+		func() bool { return profiles.AddOrder("user-1", &cache.Order{UUID: "order-1", Value: 10}) },
+		func() bool { return profiles.UpdateOrder("user-1", &cache.Order{UUID: "order-1", Value: 20}) },
+		func() bool { return profiles.DeleteOrder("user-1", "order-1") },
+	} {
+		clock.Advance(50 * time.Second)
+
+		if !change() {
+			t.Fatal("expected order change to succeed")
+		}
+
+		if ttl, ok := profiles.TTL("user-1"); !ok || ttl != time.Minute {
+			t.Fatalf("expected ttl to be renewed to a minute, got %s", ttl)
+		}
+	}
+
+	clock.Advance(2 * time.Minute)
+
+	if profiles.AddOrder("user-1", &cache.Order{UUID: "order-2"}) {
+		t.Fatal("expected order change of an expired profile to fail")
+	}
+}
+
+func TestConcurrentOrderChanges(t *testing.T) {
+	profiles := newProfiles(t, cache.WithTTL(time.Hour))
+
+	profiles.Set(&cache.Profile{UUID: "user-1"})
+
+	const workers, orders = 8, 50
+
+	var wg sync.WaitGroup
+
+	for worker := range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range orders {
+				uuid := fmt.Sprintf("order-%d-%d", worker, i)
+
+				profiles.AddOrder("user-1", &cache.Order{UUID: uuid, Value: i})
+				profiles.UpdateOrder("user-1", &cache.Order{UUID: uuid, Value: -i})
+				profiles.Get("user-1")
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	profile, _ := profiles.Get("user-1")
+
+	if len(profile.Orders) != workers*orders {
+		t.Fatalf("expected %d orders, got %d", workers*orders, len(profile.Orders))
+	}
+}
+
+func TestOrderOperations(t *testing.T) {
+	clock := cachetest.NewFakeClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	profiles := newProfiles(t, cache.WithClock(clock), cache.WithoutBackgroundGC())
+
+	if profiles.AddOrder("user-1", &cache.Order{UUID: "order-1"}) {
+		t.Fatal("expected order of a missing profile to be rejected")
+	}
+
+	profiles.Set(&cache.Profile{UUID: "user-1"})
+
+	if !profiles.AddOrder("user-1", &cache.Order{UUID: "order-1", Value: "book"}) {
+		t.Fatal("expected order to be added")
+	}
+
+	profile, order, ok := profiles.GetByOrderUUID("order-1")
+
+	if !ok || profile.UUID != "user-1" || order.Value != "book" {
+		t.Fatalf("expected order in profile index, got %v, %v, %v", profile, order, ok)
+	}
+
+	if !order.CreatedAt.Equal(clock.Now()) || !order.UpdatedAt.Equal(clock.Now()) {
+		t.Fatalf("expected order timestamps to be set, got %v", order)
+	}
+
+	clock.Advance(time.Second)
+
+	if !profiles.UpdateOrder("user-1", &cache.Order{UUID: "order-1", Value: "pen"}) {
+		t.Fatal("expected order to be updated")
+	}
+
+	if profiles.UpdateOrder("user-1", &cache.Order{UUID: "order-2"}) {
+		t.Fatal("expected update of a missing order to fail")
+	}
+
+	_, order, _ = profiles.GetByOrderUUID("order-1")
+
+	if order.Value != "pen" || !order.UpdatedAt.Equal(clock.Now()) {
+		t.Fatalf("expected updated order, got %v", order)
+	}
+
+	if !profiles.DeleteOrder("user-1", "order-1") || profiles.DeleteOrder("user-1", "order-1") {
+		t.Fatal("expected order to be deleted once")
+	}
+
+	if _, _, ok := profiles.GetByOrderUUID("order-1"); ok {
+		t.Fatal("expected deleted order to leave the index")
+	}
+}
+
+func TestNilOrdersDoNotBreakProfile(t *testing.T) {
+	profiles := newProfiles(t, cache.WithoutBackgroundGC())
+
+	profiles.Set(&cache.Profile{UUID: "user-1", Orders: []*cache.Order{nil, {UUID: "order-1"}}})
+
+	if profiles.AddOrder("user-1", nil) || profiles.UpdateOrder("user-1", nil) {
+		t.Fatal("expected nil order to be rejected")
+	}
+
+	if _, _, ok := profiles.GetByOrderUUID("order-1"); !ok {
+		t.Fatal("expected order next to a nil order to be indexed")
+	}
+
+	if !profiles.DeleteOrder("user-1", "order-1") || !profiles.Delete("user-1") {
+		t.Fatal("expected profile with a nil order to stay usable")
+	}
+}
+
+func TestOrderIsCopiedOnAdd(t *testing.T) {
+	profiles := newProfiles(t, cache.WithoutBackgroundGC())
+
+	profiles.Set(&cache.Profile{UUID: "user-1"})
+
+	order := &cache.Order{UUID: "order-1", Value: "book"}
+
+	profiles.AddOrder("user-1", order)
+
+	if !order.CreatedAt.IsZero() || !order.UpdatedAt.IsZero() {
+		t.Fatalf("expected caller's order to stay unchanged, got %v", order)
+	}
+
+	order.Value = "changed"
+
+	if _, stored, _ := profiles.GetByOrderUUID("order-1"); stored.Value != "book" {
+		t.Fatalf("expected stored order to be a copy, got %v", stored.Value)
+	}
+}
+
+func TestMaxOrdersPerProfile(t *testing.T) {
+	clock := cachetest.NewFakeClock(time.Now())
+
+	profiles := newProfiles(t, cache.WithClock(clock), cache.WithMaxOrdersPerProfile(2), cache.WithoutBackgroundGC())
+
+	profiles.Set(&cache.Profile{UUID: "user-1"})
+
+	for _, uuid := range []string{"order-1", "order-2", "order-3"} {
+		clock.Advance(time.Second)
+
+		profiles.AddOrder("user-1", &cache.Order{UUID: uuid})
+	}
+
+	profile, _ := profiles.Get("user-1")
+
+	if len(profile.Orders) != 2 || profile.Orders[0].UUID != "order-2" || profile.Orders[1].UUID != "order-3" {
+		t.Fatalf("expected the two newest orders, got %v", profile.Orders)
+	}
+}
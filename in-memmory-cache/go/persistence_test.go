@@ -0,0 +1,245 @@
+package cache_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachetest"
+)
+
+// Значение заказа, хранящееся в поле-интерфейсе `Order.Value`, поэтому для gob оно регистрируется
+type orderValue struct {
+	Sum float64
+}
+
+func init() {
+	gob.Register(orderValue{})
+}
+
+func TestSaveToLoadFrom(t *testing.T) {
+	clock := cachetest.NewFakeClock(time.Now())
+
+	source := newProfiles(t, cache.WithClock(clock), cache.WithTTL(time.Hour), cache.WithoutBackgroundGC())
+
+	source.Set(&cache.Profile{UUID: "user-1", Orders: []*cache.Order{{UUID: "order-1", Value: orderValue{Sum: 10.5}}}})
+	source.SetWithTTL(&cache.Profile{UUID: "user-2"}, time.Minute)
+
+	var snapshot bytes.Buffer
+
+	if err := source.SaveTo(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	// Профиль, истекший за время простоя, при восстановлении пропускается
+	clock.Advance(2 * time.Minute)
+
+	restored := newProfiles(t, cache.WithClock(clock), cache.WithTTL(time.Hour), cache.WithoutBackgroundGC())
+
+	if err := restored.LoadFrom(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.Len() != 1 {
+		t.Fatalf("expected one restored profile, got %v", restored.Keys())
+	}
+
+	_, order, ok := restored.GetByOrderUUID("order-1")
+
+	if !ok || order.Value != (orderValue{Sum: 10.5}) {
+		t.Fatalf("expected restored and indexed order, got %v, %v", order, ok)
+	}
+
+	// Время истечения сохраняется, а не отсчитывается заново
+	if ttl, _ := restored.TTL("user-1"); ttl != time.Hour-2*time.Minute {
+		t.Fatalf("expected remaining ttl to be preserved, got %s", ttl)
+	}
+}
+
+func TestSnapshotOnClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.snapshot")
+
+	profiles, err := cache.New(cache.WithSnapshot(path, time.Hour), cache.WithoutBackgroundGC())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profiles.Set(&cache.Profile{UUID: "user-1", Name: "Alice"})
+
+	profiles.Close()
+
+	reopened := newProfiles(t, cache.WithSnapshot(path, time.Hour), cache.WithoutBackgroundGC())
+
+	if profile, ok := reopened.Get("user-1"); !ok || profile.Name != "Alice" {
+		t.Fatalf("expected profile restored from snapshot, got %v", profile)
+	}
+}
+
+func TestPersistenceLogReplaysChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.log")
+
+	profiles, err := cache.New(cache.WithPersistenceLog(path), cache.WithoutBackgroundGC())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profiles.Set(&cache.Profile{UUID: "user-1", Name: "Alice"})
+	profiles.Set(&cache.Profile{UUID: "user-2", Name: "Bob"})
+	profiles.AddOrder("user-2", &cache.Order{UUID: "order-1", Value: orderValue{Sum: 3}})
+	profiles.Delete("user-1")
+
+	profiles.Close()
+
+	reopened := newProfiles(t, cache.WithPersistenceLog(path), cache.WithoutBackgroundGC())
+
+	if _, ok := reopened.Get("user-1"); ok {
+		t.Fatal("expected deleted profile to stay deleted after replay")
+	}
+
+	profile, ok := reopened.Get("user-2")
+
+	if !ok || profile.Name != "Bob" || len(profile.Orders) != 1 || profile.Orders[0].Value != (orderValue{Sum: 3}) {
+		t.Fatalf("expected replayed profile with its order, got %v", profile)
+	}
+}
+
+func TestPersistenceLogReplaysExpiryChanges(t *testing.T) {
+	for _, compact := range []bool{false, true} {
+		t.Run(map[bool]string{false: "Log", true: "Compacted"}[compact], func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "values.log")
+			clock := cachetest.NewFakeClock(time.Now())
+
+			opts := []cache.Option{cache.WithClock(clock), cache.WithTTL(time.Minute), cache.WithPersistenceLog(path)}
+
+			values := newValues(t, opts...)
+
+			values.Set("touched", 1)
+			values.Set("expired", 2)
+			values.Set("shifted", 3)
+			values.SetWithTTL("custom", 4, 5*time.Minute)
+
+			clock.Advance(30 * time.Second)
+
+			values.Touch("touched")
+			values.Expire("expired", 10*time.Minute)
+
+			// Значения с прежним TTL по умолчанию сдвигаются на разницу: `shifted` истекает через 90 секунд
+			if err := values.SetDefaultTTL(2*time.Minute, true); err != nil {
+				t.Fatal(err)
+			}
+
+			values.Set("later", 5)
+
+			if compact {
+				if err := values.CompactLog(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			values.Close()
+
+			reopened := newValues(t, opts...)
+
+			expected := map[string]time.Duration{
+				"touched": 2 * time.Minute,
+				"expired": 10 * time.Minute,
+				"shifted": 90 * time.Second,
+				"custom":  270 * time.Second,
+				"later":   2 * time.Minute,
+			}
+
+			for key, ttl := range expected {
+				if remaining, ok := reopened.TTL(key); !ok || remaining != ttl {
+					t.Fatalf("expected %q to expire in %s after replay, got %s, %v", key, ttl, remaining, ok)
+				}
+			}
+
+			if ttl := reopened.DefaultTTL(); ttl != 2*time.Minute {
+				t.Fatalf("expected the changed default ttl after replay, got %s", ttl)
+			}
+		})
+	}
+}
+
+func TestPersistenceLogKeepsDefaultTTLMarks(t *testing.T) {
+	for _, compact := range []bool{false, true} {
+		t.Run(map[bool]string{false: "Log", true: "Compacted"}[compact], func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "values.log")
+			clock := cachetest.NewFakeClock(time.Now())
+
+			opts := []cache.Option{cache.WithClock(clock), cache.WithTTL(time.Minute), cache.WithPersistenceLog(path)}
+
+			values := newValues(t, opts...)
+
+			values.Set("default", 1)
+			values.SetWithTTL("explicit", 2, time.Minute)
+
+			if compact {
+				if err := values.CompactLog(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			values.Close()
+
+			// После воспроизведения `SetDefaultTTL` по-прежнему различает значения с временем жизни по умолчанию
+			reopened := newValues(t, opts...)
+
+			if err := reopened.SetDefaultTTL(2*time.Minute, true); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := map[string]time.Duration{"default": 2 * time.Minute, "explicit": time.Minute}
+
+			for key, ttl := range expected {
+				if remaining, ok := reopened.TTL(key); !ok || remaining != ttl {
+					t.Fatalf("expected %q to expire in %s, got %s, %v", key, ttl, remaining, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestPersistenceLogTreatsOversizedRecordAsTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "values.log")
+
+	values := newValues(t, cache.WithPersistenceLog(path))
+
+	values.Set("a", 1)
+	values.Close()
+
+	valid, err := os.Stat(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Заголовок с длиной почти 4 GiB после целой записи, как при повреждении файла
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := file.Write([]byte{0xf0, 0xff, 0xff, 0xff, 0, 0, 0, 0, 1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	file.Close()
+
+	reopened := newValues(t, cache.WithPersistenceLog(path))
+
+	if value, ok := reopened.Peek("a"); !ok || value != 1 {
+		t.Fatalf("expected the records before the torn tail to replay, got %d, %v", value, ok)
+	}
+
+	if truncated, err := os.Stat(path); err != nil || truncated.Size() != valid.Size() {
+		t.Fatalf("expected the log to be truncated to %d bytes, got %v, %v", valid.Size(), truncated, err)
+	}
+}
@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Операция буфера записи: запись значения либо отметка, о которой сообщается закрытием `done`
+type writeOp[K comparable, V any] struct {
+	key   K
+	value V
+	ttl   time.Duration
+	done  chan struct{}
+}
+
+/*
+ * Буфер записи (`WithBufferedWrites`). `Set` помещает значение в кольцевой буфер и сразу возвращается,
+ * а единственная фоновая горутина применяет записи к сегментам в порядке поступления. Записывающие
+ * потоки не конкурируют за блокировки сегментов, поэтому задержка `Set` не растет при высокой конкуренции
+ */
+type writePipeline[K comparable, V any] struct {
+	ops chan writeOp[K, V]
+
+	// Количество записей, помещенных в буфер, но еще не примененных
+	pending atomic.Int64
+}
+
+// Функция помещения записи в буфер. При заполненном буфере ожидает освобождения места
+func (cache *Cache[K, V]) enqueue(key K, value V, ttl time.Duration) error {
+	cache.pipeline.pending.Add(1)
+
+	select {
+	case cache.pipeline.ops <- writeOp[K, V]{key: key, value: value, ttl: ttl}:
+		return nil
+	case <-cache.stop:
+		cache.pipeline.pending.Add(-1)
+
+		return ErrClosed
+	}
+}
+
+// Функция применения записей из буфера. Завершается после закрытия кэша
+func (cache *Cache[K, V]) writer() {
+	for {
+		select {
+		case op := <-cache.pipeline.ops:
+			if op.done != nil {
+				close(op.done)
+
+				continue
+			}
+
+			_ = cache.set(op.key, op.value, op.ttl)
+
+			cache.pipeline.pending.Add(-1)
+		case <-cache.stop:
+			return
+		}
+	}
+}
+
+/*
+ * Функция ожидания применения всех записей, помещенных в буфер (`WithBufferedWrites`) до вызова. После
+ * возврата `Get` видит эти записи, поэтому функция предназначена прежде всего для тестов. Без буфера
+ * записи ничего не делает
+ */
+func (cache *Cache[K, V]) Wait() {
+	if cache.pipeline == nil {
+		return
+	}
+
+	done := make(chan struct{})
+
+	select {
+	case cache.pipeline.ops <- writeOp[K, V]{done: done}:
+	case <-cache.stop:
+		return
+	}
+
+	select {
+	case <-done:
+	case <-cache.stop:
+	}
+}
+
+/*
+ * Функция ожидания записей из буфера перед изменением кэша в обход буфера (`Delete`, `Update` и т.д.).
+ * Иначе запись, помещенная в буфер раньше удаления, была бы применена после него и восстановила значение
+ */
+func (cache *Cache[K, V]) awaitWrites() {
+	if cache.pipeline != nil && cache.pipeline.pending.Load() > 0 {
+		cache.Wait()
+	}
+}
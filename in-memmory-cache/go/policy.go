@@ -0,0 +1,123 @@
+package cache
+
+import "fmt"
+
+/*
+ * Политика вытеснения значений из заполненного кэш-хранилища. Кэш уведомляет политику о каждом
+ * чтении, записи и удалении значения, а при нехватке места запрашивает у нее ключ-кандидат на вытеснение.
+ *
+ * Все методы вызываются под блокировкой кэш-хранилища на запись, поэтому реализации
+ * не обязаны быть потокобезопасными
+ */
+type EvictionPolicy[K comparable] interface {
+	// Значение по ключу было прочитано
+	OnGet(key K)
+
+	// Значение по ключу было записано или обновлено
+	OnSet(key K)
+
+	// Значение по ключу было удалено из хранилища
+	OnDelete(key K)
+
+	// Ключ значения, которое следует вытеснить. Возвращает `false`, если политика не отслеживает ни одного ключа.
+	// Может вызываться повторно до удаления кандидата, а также без его удаления, чтобы узнать, есть ли кандидат
+	Victim() (K, bool)
+}
+
+// Встроенная политика вытеснения, выбираемая опцией `WithPolicy`
+type Policy int
+
+const (
+	// Вытеснение давно не использованных значений (Least Recently Used)
+	LRU Policy = iota
+
+	// Вытеснение редко используемых значений (Least Frequently Used)
+	LFU
+
+	// Вытеснение значений в порядке их добавления (First In, First Out)
+	FIFO
+
+	// Адаптивное замещение с балансом между давностью и частотой обращений (Adaptive Replacement Cache)
+	ARC
+
+	// Приближение LRU по алгоритму "второй шанс" с битом обращения вместо перемещения в списке. Имя `Clock`
+	// занято интерфейсом источника времени, поэтому константа записывается так же, как названия остальных политик
+	CLOCK
+
+	// Сегментированный LRU: новые значения вытесняются раньше значений, к которым обращались повторно
+	SLRU
+
+	// Очереди 2Q: в основную очередь попадают только значения, записанные повторно вскоре после вытеснения
+	TwoQueue
+
+	// Вытеснение случайного значения без учета обращений
+	Random
+)
+
+func (policy Policy) String() string {
+	switch policy {
+	case LRU:
+		return "LRU"
+	case LFU:
+		return "LFU"
+	case FIFO:
+		return "FIFO"
+	case ARC:
+		return "ARC"
+	case CLOCK:
+		return "CLOCK"
+	case SLRU:
+		return "SLRU"
+	case TwoQueue:
+		return "2Q"
+	case Random:
+		return "Random"
+	default:
+		return fmt.Sprintf("Policy(%d)", int(policy))
+	}
+}
+
+// Функция создания экземпляра встроенной политики вытеснения для ключей типа `K`
+func newPolicy[K comparable](policy Policy) (EvictionPolicy[K], error) {
+	switch policy {
+	case LRU:
+		return newLRUPolicy[K](), nil
+	case LFU:
+		return newLFUPolicy[K](), nil
+	case FIFO:
+		return newFIFOPolicy[K](), nil
+	case ARC:
+		return newARCPolicy[K](), nil
+	case CLOCK:
+		return newClockPolicy[K](), nil
+	case SLRU:
+		return newSLRUPolicy[K](), nil
+	case TwoQueue:
+		return newTwoQueuePolicy[K](), nil
+	case Random:
+		return newRandomPolicy[K](), nil
+	default:
+		return nil, fmt.Errorf("cache: unknown eviction policy %s", policy)
+	}
+}
+
+/*
+ * Политика вытеснения, выбор кандидата которой изменяет ее состояние (например ARC запоминает кандидата,
+ * чтобы перевести его в список призраков при удалении). Такие политики выбирают того же кандидата
+ * без изменения состояния, когда кэшу нужно только узнать, есть ли кандидат или какой он
+ */
+type victimPeeker[K comparable] interface {
+	peekVictim() (K, bool)
+}
+
+/*
+ * Функция получения кандидата на вытеснение без его выбора. Для пользовательской политики, не реализующей
+ * `victimPeeker`, вызывается `Victim`, поэтому ее `Victim` не должен изменять состояние политики
+ */
+func peekVictim[K comparable](policy EvictionPolicy[K]) (K, bool) {
+	if peeker, ok := policy.(victimPeeker[K]); ok {
+		return peeker.peekVictim()
+	}
+
+	return policy.Victim()
+}
@@ -0,0 +1,291 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+)
+
+// Приоритет значения при вытеснении (`WithPriority`). Значения с меньшим приоритетом вытесняются первыми
+type Priority int8
+
+const (
+	// Значения, которые вытесняются раньше остальных, например результаты фоновых выгрузок
+	Low Priority = iota - 1
+
+	// Приоритет по умолчанию
+	Normal
+
+	// Значения, которые вытесняются только после всех значений с меньшим приоритетом
+	High
+)
+
+// Количество уровней приоритета
+const priorityLevels = int(High-Low) + 1
+
+// Количество кандидатов политики, среди которых вытесняется значение с наибольшей стоимостью (`WithCost`)
+const costCandidates = 5
+
+func (priority Priority) String() string {
+	switch priority {
+	case Low:
+		return "Low"
+	case Normal:
+		return "Normal"
+	case High:
+		return "High"
+	default:
+		return fmt.Sprintf("Priority(%d)", int(priority))
+	}
+}
+
+// Параметры записи значения `SetWithOptions`
+type setOptions struct {
+	priority Priority
+	cost     int64
+}
+
+// Параметр записи значения `SetWithOptions`
+type SetOption func(*setOptions)
+
+/*
+ * Параметр приоритета значения при вытеснении. При ограничении емкости сначала вытесняются значения
+ * с приоритетом `Low`, затем `Normal` и только затем `High`. Внутри приоритета порядок определяет политика
+ */
+func WithPriority(priority Priority) SetOption {
+	return func(o *setOptions) {
+		o.priority = priority
+	}
+}
+
+/*
+ * Параметр стоимости хранения значения, например его размера или объема занимаемой памяти. Среди
+ * нескольких первых кандидатов политики с наименьшим приоритетом вытесняется самое дорогое значение
+ */
+func WithCost(cost int64) SetOption {
+	return func(o *setOptions) {
+		o.cost = cost
+	}
+}
+
+/*
+ * Функция записи значения с параметрами вытеснения (`WithPriority`, `WithCost`) и временем жизни кэша.
+ * Параметры действуют только при ограничении емкости (`WithMaxEntries`, `WithMaxBytes`) и сбрасываются
+ * при перезаписи значения без них. Запись выполняется в обход буфера записи (`WithBufferedWrites`).
+ * Возвращает ошибку при неизвестном приоритете, отрицательной стоимости или ошибке хранилища (`WithStore`)
+ */
+func (cache *Cache[K, V]) SetWithOptions(key K, value V, opts ...SetOption) error {
+	o := setOptions{priority: Normal}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.priority < Low || o.priority > High {
+		return fmt.Errorf("cache: unknown priority %s", o.priority)
+	}
+
+	if o.cost < 0 {
+		return fmt.Errorf("cache: cost must not be negative, got %d", o.cost)
+	}
+
+	stored := cache.copyIn(value)
+
+	cache.awaitWrites()
+
+	return cache.write(context.Background(), key, value, func(shard *shard[K, V]) []evictedItem[K, V] {
+		evicted := shard.set(key, stored, useDefaultTTL)
+
+		item, ok := shard.data[key]
+
+		if !ok || shard.policy == nil || (o.priority == Normal && o.cost == 0) {
+			return evicted
+		}
+
+		item.priority = o.priority
+		item.cost = o.cost
+
+		if !item.pinned {
+			shard.weighted().prioritize(key, o.priority)
+		}
+
+		return evicted
+	})
+}
+
+/*
+ * Функция получения политики вытеснения сегмента с учетом приоритетов. При первой записи значения
+ * с приоритетом или стоимостью политика сегмента становится политикой приоритета `Normal`, поэтому
+ * кэш без `SetWithOptions` не тратит память на учет приоритетов. Вызывается под блокировкой на запись
+ */
+func (shard *shard[K, V]) weighted() *priorityPolicy[K] {
+	if policy, ok := shard.policy.(*priorityPolicy[K]); ok {
+		return policy
+	}
+
+	policy := &priorityPolicy[K]{priorities: make(map[K]Priority)}
+
+	for level := range policy.classes {
+		policy.classes[level] = shard.newPolicy()
+	}
+
+	policy.classes[Normal-Low] = shard.policy
+	shard.policy = policy
+
+	return policy
+}
+
+// Функция учета записанного значения политикой вытеснения с его приоритетом. Вызывается под блокировкой на запись
+func (shard *shard[K, V]) track(key K, item *CacheItem[V]) {
+	if policy, ok := shard.policy.(*priorityPolicy[K]); ok {
+		policy.prioritize(key, item.priority)
+
+		return
+	}
+
+	shard.policy.OnSet(key)
+}
+
+/*
+ * Функция выбора ключа для вытеснения. С учетом приоритетов из нескольких первых кандидатов политики
+ * выбирается значение с наибольшей стоимостью (`WithCost`), при равной стоимости - первое по порядку
+ * политики. Вызывается под блокировкой на запись
+ */
+func (shard *shard[K, V]) victim() (K, bool) {
+	policy, ok := shard.policy.(*priorityPolicy[K])
+
+	if !ok {
+		return shard.policy.Victim()
+	}
+
+	candidates := policy.victims(costCandidates)
+
+	if len(candidates) == 0 {
+		var zero K
+
+		return zero, false
+	}
+
+	victim, cost := candidates[0], int64(-1)
+
+	for _, key := range candidates {
+		item, ok := shard.data[key]
+
+		// Ключ, которого нет в хранилище, выбираем сразу, чтобы снять его с учета политики
+		if !ok {
+			return key, true
+		}
+
+		if item.cost > cost {
+			victim, cost = key, item.cost
+		}
+	}
+
+	return victim, true
+}
+
+/*
+ * Политика вытеснения с приоритетами: для каждого приоритета ведется собственный экземпляр встроенной
+ * или пользовательской политики, а кандидат на вытеснение выбирается из политики наименьшего приоритета,
+ * в которой есть ключи
+ */
+type priorityPolicy[K comparable] struct {
+	classes [priorityLevels]EvictionPolicy[K]
+
+	// Приоритеты ключей, отличные от `Normal`
+	priorities map[K]Priority
+}
+
+func (policy *priorityPolicy[K]) class(key K) EvictionPolicy[K] {
+	priority, ok := policy.priorities[key]
+
+	if !ok {
+		priority = Normal
+	}
+
+	return policy.classes[priority-Low]
+}
+
+func (policy *priorityPolicy[K]) OnGet(key K) {
+	policy.class(key).OnGet(key)
+}
+
+// Значение, записанное без параметров, получает приоритет `Normal`
+func (policy *priorityPolicy[K]) OnSet(key K) {
+	policy.prioritize(key, Normal)
+}
+
+func (policy *priorityPolicy[K]) OnDelete(key K) {
+	policy.class(key).OnDelete(key)
+
+	delete(policy.priorities, key)
+}
+
+func (policy *priorityPolicy[K]) Victim() (K, bool) {
+	for _, class := range policy.classes {
+		if key, ok := class.Victim(); ok {
+			return key, true
+		}
+	}
+
+	var zero K
+
+	return zero, false
+}
+
+func (policy *priorityPolicy[K]) peekVictim() (K, bool) {
+	for _, class := range policy.classes {
+		if key, ok := peekVictim(class); ok {
+			return key, true
+		}
+	}
+
+	var zero K
+
+	return zero, false
+}
+
+// Функция учета записи ключа с приоритетом. При смене приоритета ключ переносится в политику нового приоритета
+func (policy *priorityPolicy[K]) prioritize(key K, priority Priority) {
+	current := policy.class(key)
+	class := policy.classes[priority-Low]
+
+	if current != class {
+		current.OnDelete(key)
+	}
+
+	if priority == Normal {
+		delete(policy.priorities, key)
+	} else {
+		policy.priorities[key] = priority
+	}
+
+	class.OnSet(key)
+}
+
+/*
+ * Функция получения до `n` первых кандидатов на вытеснение из политики наименьшего приоритета, в которой
+ * есть ключи. Встроенные политики перечисляют кандидатов по порядку (`victimLister`), а для пользовательских
+ * возвращается единственный кандидат
+ */
+func (policy *priorityPolicy[K]) victims(n int) []K {
+	for _, class := range policy.classes {
+		if lister, ok := class.(victimLister[K]); ok {
+			if keys := lister.victims(n); len(keys) > 0 {
+				return keys
+			}
+
+			continue
+		}
+
+		if key, ok := class.Victim(); ok {
+			return []K{key}
+		}
+	}
+
+	return nil
+}
+
+// Политика вытеснения, которая перечисляет несколько первых кандидатов на вытеснение по порядку
+type victimLister[K comparable] interface {
+	victims(n int) []K
+}
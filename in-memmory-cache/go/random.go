@@ -0,0 +1,78 @@
+package cache
+
+import "math/rand/v2"
+
+/*
+ * Политика вытеснения случайного значения. Ключи хранятся в срезе, а удаление переносит последний ключ
+ * на место удаленного, поэтому чтение не требует учета, а запись и удаление выполняются за `O(1)`.
+ * Кандидат запоминается до его удаления, поэтому повторный вызов `Victim` возвращает тот же ключ
+ */
+type randomPolicy[K comparable] struct {
+	keys    []K
+	indexes map[K]int
+
+	victim  K
+	pending bool
+}
+
+func newRandomPolicy[K comparable]() *randomPolicy[K] {
+	return &randomPolicy[K]{indexes: make(map[K]int)}
+}
+
+func (policy *randomPolicy[K]) OnGet(key K) {}
+
+func (policy *randomPolicy[K]) OnSet(key K) {
+	if _, ok := policy.indexes[key]; ok {
+		return
+	}
+
+	policy.indexes[key] = len(policy.keys)
+	policy.keys = append(policy.keys, key)
+}
+
+func (policy *randomPolicy[K]) OnDelete(key K) {
+	index, ok := policy.indexes[key]
+
+	if !ok {
+		return
+	}
+
+	last := len(policy.keys) - 1
+
+	policy.keys[index] = policy.keys[last]
+	policy.indexes[policy.keys[index]] = index
+
+	var zero K
+
+	policy.keys[last] = zero
+	policy.keys = policy.keys[:last]
+
+	delete(policy.indexes, key)
+
+	if policy.pending && policy.victim == key {
+		policy.pending = false
+	}
+}
+
+func (policy *randomPolicy[K]) Victim() (K, bool) {
+	if len(policy.keys) == 0 {
+		var zero K
+
+		return zero, false
+	}
+
+	if !policy.pending {
+		policy.victim, policy.pending = policy.keys[rand.IntN(len(policy.keys))], true
+	}
+
+	return policy.victim, true
+}
+
+// Функция выбора кандидата без его запоминания: до вызова `Victim` кандидат каждый раз выбирается заново
+func (policy *randomPolicy[K]) peekVictim() (K, bool) {
+	if policy.pending || len(policy.keys) == 0 {
+		return policy.victim, policy.pending
+	}
+
+	return policy.keys[rand.IntN(len(policy.keys))], true
+}
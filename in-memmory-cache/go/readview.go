@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Интервал публикации копий сегментов для чтения без блокировки (`WithReadOptimized`)
+const readViewInterval = 10 * time.Millisecond
+
+// Значение в копии сегмента для чтения. Копируется из `CacheItem`, который изменяется на месте
+type viewItem[V any] struct {
+	value    V
+	expireAt time.Time
+}
+
+// Ячейка ключа в копии сегмента. Пустая ячейка означает, что значение удалено после публикации копии
+type viewEntry[V any] struct {
+	item atomic.Pointer[viewItem[V]]
+}
+
+/*
+ * Копия словаря сегмента для чтения без блокировки (`WithReadOptimized`), устроенная как `sync.Map`.
+ * Набор ключей копии неизменяем, а значение каждого ключа хранится в отдельной ячейке, которую запись
+ * заменяет атомарно под блокировкой сегмента. Поэтому перезапись, изменение времени жизни и удаление
+ * опубликованного ключа видны читателям сразу. Ключи, записанные после публикации, ищутся в самом
+ * сегменте под блокировкой на чтение, пока фоновая горутина не опубликует новую копию с ними (RCU).
+ * Прежняя копия освобождается сборщиком мусора Go, когда ее перестанут читать
+ */
+type readView[K comparable, V any] struct {
+	entries atomic.Pointer[map[K]*viewEntry[V]]
+
+	// Признак ключей, которых нет в копии: при промахе копии читатель обращается к сегменту
+	amended atomic.Bool
+
+	// Признак изменения набора ключей сегмента после публикации копии
+	dirty atomic.Bool
+}
+
+/*
+ * Функция публикации значения ключа в копии сегмента. Вызывается под блокировкой сегмента на запись
+ * после изменения значения или времени его истечения
+ */
+func (shard *shard[K, V]) viewStore(key K, item *CacheItem[V]) {
+	if shard.view == nil {
+		return
+	}
+
+	if entry, ok := (*shard.view.entries.Load())[key]; ok {
+		entry.item.Store(&viewItem[V]{value: item.value, expireAt: item.expireAt})
+
+		return
+	}
+
+	shard.view.amended.Store(true)
+	shard.view.dirty.Store(true)
+}
+
+// Функция удаления ключа из копии сегмента. Вызывается под блокировкой сегмента на запись
+func (shard *shard[K, V]) viewDelete(key K) {
+	if shard.view == nil {
+		return
+	}
+
+	if entry, ok := (*shard.view.entries.Load())[key]; ok {
+		entry.item.Store(nil)
+		shard.view.dirty.Store(true)
+	}
+}
+
+// Функция публикации пустой копии после очистки сегмента. Вызывается под блокировкой сегмента на запись
+func (shard *shard[K, V]) viewReset() {
+	if shard.view == nil {
+		return
+	}
+
+	entries := make(map[K]*viewEntry[V])
+
+	shard.view.entries.Store(&entries)
+	shard.view.amended.Store(false)
+	shard.view.dirty.Store(false)
+}
+
+/*
+ * Функция построения и публикации копии словаря сегмента. Ячейки ключей прежней копии переиспользуются,
+ * поэтому запись, изменившая ячейку через новую копию, видна и читателям, загрузившим прежнюю
+ */
+func (shard *shard[K, V]) publishView() {
+	shard.mutex.RLock()
+
+	defer shard.mutex.RUnlock()
+
+	var previous map[K]*viewEntry[V]
+
+	if current := shard.view.entries.Load(); current != nil {
+		previous = *current
+	}
+
+	entries := make(map[K]*viewEntry[V], len(shard.data))
+
+	for key, item := range shard.data {
+		entry, ok := previous[key]
+
+		if !ok {
+			entry = new(viewEntry[V])
+		}
+
+		entry.item.Store(&viewItem[V]{value: item.value, expireAt: item.expireAt})
+
+		entries[key] = entry
+	}
+
+	// Признак сбрасывается после замены копии, поэтому читатель, не заставший признак, уже загрузит новую копию
+	shard.view.entries.Store(&entries)
+	shard.view.amended.Store(false)
+	shard.view.dirty.Store(false)
+}
+
+/*
+ * Функция чтения значения из опубликованной копии сегмента без блокировки. Ключ, записанный после
+ * публикации копии, читается из сегмента под блокировкой. Просроченные значения не возвращаются
+ */
+func (shard *shard[K, V]) viewGet(key K) (V, time.Time, bool) {
+	var zero V
+
+	// Признак загружается до копии, иначе между ними фоновая горутина могла бы заменить копию и сбросить его
+	amended := shard.view.amended.Load()
+
+	var item *viewItem[V]
+
+	if entry, ok := (*shard.view.entries.Load())[key]; ok {
+		item = entry.item.Load()
+	}
+
+	if item == nil {
+		if amended {
+			return shard.peekWithExpiration(key)
+		}
+
+		return zero, time.Time{}, false
+	}
+
+	now := shard.clock.Now()
+
+	if now.After(item.expireAt) {
+		return zero, time.Time{}, false
+	}
+
+	if shard.prune != nil {
+		if value, ok := shard.prune(item.value, now); ok {
+			return value, item.expireAt, true
+		}
+	}
+
+	return item.value, item.expireAt, true
+}
+
+// Функция периодической публикации копий сегментов, набор ключей которых изменился
+func (cache *Cache[K, V]) publisher(ticker Ticker) {
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			for _, shard := range cache.shards {
+				if shard.view.dirty.Load() {
+					shard.publishView()
+				}
+			}
+		case <-cache.stop:
+			return
+		}
+	}
+}
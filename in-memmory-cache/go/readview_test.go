@@ -0,0 +1,91 @@
+package cache_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachetest"
+)
+
+// Функция проверки значения, которое `Get` возвращает сразу после записи
+func expectValue(t *testing.T, values *cache.Cache[string, int], key string, expected int, present bool) {
+	t.Helper()
+
+	if value, ok := values.Get(key); ok != present || (present && value != expected) {
+		t.Fatalf("expected %q = %d, %v, got %d, %v", key, expected, present, value, ok)
+	}
+}
+
+func TestReadOptimizedWritesAreVisibleImmediately(t *testing.T) {
+	clock := cachetest.NewFakeClock(time.Now())
+
+	values := newValues(t, cache.WithReadOptimized(), cache.WithClock(clock), cache.WithTTL(time.Minute))
+
+	// Ключ, записанный после публикации копии, читается из сегмента
+	values.Set("key", 1)
+	expectValue(t, values, "key", 1, true)
+
+	// Публикация копии с ключом: дальнейшие изменения ключа проходят через его ячейку в копии.
+	// Проверки ниже не зависят от того, успела ли фоновая горутина опубликовать копию
+	clock.Advance(20 * time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	values.Set("key", 3)
+	expectValue(t, values, "key", 3, true)
+
+	values.Delete("key")
+	expectValue(t, values, "key", 0, false)
+
+	values.Set("key", 4)
+	expectValue(t, values, "key", 4, true)
+
+	values.Expire("key", time.Second)
+	clock.Advance(2 * time.Second)
+	expectValue(t, values, "key", 0, false)
+
+	values.Set("other", 5)
+	values.Clear()
+	expectValue(t, values, "other", 0, false)
+}
+
+func TestReadOptimizedReadsOwnWritesUnderConcurrency(t *testing.T) {
+	values := newValues(t, cache.WithReadOptimized(), cache.WithShards(4))
+
+	var wg sync.WaitGroup
+
+	// Горутины пишут собственные ключи, пока фоновая горутина публикует копии сегментов
+	for i := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range 2000 {
+				key := strconv.Itoa(i*10 + j%10)
+
+				values.Set(key, j)
+
+				if value, ok := values.Get(key); !ok || value != j {
+					t.Errorf("expected %q = %d after Set, got %d, %v", key, j, value, ok)
+
+					return
+				}
+
+				if j%3 == 0 {
+					values.Delete(key)
+
+					if _, ok := values.Get(key); ok {
+						t.Errorf("expected %q to be missing after Delete", key)
+
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
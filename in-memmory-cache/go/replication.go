@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// Размер очереди изменений реплики. Реплика, отставшая больше чем на очередь, отключается
+	// и при переподключении получает полную копию кэша
+	replicationBuffer = 4096
+
+	// Интервал пустых записей, по которым реплика обнаруживает разрыв соединения с основным экземпляром
+	replicationHeartbeat = time.Second
+
+	// Реплика переподключается, если от основного экземпляра нет записей дольше этого времени
+	replicationTimeout = 3 * replicationHeartbeat
+)
+
+// Тип записи потока репликации. Значения совпадают с операциями журнала изменений (`logOp`)
+type ReplicationOp uint8
+
+const (
+	// Пустая запись, подтверждающая, что соединение с основным экземпляром живо
+	ReplicationHeartbeat ReplicationOp = iota
+
+	// Запись значения с временем жизни и временем истечения
+	ReplicationSet
+
+	// Удаление значения по ключу
+	ReplicationDelete
+
+	// Полная очистка кэша
+	ReplicationClear
+
+	// Новое время жизни и время истечения значения без его перезаписи (`Touch`, `Expire`)
+	ReplicationExpire
+
+	// Новое время жизни значений по умолчанию (`SetDefaultTTL`) в поле `TTL`
+	ReplicationDefaultTTL
+)
+
+/*
+ * Запись потока репликации. Транспорт потока (например gRPC-сервис `cachegrpc`) кодирует записи
+ * сам, поэтому типы значений не требуют регистрации в gob
+ */
+type ReplicationRecord[K comparable, V any] struct {
+	Op       ReplicationOp
+	Key      K
+	Value    V
+	TTL      time.Duration
+	ExpireAt time.Time
+
+	// Значение записано с временем жизни по умолчанию (`SetDefaultTTL` изменяет его время жизни)
+	DefaultTTL bool
+}
+
+// Источник записей потока репликации на стороне реплики, например клиентский поток gRPC
+type ReplicationReceiver[K comparable, V any] interface {
+	Recv() (ReplicationRecord[K, V], error)
+}
+
+/*
+ * Рассылка изменений подключенным репликам. Изменения передаются под блокировкой сегмента,
+ * поэтому порядок изменений одного ключа у реплики совпадает с порядком на основном экземпляре
+ */
+type replicationHub[K comparable, V any] struct {
+	mutex     sync.Mutex
+	followers map[chan logRecord[K, V]]struct{}
+}
+
+// Функция передачи изменения репликам. Очередь отставшей реплики закрывается без ожидания
+func (hub *replicationHub[K, V]) publish(record logRecord[K, V]) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	for records := range hub.followers {
+		select {
+		case records <- record:
+		default:
+			delete(hub.followers, records)
+			close(records)
+		}
+	}
+}
+
+func (hub *replicationHub[K, V]) subscribe() chan logRecord[K, V] {
+	records := make(chan logRecord[K, V], replicationBuffer)
+
+	hub.mutex.Lock()
+	hub.followers[records] = struct{}{}
+	hub.mutex.Unlock()
+
+	return records
+}
+
+func (hub *replicationHub[K, V]) unsubscribe(records chan logRecord[K, V]) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if _, ok := hub.followers[records]; ok {
+		delete(hub.followers, records)
+		close(records)
+	}
+}
+
+// Функция подключения рассылки изменений к сегментам при первом вызове `Replicate`
+func (cache *Cache[K, V]) replicationHub() *replicationHub[K, V] {
+	cache.hubOnce.Do(func() {
+		hub := &replicationHub[K, V]{followers: make(map[chan logRecord[K, V]]struct{})}
+
+		for _, shard := range cache.shards {
+			shard.mutex.Lock()
+			shard.hub = hub
+			shard.mutex.Unlock()
+		}
+
+		cache.hub.Store(hub)
+	})
+
+	return cache.hub.Load()
+}
+
+/*
+ * Функция передачи потока репликации подключившейся реплике через транспорт `send`. Реплика получает
+ * очистку, время жизни по умолчанию и полную копию актуальных значений, а затем асинхронно записи, удаления,
+ * очистки и продления вместе с временем истечения значений и пустые записи раз в секунду. Возвращает ошибку `send`, ошибку `ctx`
+ * или `ErrReplicaLagged`, если реплика отстала от потока больше чем на очередь изменений
+ */
+func (cache *Cache[K, V]) Replicate(ctx context.Context, send func(ReplicationRecord[K, V]) error) error {
+	hub := cache.replicationHub()
+
+	// Подписка оформляется до копирования значений, поэтому изменения во время копирования
+	// не теряются, а применяются репликой после копии
+	records := hub.subscribe()
+	defer hub.unsubscribe(records)
+
+	if err := send(ReplicationRecord[K, V]{Op: ReplicationClear}); err != nil {
+		return err
+	}
+
+	if err := send(ReplicationRecord[K, V]{Op: ReplicationDefaultTTL, TTL: cache.DefaultTTL()}); err != nil {
+		return err
+	}
+
+	for _, entry := range cache.entries() {
+		record := ReplicationRecord[K, V]{Op: ReplicationSet, Key: entry.Key, Value: entry.Value, TTL: entry.TTL, ExpireAt: entry.ExpireAt, DefaultTTL: entry.DefaultTTL}
+
+		if err := send(record); err != nil {
+			return err
+		}
+	}
+
+	heartbeat := time.NewTicker(replicationHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var record ReplicationRecord[K, V]
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-heartbeat.C:
+			record = ReplicationRecord[K, V]{Op: ReplicationHeartbeat}
+		case next, ok := <-records:
+			// Очередь закрыта - реплика отстала и получит полную копию при переподключении
+			if !ok {
+				return ErrReplicaLagged
+			}
+
+			record = ReplicationRecord[K, V]{Op: ReplicationOp(next.Op), Key: next.Key, Value: next.Value, TTL: next.TTL, ExpireAt: next.ExpireAt, DefaultTTL: next.DefaultTTL}
+		}
+
+		if err := send(record); err != nil {
+			return err
+		}
+	}
+}
+
+/*
+ * Функция работы кэша репликой основного экземпляра. `connect` открывает поток `Replicate` основного
+ * экземпляра, который закрывается отменой переданного контекста. Реплика получает полную копию значений
+ * и применяет поток изменений, пока не будет отменен `ctx`, а после разрыва соединения переподключается.
+ * Время истечения значений передается вместе с ними, поэтому без связи с основным экземпляром значения
+ * реплики устаревают не дольше своего времени жизни. Реплика не должна изменяться локально: следующая
+ * полная копия затрет локальные изменения. Возвращает ошибку `ctx`
+ */
+func (cache *Cache[K, V]) Follow(ctx context.Context, connect func(ctx context.Context) (ReplicationReceiver[K, V], error)) error {
+	for {
+		err := cache.follow(ctx, connect)
+
+		if cache.log != nil && ctx.Err() == nil {
+			cache.log.Warn("cache: replication stream interrupted", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(replicationHeartbeat):
+		}
+	}
+}
+
+// Функция получения и применения одного потока репликации до его разрыва
+func (cache *Cache[K, V]) follow(ctx context.Context, connect func(ctx context.Context) (ReplicationReceiver[K, V], error)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	receiver, err := connect(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	// Поток прерывается, если основной экземпляр перестал присылать записи, включая пустые
+	watchdog := time.AfterFunc(replicationTimeout, cancel)
+	defer watchdog.Stop()
+
+	for {
+		record, err := receiver.Recv()
+
+		if err != nil {
+			return err
+		}
+
+		watchdog.Reset(replicationTimeout)
+
+		cache.replay(logRecord[K, V]{Op: logOp(record.Op), Key: record.Key, Value: record.Value, TTL: record.TTL, ExpireAt: record.ExpireAt, DefaultTTL: record.DefaultTTL})
+	}
+}
@@ -0,0 +1,119 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+)
+
+// Транспорт потока репликации в памяти процесса поверх канала
+type channelReceiver struct {
+	records <-chan cache.ReplicationRecord[string, *cache.Profile]
+	errs    <-chan error
+}
+
+func (receiver channelReceiver) Recv() (cache.ReplicationRecord[string, *cache.Profile], error) {
+	select {
+	case record := <-receiver.records:
+		return record, nil
+	case err := <-receiver.errs:
+		return cache.ReplicationRecord[string, *cache.Profile]{}, err
+	}
+}
+
+// Функция подключения реплики к основному экземпляру: каждое подключение открывает новый поток `Replicate`
+func connect(primary *cache.ProfileCache) func(ctx context.Context) (cache.ReplicationReceiver[string, *cache.Profile], error) {
+	return func(ctx context.Context) (cache.ReplicationReceiver[string, *cache.Profile], error) {
+		records := make(chan cache.ReplicationRecord[string, *cache.Profile])
+		errs := make(chan error, 1)
+
+		go func() {
+			errs <- primary.Replicate(ctx, func(record cache.ReplicationRecord[string, *cache.Profile]) error {
+				select {
+				case records <- record:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+		}()
+
+		return channelReceiver{records: records, errs: errs}, nil
+	}
+}
+
+func TestReplicationFollowsPrimary(t *testing.T) {
+	primary := newProfiles(t, cache.WithoutBackgroundGC())
+	replica := newProfiles(t, cache.WithoutBackgroundGC())
+
+	primary.SetWithTTL(&cache.Profile{UUID: "user-1", Orders: []*cache.Order{{UUID: "order-1"}}}, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+
+	go func() { done <- replica.Follow(ctx, connect(primary)) }()
+
+	eventually(t, func() bool { _, ok := replica.Peek("user-1"); return ok })
+
+	if _, _, ok := replica.GetByOrderUUID("order-1"); !ok {
+		t.Fatal("expected replicated profile to be indexed by order")
+	}
+
+	if ttl, _ := replica.TTL("user-1"); ttl <= 0 || ttl > time.Hour {
+		t.Fatalf("expected replicated ttl within an hour, got %s", ttl)
+	}
+
+	primary.Set(&cache.Profile{UUID: "user-2"})
+	primary.AddOrder("user-2", &cache.Order{UUID: "order-2"})
+	primary.Delete("user-1")
+
+	eventually(t, func() bool {
+		_, deleted := replica.Peek("user-1")
+		_, _, added := replica.GetByOrderUUID("order-2")
+
+		return !deleted && added
+	})
+
+	primary.Clear()
+
+	eventually(t, func() bool { return replica.Len() == 0 })
+
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled from Follow, got %v", err)
+	}
+}
+
+func TestReplicateStopsOnSendError(t *testing.T) {
+	primary := newProfiles(t, cache.WithoutBackgroundGC())
+
+	primary.Set(&cache.Profile{UUID: "user-1"})
+
+	errBroken := errors.New("broken stream")
+
+	var ops []cache.ReplicationOp
+
+	err := primary.Replicate(context.Background(), func(record cache.ReplicationRecord[string, *cache.Profile]) error {
+		ops = append(ops, record.Op)
+
+		if record.Op == cache.ReplicationSet {
+			return errBroken
+		}
+
+		return nil
+	})
+
+	if !errors.Is(err, errBroken) {
+		t.Fatalf("expected send error, got %v", err)
+	}
+
+	// Полная копия начинается с очистки реплики и времени жизни по умолчанию
+	if len(ops) != 3 || ops[0] != cache.ReplicationClear || ops[1] != cache.ReplicationDefaultTTL || ops[2] != cache.ReplicationSet {
+		t.Fatalf("expected clear, default ttl and set, got %v", ops)
+	}
+}
@@ -0,0 +1,1085 @@
+package cache
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * Сегмент кэш-хранилища со своим словарем, блокировкой, политикой вытеснения и учетом памяти.
+ * Кэш распределяет ключи по сегментам по хешу ключа (`WithShards`), поэтому запись в разные
+ * сегменты не конкурирует за одну блокировку. По умолчанию кэш состоит из одного сегмента
+ */
+type shard[K comparable, V any] struct {
+	data map[K]*CacheItem[V]
+
+	// Начальная емкость словаря сегмента (`WithInitialCapacity`), сохраняется и при очистке
+	initialCapacity int
+
+	// Ограничения емкости и памяти сегмента. При нескольких сегментах общий
+	// предел кэша делится между ними поровну
+	capacity int
+	maxBytes int64
+
+	// Заполнение сегмента, до которого вытесняются значения при достижении ограничения (`WithLowWatermark`).
+	// Количество значений считается до записи нового значения, поэтому оно меньше `capacity` хотя бы на одно
+	lowCapacity int
+	lowBytes    int64
+
+	// Оценка объема памяти, занимаемого значениями сегмента
+	bytes int64
+	sizer func(K, V) int64
+
+	// Версия последней записи в сегмент. Каждая запись получает следующую версию,
+	// поэтому версия значения не повторяется даже после удаления и повторной записи ключа
+	version uint64
+
+	// Продление времени жизни значения при чтении (`WithSlidingExpiration`)
+	sliding bool
+
+	// Учет чтений значений в метаданных (`WithAccessTracking`), учитывается одно из `trackingRate` чтений (`WithAccessSampling`)
+	tracking     bool
+	trackingRate uint32
+
+	// Политика вытеснения значений из заполненного сегмента. Создается только
+	// при ограничении количества значений или памяти
+	policy    EvictionPolicy[K]
+	newPolicy func() EvictionPolicy[K]
+
+	// Счетчики статистики сегмента за последние минуты (`WithStatsWindow`)
+	window *statsWindow
+
+	// Частоты обращений для окна W-TinyLFU политики сегмента (`WithTinyLFU`)
+	admission *tinyLFU[K]
+
+	// Источник времени кэша (`WithClock`)
+	clock Clock
+
+	// Колесо таймеров истечения значений (`WithExpirationEngine(TimingWheel)`)
+	wheel *timingWheel[K]
+
+	// Время жизни значений по умолчанию кэша (`useDefaultTTL`). Изменяется `SetDefaultTTL`
+	defaultTTL *atomic.Int64
+
+	// Доля времени жизни, в пределах которой случайно смещается время истечения (`WithTTLJitter`)
+	jitter float64
+
+	// Время простоя, после которого значение истекает независимо от TTL (`WithMaxIdle`)
+	maxIdle time.Duration
+
+	// Выборочная проверка истечения значений (`WithExpirationEngine(Sampling)`)
+	sampling bool
+
+	// Удаление просроченных значений при обращении вместо фонового сборщика мусора (`WithoutBackgroundGC`)
+	// и функция уведомления об удаленных значениях, вызываемая после снятия блокировки
+	lazy   bool
+	notify func([]evictedItem[K, V])
+
+	// Время, в течение которого просроченное значение хранится после истечения
+	// и возвращается как устаревшее (`WithStaleWhileRevalidate`)
+	grace time.Duration
+
+	// Обновление значения загрузчиком незадолго до истечения (`WithRefreshAhead`): окно до истечения,
+	// минимальное количество чтений значения и функция запуска фонового обновления
+	refreshWindow time.Duration
+	refreshHits   uint32
+	refresh       func(K)
+
+	// Журнал изменений (`WithPersistenceLog`) и рассылка изменений репликам (`Replicate`)
+	journal *persistenceLog[K, V]
+	hub     *replicationHub[K, V]
+
+	// Рассылка событий изменения значений подписчикам `Watch`
+	events *eventHub[K, V]
+
+	// Копия словаря для чтения без блокировки (`WithReadOptimized`)
+	view *readView[K, V]
+
+	// Вторичный индекс: вторичный ключ значения указывает на основной ключ. Индекс хранится
+	// в каждом сегменте и изменяется под его блокировкой вместе со словарем значений
+	index     map[string]K
+	indexKeys func(V) []string
+
+	// Удаление устаревших частей значения при чтении и сборке мусора (`WithOrderTTL`). Возвращает
+	// копию значения без устаревших частей и `true`, если они были
+	prune func(V, time.Time) (V, bool)
+
+	// Пулы значений `CacheItem`, удаленных сборщиком мусора, и срезов ключей для проходов сборщика
+	// мусора. Пулы общие для всех сегментов кэша
+	items *sync.Pool
+	keys  *sync.Pool
+
+	mutex  sync.RWMutex
+	closed bool
+}
+
+/*
+ * Функция получения значения сегмента. При включенном скользящем времени жизни продлевает TTL значения,
+ * а при наличии политики вытеснения учитывает обращение. Возвращает `false` для отсутствующего или
+ * просроченного значения
+ */
+func (shard *shard[K, V]) get(key K) (V, bool) {
+	value, _, ok := shard.getWithExpiration(key)
+
+	return value, ok
+}
+
+// Функция получения значения сегмента вместе со временем его истечения с учетом продления
+func (shard *shard[K, V]) getWithExpiration(key K) (V, time.Time, bool) {
+	if shard.view != nil {
+		return shard.viewGet(key)
+	}
+
+	if !shard.mutatesOnGet() {
+		value, expireAt, ok := shard.read(key)
+
+		if !ok && shard.lazy {
+			shard.reclaim(key)
+		}
+
+		return value, expireAt, ok
+	}
+
+	// Продление времени жизни, учет обращения политикой вытеснения и подсчет чтений изменяют
+	// состояние хранилища, поэтому блокируем мьютекс на запись в кэш-хранилище
+	value, expireAt, ok, evicted := shard.getExclusive(key)
+
+	if len(evicted) > 0 {
+		shard.notify(evicted)
+	}
+
+	return value, expireAt, ok
+}
+
+/*
+ * Функция удаления просроченного значения по ключу при промахе чтения (`WithoutBackgroundGC`).
+ * Значение могло быть перезаписано после чтения, поэтому истечение проверяется повторно под блокировкой
+ */
+func (shard *shard[K, V]) reclaim(key K) {
+	var evicted []evictedItem[K, V]
+
+	shard.locked(func() {
+		evicted = shard.reclaimLocked(key, nil)
+	})
+
+	if len(evicted) > 0 {
+		shard.notify(evicted)
+	}
+}
+
+/*
+ * Функция получения значения под блокировкой на запись. При промахе без фонового сборщика мусора
+ * (`WithoutBackgroundGC`) просроченное значение удаляется сразу. Возвращает удаленные значения
+ */
+func (shard *shard[K, V]) getExclusive(key K) (V, time.Time, bool, []evictedItem[K, V]) {
+	shard.mutex.Lock()
+
+	defer shard.mutex.Unlock()
+
+	value, expireAt, ok := shard.getLocked(key)
+
+	var evicted []evictedItem[K, V]
+
+	if !ok && shard.lazy {
+		evicted = shard.reclaimLocked(key, evicted)
+	}
+
+	return value, expireAt, ok, evicted
+}
+
+// Функция удаления просроченного значения по ключу. Вызывается под блокировкой на запись
+func (shard *shard[K, V]) reclaimLocked(key K, evicted []evictedItem[K, V]) []evictedItem[K, V] {
+	item, ok := shard.data[key]
+
+	if !ok || !shard.removable(item, shard.clock.Now()) {
+		return evicted
+	}
+
+	evicted = append(evicted, evictedItem[K, V]{key: key, value: item.value, reason: EvictedExpired})
+
+	shard.remove(key, EvictedExpired)
+	shard.release(item)
+
+	return evicted
+}
+
+// Функция проверки, изменяет ли чтение значения состояние сегмента и требует ли блокировки на запись
+func (shard *shard[K, V]) mutatesOnGet() bool {
+	return shard.sliding || shard.maxIdle > 0 || shard.policy != nil || shard.refresh != nil || shard.prune != nil
+}
+
+/*
+ * Функция получения значения сегмента с продлением и учетом обращения. Вызывается под блокировкой на запись
+ */
+func (shard *shard[K, V]) getLocked(key K) (V, time.Time, bool) {
+	// Фильтр допуска учитывает все обращения, включая промахи: именно
+	// они отличают часто запрашиваемые ключи от случайных
+	if shard.admission != nil {
+		shard.admission.record(key)
+	}
+
+	var zero V
+
+	item, ok := shard.data[key]
+
+	if !ok {
+		return zero, time.Time{}, false
+	}
+
+	now := shard.clock.Now()
+
+	if now.After(item.expireAt) {
+		return zero, time.Time{}, false
+	}
+
+	if shard.prune != nil {
+		shard.pruneLocked(key, item, now)
+	}
+
+	// Отсчитываем время жизни значения заново с момента чтения, а срок простоя - в любом случае
+	if shard.sliding {
+		shard.expire(key, item, shard.expireAfter(now, item.ttl))
+	} else if shard.maxIdle > 0 {
+		shard.expire(key, item, item.deadline)
+	}
+
+	if shard.policy != nil && !item.pinned {
+		shard.policy.OnGet(key)
+	}
+
+	if shard.tracking && shard.sampled() {
+		item.access(now, shard.trackingRate)
+	}
+
+	// Часто читаемое значение, срок которого подходит к концу, обновляем в фоне заранее
+	if shard.refresh != nil {
+		item.hits++
+
+		if item.hits >= shard.refreshHits && item.expireAt.Sub(now) <= shard.refreshWindow {
+			shard.refresh(key)
+		}
+	}
+
+	return item.value, item.expireAt, true
+}
+
+// Функция чтения значения сегмента без продления времени жизни и без учета обращения
+func (shard *shard[K, V]) peek(key K) (V, bool) {
+	value, _, ok := shard.peekWithExpiration(key)
+
+	return value, ok
+}
+
+// Функция чтения значения сегмента под блокировкой на чтение с учетом обращения (`WithAccessTracking`)
+func (shard *shard[K, V]) read(key K) (V, time.Time, bool) {
+	shard.mutex.RLock()
+
+	defer shard.mutex.RUnlock()
+
+	return shard.readLocked(key)
+}
+
+/*
+ * Функция чтения значения сегмента без изменения порядка вытеснения и времени жизни, но с учетом
+ * обращения в метаданных значения (`WithAccessTracking`). Вызывается под блокировкой
+ */
+func (shard *shard[K, V]) readLocked(key K) (V, time.Time, bool) {
+	value, expireAt, ok := shard.peekLocked(key)
+
+	if ok && shard.tracking && shard.sampled() {
+		shard.data[key].access(shard.clock.Now(), shard.trackingRate)
+	}
+
+	return value, expireAt, ok
+}
+
+// Функция чтения значения сегмента вместе со временем его истечения
+func (shard *shard[K, V]) peekWithExpiration(key K) (V, time.Time, bool) {
+	// На время действия функции получения значения
+	// блокируем мьютекс на чтение кэш-хранилища
+	shard.mutex.RLock()
+
+	// При завершении функции получения значения снимаем
+	// блокировку с мьютекса на чтения хранилища
+	defer shard.mutex.RUnlock()
+
+	return shard.peekLocked(key)
+}
+
+// Функция чтения значения сегмента без изменения его состояния. Вызывается под блокировкой
+func (shard *shard[K, V]) peekLocked(key K) (V, time.Time, bool) {
+	var zero V
+
+	item, ok := shard.data[key]
+
+	if !ok {
+		return zero, time.Time{}, false
+	}
+
+	now := shard.clock.Now()
+
+	// В случае если значение кэша просрочено возвращаем нулевое значение
+	if now.After(item.expireAt) {
+		return zero, time.Time{}, false
+	}
+
+	// Под блокировкой на чтение значение в сегменте не заменяется, поэтому возвращаем копию без устаревших частей
+	if shard.prune != nil {
+		if value, ok := shard.prune(item.value, now); ok {
+			return value, item.expireAt, true
+		}
+	}
+
+	return item.value, item.expireAt, true
+}
+
+/*
+ * Функция замены значения сегмента копией без устаревших частей (`WithOrderTTL`). Время истечения
+ * и версия значения не изменяются, а изменение не передается в журнал, репликам и подписчикам.
+ * Вызывается под блокировкой на запись
+ */
+func (shard *shard[K, V]) pruneLocked(key K, item *CacheItem[V], now time.Time) {
+	value, ok := shard.prune(item.value, now)
+
+	if !ok {
+		return
+	}
+
+	shard.unindex(key, item.value)
+
+	// Значение только уменьшается, поэтому вытеснять другие значения не требуется
+	if shard.maxBytes > 0 {
+		size := shard.sizer(key, value)
+
+		shard.bytes += size - item.size
+		item.size = size
+	}
+
+	item.value = value
+
+	shard.reindex(key, value)
+	shard.viewStore(key, item)
+}
+
+/*
+ * Время жизни, которое передается в `set` вместо конкретного значения, чтобы значение получило время жизни
+ * по умолчанию на момент записи и изменялось вместе с ним (`SetDefaultTTL`). Явно переданное время жизни,
+ * даже равное времени жизни по умолчанию, с ним не связано
+ */
+const useDefaultTTL time.Duration = math.MinInt64
+
+/*
+ * Функция записи значения в сегмент. Вызывается под блокировкой на запись и возвращает
+ * значения, вытесненные для освобождения места под новое значение
+ */
+func (shard *shard[K, V]) set(key K, value V, ttl time.Duration) []evictedItem[K, V] {
+	now := shard.clock.Now()
+
+	byDefault := ttl == useDefaultTTL
+
+	if byDefault {
+		ttl = time.Duration(shard.defaultTTL.Load())
+	}
+
+	// Без фонового сборщика мусора каждая запись удаляет истекшие значения одной выборки сегмента
+	if shard.lazy {
+		_, _, evicted := shard.sampleLocked(now, nil)
+
+		return append(evicted, shard.setUntil(key, value, ttl, shard.expireAfter(now, ttl), byDefault)...)
+	}
+
+	// Устанавливаем/обновляем время истечения кэша
+	return shard.setUntil(key, value, ttl, shard.expireAfter(now, ttl), byDefault)
+}
+
+// Время жизни для перезаписи значения с сохранением его связи с временем жизни по умолчанию
+func (item *CacheItem[V]) lifetime() time.Duration {
+	if item.defaultTTL {
+		return useDefaultTTL
+	}
+
+	return item.ttl
+}
+
+/*
+ * Функция вычисления времени истечения значения с временем жизни `ttl`, отсчитанным от `now`. При
+ * `WithTTLJitter` время жизни случайно изменяется в пределах `±jitter`, поэтому значения, записанные
+ * одновременно, истекают в разное время. Значения с `NoExpiration` получают время `neverExpires`
+ */
+func (shard *shard[K, V]) expireAfter(now time.Time, ttl time.Duration) time.Time {
+	if ttl == NoExpiration {
+		return neverExpires
+	}
+
+	if shard.jitter > 0 {
+		ttl += time.Duration(float64(ttl) * shard.jitter * (2*rand.Float64() - 1))
+	}
+
+	return now.Add(ttl)
+}
+
+/*
+ * Функция записи значения в сегмент с заданным временем истечения. Используется при восстановлении
+ * значений из снимка, в котором время истечения сохранено. `byDefault` отмечает значение, время жизни
+ * которого взято из времени жизни по умолчанию. Вызывается под блокировкой на запись
+ */
+func (shard *shard[K, V]) setUntil(key K, value V, ttl time.Duration, expireAt time.Time, byDefault bool) []evictedItem[K, V] {
+	var (
+		evicted []evictedItem[K, V]
+		size    int64
+	)
+
+	if shard.admission != nil {
+		shard.admission.record(key)
+	}
+
+	if shard.maxBytes > 0 {
+		size = shard.sizer(key, value)
+
+		// Значение больше всего бюджета памяти не может быть сохранено. Прежнее значение
+		// по ключу при этом удаляем, чтобы не возвращать устаревшие данные
+		if size > shard.maxBytes {
+			if item, ok := shard.data[key]; ok {
+				shard.remove(key, EvictedCapacity)
+
+				evicted = append(evicted, evictedItem[K, V]{key: key, value: item.value, reason: EvictedCapacity})
+			}
+
+			return evicted
+		}
+	}
+
+	// Если значения с таким ключом нет, а хранилище заполнено, освобождаем место под новое значение
+	if _, ok := shard.data[key]; !ok && shard.policy != nil && shard.capacity > 0 && len(shard.data) >= shard.capacity {
+		// Вытесняем значения до нижней границы (`WithLowWatermark`), по умолчанию - одно значение. С фильтром
+		// допуска (`WithTinyLFU`) новое значение всегда попадает в окно, а вытесняется проигравший сравнение частот
+		for len(shard.data) > shard.lowCapacity {
+			if _, ok := peekVictim(shard.policy); !ok {
+				break
+			}
+
+			evicted = append(evicted, shard.evict()...)
+		}
+	}
+
+	// Вытесняем значения, пока новое значение не поместится в бюджет памяти. Размер прежнего
+	// значения по тому же ключу не учитываем, поскольку оно будет заменено. После превышения
+	// бюджета память освобождается до нижней границы (`WithLowWatermark`)
+	if shard.maxBytes > 0 && shard.used(key)+size > shard.maxBytes {
+		for {
+			used := shard.used(key)
+
+			if used+size <= shard.maxBytes && used <= shard.lowBytes {
+				break
+			}
+
+			if _, ok := peekVictim(shard.policy); !ok {
+				break
+			}
+
+			evicted = append(evicted, shard.evict()...)
+		}
+	}
+
+	item, ok := shard.data[key]
+
+	// Закрепление относится к ключу и сохраняется при перезаписи значения
+	pinned := ok && item.pinned
+
+	// Время создания и учет чтений (`Entry`) сохраняются при замене актуального значения
+	now := shard.clock.Now()
+	created := now.UnixNano()
+
+	var (
+		accessed int64
+		accesses uint32
+	)
+
+	if ok {
+		shard.bytes -= item.size
+		shard.unindex(key, item.value)
+
+		// Просроченное, но еще не удаленное сборщиком мусора значение считаем истекшим, а не замененным
+		reason := EvictedReplaced
+
+		if now.After(item.expireAt) {
+			reason = EvictedExpired
+		} else {
+			created, accessed, accesses = item.created, item.accessed.Load(), item.accesses.Load()
+		}
+
+		evicted = append(evicted, evictedItem[K, V]{key: key, value: item.value, reason: reason})
+	}
+
+	shard.version++
+
+	// Перезаписываемое значение изменяется на месте, а для нового ключа значение берется из пула
+	if !ok {
+		item = shard.items.Get().(*CacheItem[V])
+		shard.data[key] = item
+	}
+
+	*item = CacheItem[V]{
+		value:      value,
+		ttl:        ttl,
+		size:       size,
+		expireAt:   shard.idleLimit(expireAt),
+		deadline:   expireAt,
+		version:    shard.version,
+		pinned:     pinned,
+		defaultTTL: byDefault,
+		created:    created,
+		updated:    now.UnixNano(),
+	}
+
+	item.accessed.Store(accessed)
+	item.accesses.Store(accesses)
+
+	shard.bytes += size
+
+	shard.reindex(key, value)
+
+	shard.record(logRecord[K, V]{Op: logSet, Key: key, Value: value, TTL: ttl, ExpireAt: expireAt, DefaultTTL: byDefault})
+	shard.events.publish(EventSet, key, value)
+	shard.viewStore(key, item)
+
+	if shard.wheel != nil {
+		shard.schedule(key, item.expireAt)
+	}
+
+	if shard.policy != nil && !pinned {
+		shard.track(key, item)
+	}
+
+	return evicted
+}
+
+// Функция оценки памяти, занимаемой значениями сегмента, кроме значения по ключу `key`
+func (shard *shard[K, V]) used(key K) int64 {
+	used := shard.bytes
+
+	if item, ok := shard.data[key]; ok {
+		used -= item.size
+	}
+
+	return used
+}
+
+/*
+ * Функция вытеснения значения, выбранного политикой вытеснения. Вызывается под блокировкой на запись
+ */
+func (shard *shard[K, V]) evict() []evictedItem[K, V] {
+	key, ok := shard.victim()
+
+	if !ok {
+		return nil
+	}
+
+	item, ok := shard.data[key]
+
+	// Политика указала на ключ, которого нет в хранилище - снимаем его
+	// с учета, чтобы не выбирать повторно
+	if !ok {
+		shard.policy.OnDelete(key)
+
+		return nil
+	}
+
+	shard.remove(key, EvictedCapacity)
+
+	return []evictedItem[K, V]{{key: key, value: item.value, reason: EvictedCapacity}}
+}
+
+/*
+ * Функция удаления значения из сегмента и политики вытеснения. Причина удаления определяет событие
+ * для подписчиков `Watch`. Вызывается под блокировкой на запись
+ */
+func (shard *shard[K, V]) remove(key K, reason EvictionReason) {
+	if item, ok := shard.data[key]; ok {
+		shard.bytes -= item.size
+		shard.unindex(key, item.value)
+
+		shard.record(logRecord[K, V]{Op: logDelete, Key: key})
+		shard.viewDelete(key)
+
+		switch reason {
+		case EvictedExpired:
+			shard.events.publish(EventExpire, key, item.value)
+		case EvictedCapacity:
+			shard.events.publish(EventEvict, key, item.value)
+		default:
+			shard.events.publish(EventDelete, key, item.value)
+		}
+	}
+
+	delete(shard.data, key)
+
+	if shard.wheel != nil {
+		shard.wheel.cancel(key)
+	}
+
+	if shard.policy != nil {
+		shard.policy.OnDelete(key)
+	}
+}
+
+/*
+ * Функция записи изменения в журнал (`WithPersistenceLog`) и передачи его репликам
+ * (`Replicate`). Вызывается под блокировкой на запись
+ */
+func (shard *shard[K, V]) record(record logRecord[K, V]) {
+	if shard.journal != nil {
+		shard.journal.append(record)
+	}
+
+	if shard.hub != nil {
+		shard.hub.publish(record)
+	}
+}
+
+/*
+ * Функция изменения времени истечения значения с переносом его таймера в колесе. При ограничении
+ * простоя (`WithMaxIdle`) значение истекает не позже `maxIdle` от текущего момента.
+ * Вызывается под блокировкой на запись
+ */
+func (shard *shard[K, V]) expire(key K, item *CacheItem[V], expireAt time.Time) {
+	item.deadline = expireAt
+	item.expireAt = shard.idleLimit(expireAt)
+
+	shard.viewStore(key, item)
+
+	if shard.wheel != nil {
+		shard.schedule(key, item.expireAt)
+	}
+}
+
+/*
+ * Функция изменения времени истечения значения без его перезаписи (`Touch`, `Expire`, `SetDefaultTTL`)
+ * с записью в журнал и передачей репликам. Вызывается под блокировкой на запись
+ */
+func (shard *shard[K, V]) reschedule(key K, item *CacheItem[V], expireAt time.Time) {
+	shard.expire(key, item, expireAt)
+
+	shard.record(logRecord[K, V]{Op: logExpire, Key: key, TTL: item.ttl, ExpireAt: item.deadline})
+}
+
+// Функция ограничения времени истечения сроком простоя (`WithMaxIdle`), отсчитанным от текущего момента
+func (shard *shard[K, V]) idleLimit(expireAt time.Time) time.Time {
+	if shard.maxIdle <= 0 {
+		return expireAt
+	}
+
+	if idleAt := shard.clock.Now().Add(shard.maxIdle); idleAt.Before(expireAt) {
+		return idleAt
+	}
+
+	return expireAt
+}
+
+/*
+ * Функция планирования таймера истечения значения в колесе таймеров. Значения без истечения
+ * (`NoExpiration`) не занимают колесо, и их прежний таймер отменяется
+ */
+func (shard *shard[K, V]) schedule(key K, expireAt time.Time) {
+	if expireAt.Equal(neverExpires) {
+		shard.wheel.cancel(key)
+
+		return
+	}
+
+	shard.wheel.schedule(key, expireAt.Add(shard.grace))
+}
+
+/*
+ * Функция проверки, что значение можно удалить из сегмента. При `WithStaleWhileRevalidate` просроченное
+ * значение хранится еще в течение окна устаревания, чтобы его можно было вернуть до обновления загрузчиком
+ */
+func (shard *shard[K, V]) removable(item *CacheItem[V], now time.Time) bool {
+	return now.After(item.expireAt.Add(shard.grace))
+}
+
+// Функция определения причины промаха по ключу для `GetE`
+func (shard *shard[K, V]) missReason(key K) error {
+	shard.mutex.RLock()
+
+	defer shard.mutex.RUnlock()
+
+	if shard.closed {
+		return ErrClosed
+	}
+
+	if item, ok := shard.data[key]; ok && shard.clock.Now().After(item.expireAt) {
+		return ErrExpired
+	}
+
+	return ErrNotFound
+}
+
+// Функция чтения просроченного значения сегмента, время хранения которого еще не истекло
+func (shard *shard[K, V]) stale(key K) (V, bool) {
+	shard.mutex.RLock()
+
+	defer shard.mutex.RUnlock()
+
+	var zero V
+
+	item, ok := shard.data[key]
+
+	if !ok {
+		return zero, false
+	}
+
+	now := shard.clock.Now()
+
+	if !now.After(item.expireAt) || shard.removable(item, now) {
+		return zero, false
+	}
+
+	return item.value, true
+}
+
+/*
+ * Функция полной очистки сегмента подменой словаря. Вызывается под блокировкой на запись
+ */
+func (shard *shard[K, V]) reset() {
+	shard.events.publishCleared(shard.data)
+
+	// Закрытый сегмент больше не заполняется, поэтому емкость под него не выделяем
+	if shard.closed {
+		shard.data = make(map[K]*CacheItem[V])
+	} else {
+		shard.data = make(map[K]*CacheItem[V], shard.initialCapacity)
+	}
+
+	shard.bytes = 0
+
+	shard.viewReset()
+
+	if shard.policy != nil {
+		shard.policy = shard.newPolicy()
+	}
+
+	if shard.wheel != nil {
+		shard.wheel = newTimingWheel[K](shard.wheel.tick, shard.clock.Now())
+	}
+
+	if shard.index != nil {
+		shard.index = make(map[string]K)
+	}
+}
+
+// Функция добавления вторичных ключей значения в индекс. Вызывается под блокировкой на запись
+func (shard *shard[K, V]) reindex(key K, value V) {
+	if shard.indexKeys == nil {
+		return
+	}
+
+	for _, secondary := range shard.indexKeys(value) {
+		shard.index[secondary] = key
+	}
+}
+
+/*
+ * Функция удаления вторичных ключей значения из индекса. Вторичный ключ мог быть перенесен на другое
+ * значение, поэтому удаляется только если указывает на данный ключ. Вызывается под блокировкой на запись
+ */
+func (shard *shard[K, V]) unindex(key K, value V) {
+	if shard.indexKeys == nil {
+		return
+	}
+
+	for _, secondary := range shard.indexKeys(value) {
+		if shard.index[secondary] == key {
+			delete(shard.index, secondary)
+		}
+	}
+}
+
+/*
+ * Функция вызова `fn` под блокировкой сегмента на запись. Блокировка снимается отложенным вызовом,
+ * поэтому паника в `fn`, например в функции вторичных ключей (`indexKeys`) или пользовательской
+ * политике вытеснения, не оставляет сегмент заблокированным навсегда
+ */
+func (shard *shard[K, V]) locked(fn func()) {
+	shard.mutex.Lock()
+
+	defer shard.mutex.Unlock()
+
+	fn()
+}
+
+/*
+ * Функция записи в сегмент функцией `fn`, вызываемой под блокировкой на запись, как и `locked`.
+ * Возвращает значения, удаленные при записи, или `ErrClosed`, если кэш закрыт
+ */
+func (shard *shard[K, V]) apply(fn func(*shard[K, V]) []evictedItem[K, V]) ([]evictedItem[K, V], error) {
+	shard.mutex.Lock()
+
+	defer shard.mutex.Unlock()
+
+	// Закрытый кэш больше не принимает новые значения
+	if shard.closed {
+		return nil, ErrClosed
+	}
+
+	return fn(shard), nil
+}
+
+// Функция поиска актуального значения сегмента по вторичному ключу
+func (shard *shard[K, V]) lookup(secondary string) (K, V, bool) {
+	shard.mutex.RLock()
+
+	defer shard.mutex.RUnlock()
+
+	var (
+		zeroKey   K
+		zeroValue V
+	)
+
+	key, ok := shard.index[secondary]
+
+	if !ok {
+		return zeroKey, zeroValue, false
+	}
+
+	item, ok := shard.data[key]
+
+	now := shard.clock.Now()
+
+	if !ok || now.After(item.expireAt) {
+		return zeroKey, zeroValue, false
+	}
+
+	if shard.prune != nil {
+		if value, ok := shard.prune(item.value, now); ok {
+			return key, value, true
+		}
+	}
+
+	return key, item.value, true
+}
+
+// Количество значений, удаляемых сборщиком мусора за одну блокировку сегмента на запись
+const sweepBatchSize = 256
+
+/*
+ * Функция очистки сегмента от просроченных значений. Очистка выполняется в два этапа:
+ *
+ * 1. Под блокировкой на чтение собираем ключи просроченных значений. Читатели на этом этапе не блокируются.
+ * 2. Удаляем собранные ключи небольшими пачками по `sweepBatchSize`, блокируя сегмент на запись только на
+ * время удаления одной пачки. Между пачками блокировка снимается, поэтому на больших кэшах читатели и
+ * писатели не простаивают на все время очистки.
+ *
+ * Поскольку между сбором ключей и удалением блокировка снимается, значение могло быть перезаписано
+ * или продлено другим потоком. Поэтому под блокировкой на запись время истечения проверяется повторно
+ * и удаляются только значения, которые по-прежнему просрочены
+ */
+func cleanCacheItems[K comparable, V any](shard *shard[K, V]) []evictedItem[K, V] {
+	// Срез идентификаторов истекших по времени кэш-значений берется из пула, поскольку проходы повторяются
+	keys := shard.keys.Get().(*[]K)
+	defer shard.recycleKeys(keys)
+
+	expiredCacheItemIds := (*keys)[:0]
+
+	now := shard.clock.Now()
+
+	shard.mutex.RLock()
+
+	for id, item := range shard.data {
+		if shard.removable(item, now) {
+			expiredCacheItemIds = append(expiredCacheItemIds, id)
+		}
+	}
+
+	*keys = expiredCacheItemIds
+
+	shard.mutex.RUnlock()
+
+	// Срез удаленных значений для последующего учета в статистике и уведомления функции обратного вызова
+	var evicted []evictedItem[K, V]
+
+	for len(expiredCacheItemIds) > 0 {
+		batch := expiredCacheItemIds[:min(sweepBatchSize, len(expiredCacheItemIds))]
+		expiredCacheItemIds = expiredCacheItemIds[len(batch):]
+
+		shard.locked(func() {
+			now := shard.clock.Now()
+
+			for _, id := range batch {
+				item, ok := shard.data[id]
+
+				// Значение уже удалено, перезаписано или продлено после сбора ключей
+				if !ok || !shard.removable(item, now) {
+					continue
+				}
+
+				evicted = append(evicted, evictedItem[K, V]{key: id, value: item.value, reason: EvictedExpired})
+
+				shard.remove(id, EvictedExpired)
+				shard.release(item)
+			}
+		})
+	}
+
+	return evicted
+}
+
+/*
+ * Функция очистки сегмента с помощью колеса таймеров. Продвигает колесо до текущего момента и удаляет
+ * значения истекших таймеров, не просматривая остальные значения сегмента. Таймер мог сработать раньше
+ * времени истечения из-за округления до тика - такие значения планируются повторно
+ */
+func expireWheelItems[K comparable, V any](shard *shard[K, V]) []evictedItem[K, V] {
+	shard.mutex.Lock()
+
+	defer shard.mutex.Unlock()
+
+	now := shard.clock.Now()
+
+	var evicted []evictedItem[K, V]
+
+	for _, id := range shard.wheel.advance(now) {
+		item, ok := shard.data[id]
+
+		if !ok {
+			continue
+		}
+
+		if !shard.removable(item, now) {
+			shard.schedule(id, item.expireAt)
+
+			continue
+		}
+
+		evicted = append(evicted, evictedItem[K, V]{key: id, value: item.value, reason: EvictedExpired})
+
+		shard.remove(id, EvictedExpired)
+		shard.release(item)
+	}
+
+	return evicted
+}
+
+const (
+	// Количество значений в одной выборке и наибольшее количество выборок сегмента за проход
+	// сборщика мусора (`WithExpirationEngine(Sampling)`)
+	sampleSize   = 20
+	sampleRounds = 16
+
+	// Выборка повторяется, пока истекших значений в ней больше четверти
+	sampleExpiredRatio = 4
+)
+
+/*
+ * Функция выборочной очистки сегмента, как в Redis. Под блокировкой на запись проверяет `sampleSize`
+ * значений, начиная обход словаря со случайной позиции (порядок обхода словарей Go случаен), и удаляет
+ * истекшие. Если истекших в выборке больше четверти, в сегменте вероятно остались и другие, поэтому
+ * выборка повторяется, но не больше `sampleRounds` раз. Стоимость прохода ограничена и не зависит
+ * от размера сегмента, а доля истекших, но не удаленных значений удерживается около четверти
+ */
+func sampleCacheItems[K comparable, V any](shard *shard[K, V]) []evictedItem[K, V] {
+	var evicted []evictedItem[K, V]
+
+	for range sampleRounds {
+		var sampled, expired int
+
+		shard.locked(func() {
+			sampled, expired, evicted = shard.sampleLocked(shard.clock.Now(), evicted)
+		})
+
+		if expired*sampleExpiredRatio <= sampled {
+			break
+		}
+	}
+
+	return evicted
+}
+
+/*
+ * Функция одной выборки: проверяет до `sampleSize` значений со случайной позиции словаря и удаляет
+ * истекшие. Возвращает количество проверенных и удаленных значений. Вызывается под блокировкой на запись
+ */
+func (shard *shard[K, V]) sampleLocked(now time.Time, evicted []evictedItem[K, V]) (int, int, []evictedItem[K, V]) {
+	sampled, expired := 0, 0
+
+	for id, item := range shard.data {
+		if sampled == sampleSize {
+			break
+		}
+
+		sampled++
+
+		if !shard.removable(item, now) {
+			continue
+		}
+
+		expired++
+
+		evicted = append(evicted, evictedItem[K, V]{key: id, value: item.value, reason: EvictedExpired})
+
+		shard.remove(id, EvictedExpired)
+		shard.release(item)
+	}
+
+	return sampled, expired, evicted
+}
+
+/*
+ * Функция удаления устаревших частей значений сегмента (`WithOrderTTL`). Как и очистка просроченных
+ * значений, собирает ключи под блокировкой на чтение и обрабатывает их пачками по `sweepBatchSize`
+ * под блокировкой на запись. Значения, просроченные или удаленные после сбора ключей, пропускаются
+ */
+func pruneCacheItems[K comparable, V any](shard *shard[K, V]) {
+	keys := shard.keys.Get().(*[]K)
+	defer shard.recycleKeys(keys)
+
+	shard.mutex.RLock()
+
+	ids := (*keys)[:0]
+
+	for id := range shard.data {
+		ids = append(ids, id)
+	}
+
+	shard.mutex.RUnlock()
+
+	*keys = ids
+
+	for len(ids) > 0 {
+		batch := ids[:min(sweepBatchSize, len(ids))]
+		ids = ids[len(batch):]
+
+		shard.locked(func() {
+			now := shard.clock.Now()
+
+			for _, id := range batch {
+				if item, ok := shard.data[id]; ok && !now.After(item.expireAt) {
+					shard.pruneLocked(id, item, now)
+				}
+			}
+		})
+	}
+}
+
+/*
+ * Функция возврата значения, удаленного сборщиком мусора, в пул для повторного использования при записи.
+ * Вызывается только для значений, указатели на которые не сохраняются после снятия блокировки
+ */
+func (shard *shard[K, V]) release(item *CacheItem[V]) {
+	// Обнуляем значение, чтобы пул не удерживал его от сборки мусора Go
+	*item = CacheItem[V]{}
+
+	shard.items.Put(item)
+}
+
+// Функция возврата среза ключей прохода сборщика мусора в пул
+func (shard *shard[K, V]) recycleKeys(keys *[]K) {
+	clear(*keys)
+
+	*keys = (*keys)[:0]
+
+	shard.keys.Put(keys)
+}
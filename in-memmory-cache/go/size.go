@@ -0,0 +1,109 @@
+package cache
+
+import "reflect"
+
+// Функция оценки размера значения в байтах для ограничения памяти кэша (`WithMaxBytes`)
+type Sizer[V any] func(value V) int64
+
+/*
+ * Функция оценки объема памяти, занимаемого значением, с помощью рефлексии. Учитывается память
+ * самого значения и все данные, достижимые через указатели, срезы, строки, словари и интерфейсы.
+ * Данные, на которые ссылаются несколько указателей, учитываются один раз. Оценка приблизительная:
+ * служебные накладные расходы словарей и аллокатора не учитываются
+ */
+func EstimateSize(value any) int64 {
+	if value == nil {
+		return 0
+	}
+
+	v := reflect.ValueOf(value)
+
+	return int64(v.Type().Size()) + indirectSize(v, make(map[uintptr]struct{}))
+}
+
+// Функция подсчета памяти, на которую значение ссылается за пределами собственного размера
+func indirectSize(v reflect.Value, seen map[uintptr]struct{}) int64 {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+
+		elem := v.Elem()
+
+		return int64(elem.Type().Size()) + indirectSize(elem, seen)
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+
+		elem := v.Elem()
+
+		return int64(elem.Type().Size()) + indirectSize(elem, seen)
+
+	case reflect.String:
+		return int64(v.Len())
+
+	case reflect.Slice:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+
+		size := int64(v.Cap()) * int64(v.Type().Elem().Size())
+
+		for i := 0; i < v.Len(); i++ {
+			size += indirectSize(v.Index(i), seen)
+		}
+
+		return size
+
+	case reflect.Array:
+		var size int64
+
+		for i := 0; i < v.Len(); i++ {
+			size += indirectSize(v.Index(i), seen)
+		}
+
+		return size
+
+	case reflect.Map:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+
+		entrySize := int64(v.Type().Key().Size() + v.Type().Elem().Size())
+		size := int64(v.Len()) * entrySize
+
+		iter := v.MapRange()
+
+		for iter.Next() {
+			size += indirectSize(iter.Key(), seen) + indirectSize(iter.Value(), seen)
+		}
+
+		return size
+
+	case reflect.Struct:
+		var size int64
+
+		for i := 0; i < v.NumField(); i++ {
+			size += indirectSize(v.Field(i), seen)
+		}
+
+		return size
+
+	default:
+		return 0
+	}
+}
+
+// Функция проверки, учтены ли уже данные по адресу. Отмечает адрес как учтенный
+func visited(pointer uintptr, seen map[uintptr]struct{}) bool {
+	if _, ok := seen[pointer]; ok {
+		return true
+	}
+
+	seen[pointer] = struct{}{}
+
+	return false
+}
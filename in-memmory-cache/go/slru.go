@@ -0,0 +1,117 @@
+package cache
+
+import "container/list"
+
+// Доля емкости политики SLRU, отводимая защищенному сегменту
+const slruProtectedRatio = 0.8
+
+// Положение ключа в сегментах политики SLRU
+type slruEntry struct {
+	element   *list.Element
+	protected bool
+}
+
+/*
+ * Политика сегментированного LRU (SLRU). Новые значения попадают в испытательный сегмент и переходят
+ * в защищенный только при повторном обращении. Кандидаты на вытеснение выбираются из испытательного
+ * сегмента, поэтому массовая загрузка новых значений не вытесняет давно используемые. При переполнении
+ * защищенного сегмента давно не использованное значение возвращается в начало испытательного.
+ *
+ * Как и ARC, политика считает емкостью наибольшее количество отслеживаемых значений
+ */
+type slruPolicy[K comparable] struct {
+	probation *list.List
+	protected *list.List
+	entries   map[K]*slruEntry
+	capacity  int
+}
+
+func newSLRUPolicy[K comparable]() *slruPolicy[K] {
+	return &slruPolicy[K]{
+		probation: list.New(),
+		protected: list.New(),
+		entries:   make(map[K]*slruEntry),
+	}
+}
+
+func (policy *slruPolicy[K]) OnGet(key K) {
+	if entry, ok := policy.entries[key]; ok {
+		policy.promote(key, entry)
+	}
+}
+
+func (policy *slruPolicy[K]) OnSet(key K) {
+	if entry, ok := policy.entries[key]; ok {
+		policy.promote(key, entry)
+
+		return
+	}
+
+	policy.entries[key] = &slruEntry{element: policy.probation.PushFront(key)}
+	policy.capacity = max(policy.capacity, len(policy.entries))
+}
+
+func (policy *slruPolicy[K]) OnDelete(key K) {
+	entry, ok := policy.entries[key]
+
+	if !ok {
+		return
+	}
+
+	if entry.protected {
+		policy.protected.Remove(entry.element)
+	} else {
+		policy.probation.Remove(entry.element)
+	}
+
+	delete(policy.entries, key)
+}
+
+func (policy *slruPolicy[K]) Victim() (K, bool) {
+	element := policy.probation.Back()
+
+	if element == nil {
+		element = policy.protected.Back()
+	}
+
+	if element == nil {
+		var zero K
+
+		return zero, false
+	}
+
+	return element.Value.(K), true
+}
+
+func (policy *slruPolicy[K]) victims(n int) []K {
+	var keys []K
+
+	for _, segment := range []*list.List{policy.probation, policy.protected} {
+		for element := segment.Back(); element != nil && len(keys) < n; element = element.Prev() {
+			keys = append(keys, element.Value.(K))
+		}
+	}
+
+	return keys
+}
+
+// Функция учета повторного обращения: ключ переносится в начало защищенного сегмента
+func (policy *slruPolicy[K]) promote(key K, entry *slruEntry) {
+	if entry.protected {
+		policy.protected.MoveToFront(entry.element)
+
+		return
+	}
+
+	policy.probation.Remove(entry.element)
+
+	entry.element = policy.protected.PushFront(key)
+	entry.protected = true
+
+	// Переполнение защищенного сегмента возвращает давно не использованный ключ на испытание
+	for policy.protected.Len() > max(int(float64(policy.capacity)*slruProtectedRatio), 1) {
+		demoted := policy.protected.Remove(policy.protected.Back()).(K)
+
+		policy.entries[demoted] = &slruEntry{element: policy.probation.PushFront(demoted)}
+	}
+}
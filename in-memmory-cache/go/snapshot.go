@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Версия формата снимка кэш-хранилища. Увеличивается при несовместимом изменении формата
+const snapshotVersion = 1
+
+/*
+ * Заголовок снимка кэш-хранилища. Снимок записывается потоком gob: заголовок, а за ним `Count` значений
+ */
+type snapshotHeader struct {
+	Version int
+	Count   int
+}
+
+// Значение снимка вместе с временем жизни и временем истечения
+type snapshotEntry[K comparable, V any] struct {
+	Key      K
+	Value    V
+	TTL      time.Duration
+	ExpireAt time.Time
+
+	// Значение записано с временем жизни по умолчанию (`SetDefaultTTL` изменяет его время жизни)
+	DefaultTTL bool
+}
+
+/*
+ * Функция сохранения снимка актуальных значений кэш-хранилища. Для каждого значения сохраняется время
+ * истечения, поэтому после восстановления значение истечет в тот же момент, что и в исходном кэше.
+ *
+ * Снимок кодируется с помощью `encoding/gob`, поэтому ключи и значения должны состоять из экспортируемых
+ * полей, а конкретные типы, хранящиеся в полях-интерфейсах (например `Order.Value`), должны быть
+ * зарегистрированы через `gob.Register`. Сегменты блокируются на чтение только на время копирования значений
+ */
+func (cache *Cache[K, V]) SaveTo(w io.Writer) error {
+	entries := cache.entries()
+
+	encoder := gob.NewEncoder(w)
+
+	if err := encoder.Encode(snapshotHeader{Version: snapshotVersion, Count: len(entries)}); err != nil {
+		return fmt.Errorf("cache: encode snapshot header: %w", err)
+	}
+
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("cache: encode snapshot entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Функция копирования актуальных значений кэша. Сегменты блокируются на чтение по очереди
+func (cache *Cache[K, V]) entries() []snapshotEntry[K, V] {
+	var entries []snapshotEntry[K, V]
+
+	now := cache.clock.Now()
+
+	for _, shard := range cache.shards {
+		shard.mutex.RLock()
+
+		for key, item := range shard.data {
+			if now.After(item.expireAt) {
+				continue
+			}
+
+			entries = append(entries, snapshotEntry[K, V]{
+				Key:        key,
+				Value:      item.value,
+				TTL:        item.ttl,
+				ExpireAt:   item.deadline,
+				DefaultTTL: item.defaultTTL,
+			})
+		}
+
+		shard.mutex.RUnlock()
+	}
+
+	return entries
+}
+
+/*
+ * Функция восстановления значений из снимка, сохраненного `SaveTo`. Значения записываются поверх текущих
+ * с сохраненным временем истечения, а истекшие за время простоя значения пропускаются. Восстановленные
+ * значения не сохраняются в хранилище (`WithStore`). При ошибке чтения снимка уже восстановленные
+ * значения остаются в кэше
+ */
+func (cache *Cache[K, V]) LoadFrom(r io.Reader) error {
+	decoder := gob.NewDecoder(r)
+
+	var header snapshotHeader
+
+	if err := decoder.Decode(&header); err != nil {
+		return fmt.Errorf("cache: decode snapshot header: %w", err)
+	}
+
+	if header.Version != snapshotVersion {
+		return fmt.Errorf("cache: unsupported snapshot version %d, want %d", header.Version, snapshotVersion)
+	}
+
+	for range header.Count {
+		var entry snapshotEntry[K, V]
+
+		if err := decoder.Decode(&entry); err != nil {
+			return fmt.Errorf("cache: decode snapshot entry: %w", err)
+		}
+
+		if cache.clock.Now().After(entry.ExpireAt) {
+			continue
+		}
+
+		if err := cache.restore(entry.Key, entry.Value, entry.TTL, entry.ExpireAt, entry.DefaultTTL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Функция записи восстановленного значения с сохраненным временем истечения
+func (cache *Cache[K, V]) restore(key K, value V, ttl time.Duration, expireAt time.Time, byDefault bool) error {
+	cache.awaitWrites()
+
+	evicted, err := cache.shardFor(key).apply(func(shard *shard[K, V]) []evictedItem[K, V] {
+		return shard.setUntil(key, value, ttl, expireAt, byDefault)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	cache.notifyEvicted(evicted)
+
+	return nil
+}
+
+/*
+ * Функция немедленного сохранения снимка в файл из `WithSnapshot`. Снимок записывается во временный файл
+ * в том же каталоге, который затем переименовывается, поэтому при сбое во время записи прежний снимок
+ * остается целым. Без `WithSnapshot` ничего не делает
+ */
+func (cache *Cache[K, V]) SaveSnapshot() error {
+	if cache.snapshotPath == "" {
+		return nil
+	}
+
+	start := time.Now()
+
+	err := cache.saveSnapshot()
+
+	if cache.log != nil {
+		if err != nil {
+			cache.log.Warn("cache: snapshot save failed", "path", cache.snapshotPath, "error", err)
+		} else {
+			cache.log.Debug("cache: snapshot saved", "path", cache.snapshotPath, "duration", time.Since(start))
+		}
+	}
+
+	return err
+}
+
+// Функция сохранения снимка во временный файл с последующим переименованием
+func (cache *Cache[K, V]) saveSnapshot() error {
+	dir, name := filepath.Split(cache.snapshotPath)
+
+	file, err := os.CreateTemp(dir, name+".tmp-*")
+
+	if err != nil {
+		return fmt.Errorf("cache: create snapshot: %w", err)
+	}
+
+	// При любой ошибке удаляем временный файл, после переименования удаление ничего не делает
+	defer os.Remove(file.Name())
+
+	writer := bufio.NewWriter(file)
+
+	if err := cache.SaveTo(writer); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	if err := writer.Flush(); err != nil {
+		file.Close()
+
+		return fmt.Errorf("cache: write snapshot: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+
+		return fmt.Errorf("cache: sync snapshot: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("cache: close snapshot: %w", err)
+	}
+
+	if err := os.Rename(file.Name(), cache.snapshotPath); err != nil {
+		return fmt.Errorf("cache: rename snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Функция восстановления значений из файла снимка. Отсутствие файла не является ошибкой
+func (cache *Cache[K, V]) restoreSnapshot(path string) error {
+	file, err := os.Open(path)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("cache: open snapshot: %w", err)
+	}
+
+	defer file.Close()
+
+	return cache.LoadFrom(bufio.NewReader(file))
+}
+
+// Функция периодического сохранения снимков. Ошибка сохранения не прерывает работу, снимок повторяется по интервалу
+func (cache *Cache[K, V]) snapshotter(ticker Ticker) {
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			_ = cache.SaveSnapshot()
+		case <-cache.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Источник статистики кэша. Реализуется любым `Cache[K, V]` и `ProfileCache` (`cacheprom.NewCollector`)
+type StatsProvider interface {
+	Stats() Stats
+}
+
+/*
+ * Снимок статистики кэш-хранилища. Счетчики накапливаются с момента создания кэша (`Stats`)
+ * либо за последний период (`StatsWindow`)
+ */
+type Stats struct {
+	// Количество успешных чтений значений
+	Hits uint64
+
+	// Количество чтений отсутствующих или просроченных значений
+	Misses uint64
+
+	// Количество значений, удаленных сборщиком мусора по истечении времени жизни
+	Expired uint64
+
+	// Количество значений, вытесненных из-за ограничения емкости или памяти
+	Evictions uint64
+
+	// Количество новых значений, вытесненных из окна W-TinyLFU, поскольку фильтр допуска (`WithTinyLFU`)
+	// оценил обращения к ним реже, чем к кандидату основной политики. Входят в `Evictions`
+	Rejected uint64
+
+	// Количество значений, замененных новой записью по тому же ключу
+	Replaced uint64
+
+	// Количество значений, удаленных явно (`Delete`, `Pop`, `DeleteMany`, `DeleteByPrefix`)
+	Deleted uint64
+
+	// Количество значений, удаленных полной очисткой кэша (`Clear`)
+	Cleared uint64
+
+	// Количество сообщений об изменении значений, которые не удалось отправить в шину (`WithInvalidationBus`)
+	DeadLetters uint64
+
+	// Количество значений в хранилище, включая просроченные, но еще не удаленные сборщиком мусора
+	Entries int
+
+	// Количество проходов сборщика мусора и их суммарная длительность
+	Sweeps        uint64
+	SweepDuration time.Duration
+}
+
+/*
+ * Функция вычисления доли успешных чтений. Возвращает ноль, если чтений еще не было
+ */
+func (stats Stats) HitRatio() float64 {
+	total := stats.Hits + stats.Misses
+
+	if total == 0 {
+		return 0
+	}
+
+	return float64(stats.Hits) / float64(total)
+}
+
+// Счетчики статистики. Обновляются атомарно, поэтому не требуют блокировки кэша
+type counters struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	expired   atomic.Uint64
+	evictions atomic.Uint64
+	rejected  atomic.Uint64
+	replaced  atomic.Uint64
+	deleted   atomic.Uint64
+	cleared   atomic.Uint64
+
+	deadLetters atomic.Uint64
+
+	sweeps        atomic.Uint64
+	sweepDuration atomic.Int64
+
+	// Метрики OpenTelemetry, дублирующие счетчики чтений (`WithTelemetry`)
+	telemetry *telemetry
+}
+
+// Функция учета чтения значения
+func (c *counters) recordRead(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+
+	if c.telemetry != nil {
+		c.telemetry.recordRead(hit)
+	}
+}
+
+// Функция учета удаленного значения по причине удаления
+func (c *counters) record(reason EvictionReason) {
+	switch reason {
+	case EvictedExpired:
+		c.expired.Add(1)
+	case EvictedCapacity:
+		c.evictions.Add(1)
+	case EvictedReplaced:
+		c.replaced.Add(1)
+	case EvictedDeleted:
+		c.deleted.Add(1)
+	case EvictedCleared:
+		c.cleared.Add(1)
+	}
+}
+
+// Функция учета прохода сборщика мусора
+func (c *counters) recordSweep(duration time.Duration) {
+	c.sweeps.Add(1)
+	c.sweepDuration.Add(int64(duration))
+}
+
+/*
+ * Функция получения снимка статистики кэш-хранилища
+ */
+func (cache *Cache[K, V]) Stats() Stats {
+	entries := 0
+
+	for _, shard := range cache.shards {
+		shard.mutex.RLock()
+		entries += len(shard.data)
+		shard.mutex.RUnlock()
+	}
+
+	return Stats{
+		Hits:      cache.stats.hits.Load(),
+		Misses:    cache.stats.misses.Load(),
+		Expired:   cache.stats.expired.Load(),
+		Evictions: cache.stats.evictions.Load(),
+		Rejected:  cache.stats.rejected.Load(),
+		Replaced:  cache.stats.replaced.Load(),
+		Deleted:   cache.stats.deleted.Load(),
+		Cleared:   cache.stats.cleared.Load(),
+		Entries:   entries,
+
+		DeadLetters: cache.stats.deadLetters.Load(),
+
+		Sweeps:        cache.stats.sweeps.Load(),
+		SweepDuration: time.Duration(cache.stats.sweepDuration.Load()),
+	}
+}
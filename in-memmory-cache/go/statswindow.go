@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Длительность интервала скользящей статистики и количество хранимых интервалов (один час)
+const (
+	statsInterval  = 10 * time.Second
+	statsIntervals = 360
+)
+
+// Счетчики одного интервала скользящей статистики
+type statsBucket struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	expired   atomic.Uint64
+	evictions atomic.Uint64
+	replaced  atomic.Uint64
+	deleted   atomic.Uint64
+	cleared   atomic.Uint64
+}
+
+/*
+ * Кольцевой буфер счетчиков сегмента по интервалам `statsInterval` для статистики за последние минуты
+ * (`StatsWindow`). Номер текущего интервала увеличивает фоновая горутина, поэтому учет операции
+ * не запрашивает время и стоит одного атомарного сложения в счетчике своего сегмента
+ */
+type statsWindow struct {
+	buckets [statsIntervals]statsBucket
+	current atomic.Int64
+}
+
+// Функция получения счетчиков текущего интервала
+func (window *statsWindow) bucket() *statsBucket {
+	return &window.buckets[window.current.Load()%statsIntervals]
+}
+
+// Функция учета чтения значения в текущем интервале
+func (window *statsWindow) recordRead(hit bool) {
+	if hit {
+		window.bucket().hits.Add(1)
+	} else {
+		window.bucket().misses.Add(1)
+	}
+}
+
+// Функция учета `n` удаленных значений в текущем интервале по причине удаления
+func (window *statsWindow) record(reason EvictionReason, n uint64) {
+	bucket := window.bucket()
+
+	switch reason {
+	case EvictedExpired:
+		bucket.expired.Add(n)
+	case EvictedCapacity:
+		bucket.evictions.Add(n)
+	case EvictedReplaced:
+		bucket.replaced.Add(n)
+	case EvictedDeleted:
+		bucket.deleted.Add(n)
+	case EvictedCleared:
+		bucket.cleared.Add(n)
+	}
+}
+
+/*
+ * Функция перехода к следующему интервалу. Счетчики следующего интервала обнуляются до того, как он
+ * станет текущим, поэтому в него не попадают операции интервала, вышедшего за пределы буфера
+ */
+func (window *statsWindow) rotate() {
+	next := window.current.Load() + 1
+
+	bucket := &window.buckets[next%statsIntervals]
+
+	for _, counter := range []*atomic.Uint64{
+		&bucket.hits, &bucket.misses, &bucket.expired, &bucket.evictions, &bucket.replaced, &bucket.deleted, &bucket.cleared,
+	} {
+		counter.Store(0)
+	}
+
+	window.current.Store(next)
+}
+
+/*
+ * Функция учета чтения значения по ключу. При `WithStatsWindow` чтение учитывается и в счетчиках
+ * за период сегмента ключа, поэтому потоки, читающие разные сегменты, не конкурируют за один счетчик
+ */
+func (cache *Cache[K, V]) recordRead(key K, hit bool) {
+	cache.stats.recordRead(hit)
+
+	if cache.windowed {
+		cache.shardFor(key).window.recordRead(hit)
+	}
+}
+
+// Функция учета удаленного по ключу значения, как и `recordRead`
+func (cache *Cache[K, V]) record(key K, reason EvictionReason) {
+	cache.stats.record(reason)
+
+	if cache.windowed {
+		cache.shardFor(key).window.record(reason, 1)
+	}
+}
+
+// Функция смены интервалов скользящей статистики всех сегментов. Запускается только при `WithStatsWindow`
+func (cache *Cache[K, V]) rotateStats(ticker Ticker) {
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			for _, shard := range cache.shards {
+				shard.window.rotate()
+			}
+		case <-cache.stop:
+			return
+		}
+	}
+}
+
+/*
+ * Функция получения статистики за последний период `window`, например за 5 минут после выкладки:
+ * накопленная доля попаданий скрывает недавнее ухудшение. Счетчики хранятся по интервалам 10 секунд,
+ * поэтому период округляется вверх до целого числа интервалов, включая текущий незавершенный, и
+ * ограничен одним часом. Количество значений (`Entries`) соответствует текущему моменту, а проходы
+ * сборщика мусора и сообщения шины в скользящей статистике не учитываются. Без `WithStatsWindow`
+ * счетчики за период не ведутся и равны нулю
+ */
+func (cache *Cache[K, V]) StatsWindow(window time.Duration) Stats {
+	stats := Stats{Entries: cache.Stats().Entries}
+
+	if !cache.windowed {
+		return stats
+	}
+
+	intervals := min(max(int64((window+statsInterval-1)/statsInterval), 1), statsIntervals-1)
+
+	for _, shard := range cache.shards {
+		current := shard.window.current.Load()
+
+		// Интервал, следующий за текущим, может обнуляться во время чтения, поэтому буфер хранит на один интервал больше
+		for i := current; i > current-intervals && i >= 0; i-- {
+			bucket := &shard.window.buckets[i%statsIntervals]
+
+			stats.Hits += bucket.hits.Load()
+			stats.Misses += bucket.misses.Load()
+			stats.Expired += bucket.expired.Load()
+			stats.Evictions += bucket.evictions.Load()
+			stats.Replaced += bucket.replaced.Load()
+			stats.Deleted += bucket.deleted.Load()
+			stats.Cleared += bucket.cleared.Load()
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,125 @@
+package cache
+
+import "context"
+
+/*
+ * Хранилище - источник данных, в которое кэш синхронно записывает изменения (`WithStore`).
+ * Методы вызываются без удержания блокировки сегментов, но под блокировкой записи ключа,
+ * поэтому не должны изменять кэш по тому же ключу
+ */
+type Store[K comparable, V any] interface {
+	// Сохранение значения по ключу
+	Save(ctx context.Context, key K, value V) error
+
+	// Удаление значения по ключу
+	Delete(ctx context.Context, key K) error
+}
+
+/*
+ * Функция записи значения в кэш с сохранением в хранилище (`WithStore`). Значение сначала сохраняется
+ * в хранилище, и при ошибке сохранения кэш не изменяется, а ошибка возвращается вызывающему коду
+ */
+func (cache *Cache[K, V]) SetContext(ctx context.Context, key K, value V) error {
+	return cache.setWithTTL(ctx, key, value, useDefaultTTL)
+}
+
+/*
+ * Функция удаления значения из кэша и из хранилища (`WithStore`). Значение удаляется из кэша даже при
+ * ошибке хранилища: следующее чтение обратится к источнику данных и не вернет устаревшее значение.
+ * Возвращает `true`, если значение присутствовало в кэше
+ */
+func (cache *Cache[K, V]) DeleteContext(ctx context.Context, key K) (bool, error) {
+	unlock := cache.lockWrite(key)
+
+	defer unlock()
+
+	ok := cache.invalidate(key)
+
+	// Значение могло отсутствовать в данном экземпляре, но присутствовать в остальных
+	cache.broadcast(key)
+
+	if cache.store == nil {
+		return ok, nil
+	}
+
+	return ok, cache.drop(ctx, key)
+}
+
+/*
+ * Функция изменения значения по ключу, через которую проходят все записи кэша: запись в хранилище
+ * (`WithStore`), уведомление об удаленных значениях и рассылка в шину (`WithInvalidationBus`). Функция `fn`
+ * изменяет сегмент под блокировкой на запись и возвращает записанное значение и признак записи. При заданном
+ * хранилище изменение сегмента и сохранение выполняются под блокировкой записи ключа, поэтому одновременные
+ * записи одного ключа оставляют в кэше и в хранилище одно и то же значение.
+ *
+ * Значение, известное заранее (`value != nil`), сохраняется до изменения кэша, и при ошибке сохранения кэш
+ * не изменяется. Значение, вычисленное `fn` под блокировкой сегмента, сохраняется после записи, и при ошибке
+ * сохранения удаляется из кэша, чтобы кэш не возвращал данные, которых нет в хранилище
+ */
+func (cache *Cache[K, V]) writeThrough(ctx context.Context, key K, value *V, fn func(*shard[K, V]) (V, bool, []evictedItem[K, V])) (bool, error) {
+	unlock := cache.lockWrite(key)
+
+	defer unlock()
+
+	if cache.store != nil && value != nil {
+		if err := cache.save(ctx, key, *value); err != nil {
+			return false, err
+		}
+	}
+
+	var (
+		written V
+		ok      bool
+	)
+
+	evicted, err := cache.shardFor(key).apply(func(shard *shard[K, V]) []evictedItem[K, V] {
+		var evicted []evictedItem[K, V]
+
+		written, ok, evicted = fn(shard)
+
+		return evicted
+	})
+
+	if err != nil || !ok {
+		return false, err
+	}
+
+	// Функции обратного вызова вызываются после снятия блокировки,
+	// чтобы они могли обращаться к кэшу без взаимной блокировки
+	cache.notifyEvicted(evicted)
+	cache.broadcast(key)
+
+	if cache.store == nil || value != nil {
+		return true, nil
+	}
+
+	if err := cache.save(ctx, key, written); err != nil {
+		cache.invalidate(key)
+
+		return true, err
+	}
+
+	return true, nil
+}
+
+// Функция сохранения значения в хранилище либо постановки в очередь отложенной записи
+func (cache *Cache[K, V]) save(ctx context.Context, key K, value V) error {
+	if cache.writeBehind != nil {
+		cache.writeBehind.enqueue(key, pendingWrite[V]{value: value})
+
+		return nil
+	}
+
+	return cache.store.Save(ctx, key, value)
+}
+
+// Функция удаления значения из хранилища либо постановки удаления в очередь отложенной записи
+func (cache *Cache[K, V]) drop(ctx context.Context, key K) error {
+	if cache.writeBehind != nil {
+		cache.writeBehind.enqueue(key, pendingWrite[V]{deleted: true})
+
+		return nil
+	}
+
+	return cache.store.Delete(ctx, key)
+}
@@ -0,0 +1,205 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+)
+
+// Хранилище в памяти, которое запоминает сохраненные значения и может возвращать ошибку
+type memoryStore struct {
+	mutex  sync.Mutex
+	values map[string]int
+	saves  int
+	fail   error
+	delay  func() time.Duration
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{values: make(map[string]int)}
+}
+
+func (store *memoryStore) Save(ctx context.Context, key string, value int) error {
+	if store.delay != nil {
+		time.Sleep(store.delay())
+	}
+
+	store.mutex.Lock()
+
+	defer store.mutex.Unlock()
+
+	if store.fail != nil {
+		return store.fail
+	}
+
+	store.values[key] = value
+	store.saves++
+
+	return nil
+}
+
+func (store *memoryStore) Delete(ctx context.Context, key string) error {
+	store.mutex.Lock()
+
+	defer store.mutex.Unlock()
+
+	delete(store.values, key)
+
+	return nil
+}
+
+// Функция установки ошибки, которую возвращают последующие сохранения. `nil` восстанавливает работу хранилища
+func (store *memoryStore) failWith(err error) {
+	store.mutex.Lock()
+
+	defer store.mutex.Unlock()
+
+	store.fail = err
+}
+
+// Функция получения количества успешных сохранений
+func (store *memoryStore) saved() int {
+	store.mutex.Lock()
+
+	defer store.mutex.Unlock()
+
+	return store.saves
+}
+
+func (store *memoryStore) get(key string) (int, bool) {
+	store.mutex.Lock()
+
+	defer store.mutex.Unlock()
+
+	value, ok := store.values[key]
+
+	return value, ok
+}
+
+// Шина, которая запоминает отправленные сообщения
+type recordingBus struct {
+	mutex sync.Mutex
+	keys  []string
+}
+
+func (bus *recordingBus) Publish(ctx context.Context, message cache.Invalidation) error {
+	bus.mutex.Lock()
+
+	defer bus.mutex.Unlock()
+
+	bus.keys = append(bus.keys, message.Key)
+
+	return nil
+}
+
+func (bus *recordingBus) Subscribe(fn func(cache.Invalidation)) (func(), error) {
+	return func() {}, nil
+}
+
+func (bus *recordingBus) published() []string {
+	bus.mutex.Lock()
+
+	defer bus.mutex.Unlock()
+
+	return append([]string(nil), bus.keys...)
+}
+
+func TestEveryWritePathGoesThroughStoreAndBus(t *testing.T) {
+	store := newMemoryStore()
+	bus := new(recordingBus)
+
+	values := newValues(t, cache.WithStore[string, int](store), cache.WithInvalidationBus(bus))
+
+	values.Set("set", 1)
+
+	if _, found := values.GetOrSet("get-or-set", 2); found {
+		t.Fatal("expected GetOrSet to write a missing key")
+	}
+
+	if _, err := values.GetOrCompute("computed", func() (int, error) { return 3, nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if !values.Update("set", func(value int) int { return value + 10 }) {
+		t.Fatal("expected Update to change the present key")
+	}
+
+	for key, expected := range map[string]int{"set": 11, "get-or-set": 2, "computed": 3} {
+		if value, ok := store.get(key); !ok || value != expected {
+			t.Fatalf("expected %q = %d in the store, got %d, %v", key, expected, value, ok)
+		}
+	}
+
+	if keys := bus.published(); len(keys) != 4 {
+		t.Fatalf("expected every write to be broadcast, got %v", keys)
+	}
+
+	// Присутствующее значение `GetOrSet` не перезаписывает, поэтому ни хранилище, ни шина не затрагиваются
+	if value, found := values.GetOrSet("get-or-set", 5); !found || value != 2 {
+		t.Fatalf("expected the stored value, got %d, %v", value, found)
+	}
+
+	if value, _ := store.get("get-or-set"); value != 2 || len(bus.published()) != 4 {
+		t.Fatalf("expected GetOrSet of a present key to change nothing, got %d and %v", value, bus.published())
+	}
+}
+
+func TestGetOrSetDropsValueOnStoreError(t *testing.T) {
+	store := newMemoryStore()
+	store.failWith(errors.New("database is down"))
+
+	values := newValues(t, cache.WithStore[string, int](store))
+
+	values.GetOrSet("key", 1)
+
+	if _, ok := values.Peek("key"); ok {
+		t.Fatal("expected the value to be dropped when the store fails")
+	}
+}
+
+func TestConcurrentWritesKeepStoreAndCacheConsistent(t *testing.T) {
+	store := newMemoryStore()
+
+	// Случайная задержка сохранения меняет порядок, в котором одновременные записи доходят до хранилища
+	store.delay = func() time.Duration {
+		return time.Duration(rand.IntN(200)) * time.Microsecond
+	}
+
+	values := newValues(t, cache.WithStore[string, int](store))
+
+	var wg sync.WaitGroup
+
+	for i := range 16 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range 20 {
+				switch j % 3 {
+				case 0:
+					values.Set("key", i*100+j)
+				case 1:
+					values.Update("key", func(value int) int { return value + 1 })
+				default:
+					values.Delete("key")
+					values.GetOrSet("key", i*100+j)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	cached, cachedOK := values.Peek("key")
+	stored, storedOK := store.get("key")
+
+	if cached != stored || cachedOK != storedOK {
+		t.Fatalf("expected the cache and the store to agree, got %d, %v in the cache and %d, %v in the store", cached, cachedOK, stored, storedOK)
+	}
+}
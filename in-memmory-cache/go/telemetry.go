@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Имя инструментирующей библиотеки, под которым создаются трассировщик и измеритель
+const instrumentationName = "golang-cache"
+
+/*
+ * Минимальное подмножество API трассировки OpenTelemetry, используемое кэшем. Кэш не зависит от модулей
+ * OpenTelemetry: методы повторяют одноименные методы `trace.TracerProvider`, `trace.Tracer` и `trace.Span`,
+ * а провайдеры OpenTelemetry подключаются опцией `cacheotel.WithTelemetry` из модуля `golang-cache/cacheotel`
+ */
+type TracerProvider interface {
+	Tracer(name string) Tracer
+}
+
+type Tracer interface {
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+type Span interface {
+	RecordError(err error)
+	End()
+}
+
+/*
+ * Минимальное подмножество API метрик OpenTelemetry, используемое кэшем. Методы повторяют
+ * `metric.MeterProvider`, `metric.Meter`, `metric.Int64Counter` и `metric.Float64Histogram`
+ */
+type MeterProvider interface {
+	Meter(name string) Meter
+}
+
+type Meter interface {
+	Int64Counter(name, description string) (Int64Counter, error)
+	Float64Histogram(name, description, unit string) (Float64Histogram, error)
+}
+
+type Int64Counter interface {
+	Add(ctx context.Context, incr int64)
+}
+
+type Float64Histogram interface {
+	Record(ctx context.Context, value float64)
+}
+
+// Инструменты телеметрии кэша. Любой из них может отсутствовать, если провайдер не передан
+type telemetry struct {
+	tracer Tracer
+
+	hits         Int64Counter
+	misses       Int64Counter
+	loadDuration Float64Histogram
+}
+
+// Функция создания инструментов телеметрии из провайдеров, переданных в `WithTelemetry`
+func newTelemetry(tp TracerProvider, mp MeterProvider) (*telemetry, error) {
+	t := &telemetry{}
+
+	if tp != nil {
+		t.tracer = tp.Tracer(instrumentationName)
+	}
+
+	if mp == nil {
+		return t, nil
+	}
+
+	meter := mp.Meter(instrumentationName)
+
+	var err error
+
+	if t.hits, err = meter.Int64Counter("cache.hits", "Number of cache hits."); err != nil {
+		return nil, err
+	}
+
+	if t.misses, err = meter.Int64Counter("cache.misses", "Number of cache misses."); err != nil {
+		return nil, err
+	}
+
+	if t.loadDuration, err = meter.Float64Histogram("cache.load.duration", "Duration of loader calls.", "s"); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Функция учета чтения значения в счетчиках попаданий и промахов
+func (t *telemetry) recordRead(hit bool) {
+	switch {
+	case hit && t.hits != nil:
+		t.hits.Add(context.Background(), 1)
+	case !hit && t.misses != nil:
+		t.misses.Add(context.Background(), 1)
+	}
+}
+
+// Функция начала span-а. Без трассировщика возвращает исходный контекст и пустой span
+func (t *telemetry) start(ctx context.Context, name string) (context.Context, Span) {
+	if t.tracer == nil {
+		return ctx, nopSpan{}
+	}
+
+	return t.tracer.Start(ctx, name)
+}
+
+/*
+ * Функция оборачивания загрузчика: каждый вызов выполняется в span-е `cache.load`, ошибка загрузчика
+ * записывается в span, а длительность вызова - в гистограмму `cache.load.duration`
+ */
+func instrumentLoader[K comparable, V any](t *telemetry, loader func(context.Context, K) (V, error)) func(context.Context, K) (V, error) {
+	return func(ctx context.Context, key K) (V, error) {
+		ctx, span := t.start(ctx, "cache.load")
+		defer span.End()
+
+		start := time.Now()
+
+		value, err := loader(ctx, key)
+
+		if t.loadDuration != nil {
+			t.loadDuration.Record(ctx, time.Since(start).Seconds())
+		}
+
+		if err != nil {
+			span.RecordError(err)
+		}
+
+		return value, err
+	}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) RecordError(error) {}
+func (nopSpan) End()              {}
@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * Внешний кэш второго уровня (например Redis или memcached) для `NewTiered`. Реализация отвечает
+ * за кодирование значений и передает время жизни внешнему кэшу
+ */
+type Backend[K comparable, V any] interface {
+	// Получение значения. Отсутствие значения не является ошибкой и возвращается как `false`
+	Get(ctx context.Context, key K) (V, bool, error)
+
+	// Запись значения с временем жизни
+	Set(ctx context.Context, key K, value V, ttl time.Duration) error
+
+	// Удаление значения
+	Delete(ctx context.Context, key K) error
+}
+
+/*
+ * Двухуровневый кэш: локальный кэш процесса (L1) перед общим внешним кэшем (L2). Чтение проходит
+ * L1 → L2 → загрузчик L1 (`WithLoader`), а найденное значение записывается во все уровни выше.
+ * Значения L1 живут меньше значений L2, поэтому экземпляры сервиса быстро получают изменения,
+ * записанные другими экземплярами в L2
+ */
+type Tiered[K comparable, V any] struct {
+	l1  *Cache[K, V]
+	l2  Backend[K, V]
+	ttl time.Duration
+
+	l1Hits   atomic.Uint64
+	l2Hits   atomic.Uint64
+	misses   atomic.Uint64
+	l2Errors atomic.Uint64
+}
+
+/*
+ * Функция-конструктор двухуровневого кэша. `ttl` - время жизни значений в L2, которое должно быть
+ * больше времени жизни значений L1 (`WithTTL` кэша `l1`)
+ */
+func NewTiered[K comparable, V any](l1 *Cache[K, V], l2 Backend[K, V], ttl time.Duration) (*Tiered[K, V], error) {
+	if l1 == nil || l2 == nil {
+		return nil, fmt.Errorf("cache: tiered cache requires both l1 and l2")
+	}
+
+	if ttl <= l1.DefaultTTL() {
+		return nil, fmt.Errorf("cache: l2 ttl %s must be longer than l1 ttl %s", ttl, l1.DefaultTTL())
+	}
+
+	return &Tiered[K, V]{l1: l1, l2: l2, ttl: ttl}, nil
+}
+
+// Статистика чтений двухуровневого кэша по уровням
+type TieredStats struct {
+	// Количество чтений, обслуженных L1
+	L1Hits uint64
+
+	// Количество чтений, обслуженных L2 после промаха L1
+	L2Hits uint64
+
+	// Количество чтений, не найденных ни в одном уровне (загруженных загрузчиком или отсутствующих)
+	Misses uint64
+
+	// Количество ошибок обращения к L2. Ошибка чтения L2 обрабатывается как промах
+	L2Errors uint64
+}
+
+// Функция получения снимка статистики чтений по уровням
+func (tiered *Tiered[K, V]) Stats() TieredStats {
+	return TieredStats{
+		L1Hits:   tiered.l1Hits.Load(),
+		L2Hits:   tiered.l2Hits.Load(),
+		Misses:   tiered.misses.Load(),
+		L2Errors: tiered.l2Errors.Load(),
+	}
+}
+
+// Функция получения значения. Ошибки загрузки и L2 не различаются с отсутствием значения
+func (tiered *Tiered[K, V]) Get(key K) (V, bool) {
+	value, err := tiered.GetContext(context.Background(), key)
+
+	return value, err == nil
+}
+
+/*
+ * Функция получения значения с проходом по уровням. Одновременные промахи L1 по одному ключу
+ * объединяются, поэтому L2 и загрузчик вызываются один раз. Без загрузчика значение, отсутствующее
+ * в обоих уровнях, возвращается как `ErrNotFound`
+ */
+func (tiered *Tiered[K, V]) GetContext(ctx context.Context, key K) (V, error) {
+	l1 := tiered.l1
+
+	value, ok := l1.get(key)
+
+	l1.recordRead(key, ok)
+
+	if ok {
+		tiered.l1Hits.Add(1)
+
+		return l1.copyOut(value), nil
+	}
+
+	value, err := l1.loads.do(key, func() (V, error) {
+		// Пока данный поток ожидал очереди на загрузку, значение могло быть записано в L1
+		if value, ok := l1.peek(key); ok {
+			return value, nil
+		}
+
+		value, ok, err := tiered.l2.Get(ctx, key)
+
+		if err != nil {
+			tiered.l2Errors.Add(1)
+		}
+
+		if err == nil && ok {
+			tiered.l2Hits.Add(1)
+
+			l1.fill(key, value)
+
+			return value, nil
+		}
+
+		tiered.misses.Add(1)
+
+		if l1.loader == nil {
+			return value, ErrNotFound
+		}
+
+		value, err = l1.loader(ctx, key)
+
+		if err != nil {
+			return value, err
+		}
+
+		if err := tiered.l2.Set(ctx, key, value, tiered.ttl); err != nil {
+			tiered.l2Errors.Add(1)
+		}
+
+		l1.fill(key, value)
+
+		return value, nil
+	})
+
+	if err != nil {
+		return value, err
+	}
+
+	return l1.copyOut(value), nil
+}
+
+// Функция записи значения в оба уровня
+func (tiered *Tiered[K, V]) Set(key K, value V) error {
+	return tiered.SetContext(context.Background(), key, value)
+}
+
+/*
+ * Функция записи значения в оба уровня. Значение сначала записывается в L2, и при ошибке записи
+ * L1 не изменяется, а ошибка возвращается вызывающему коду
+ */
+func (tiered *Tiered[K, V]) SetContext(ctx context.Context, key K, value V) error {
+	if err := tiered.l2.Set(ctx, key, value, tiered.ttl); err != nil {
+		tiered.l2Errors.Add(1)
+
+		return err
+	}
+
+	return tiered.l1.SetContext(ctx, key, value)
+}
+
+// Функция удаления значения из обоих уровней
+func (tiered *Tiered[K, V]) Delete(key K) error {
+	return tiered.DeleteContext(context.Background(), key)
+}
+
+/*
+ * Функция удаления значения из обоих уровней. Значение удаляется из L1 даже при ошибке L2,
+ * ошибка L2 возвращается вызывающему коду
+ */
+func (tiered *Tiered[K, V]) DeleteContext(ctx context.Context, key K) error {
+	if _, err := tiered.l1.DeleteContext(ctx, key); err != nil {
+		return err
+	}
+
+	if err := tiered.l2.Delete(ctx, key); err != nil {
+		tiered.l2Errors.Add(1)
+
+		return err
+	}
+
+	return nil
+}
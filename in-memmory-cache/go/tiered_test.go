@@ -0,0 +1,248 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+)
+
+// Внешний кэш в памяти, который считает чтения, запоминает время жизни записей и может возвращать ошибку
+type memoryBackend struct {
+	mutex  sync.Mutex
+	values map[string]int
+	ttls   map[string]time.Duration
+	gets   int
+	fail   error
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{values: make(map[string]int), ttls: make(map[string]time.Duration)}
+}
+
+func (backend *memoryBackend) Get(ctx context.Context, key string) (int, bool, error) {
+	backend.mutex.Lock()
+
+	defer backend.mutex.Unlock()
+
+	backend.gets++
+
+	if backend.fail != nil {
+		return 0, false, backend.fail
+	}
+
+	value, ok := backend.values[key]
+
+	return value, ok, nil
+}
+
+func (backend *memoryBackend) Set(ctx context.Context, key string, value int, ttl time.Duration) error {
+	backend.mutex.Lock()
+
+	defer backend.mutex.Unlock()
+
+	if backend.fail != nil {
+		return backend.fail
+	}
+
+	backend.values[key] = value
+	backend.ttls[key] = ttl
+
+	return nil
+}
+
+func (backend *memoryBackend) Delete(ctx context.Context, key string) error {
+	backend.mutex.Lock()
+
+	defer backend.mutex.Unlock()
+
+	if backend.fail != nil {
+		return backend.fail
+	}
+
+	delete(backend.values, key)
+	delete(backend.ttls, key)
+
+	return nil
+}
+
+func (backend *memoryBackend) failWith(err error) {
+	backend.mutex.Lock()
+
+	defer backend.mutex.Unlock()
+
+	backend.fail = err
+}
+
+func (backend *memoryBackend) get(key string) (int, time.Duration, bool) {
+	backend.mutex.Lock()
+
+	defer backend.mutex.Unlock()
+
+	value, ok := backend.values[key]
+
+	return value, backend.ttls[key], ok
+}
+
+func (backend *memoryBackend) reads() int {
+	backend.mutex.Lock()
+
+	defer backend.mutex.Unlock()
+
+	return backend.gets
+}
+
+// Функция создания двухуровневого кэша с временем жизни L1 в минуту и L2 в час
+func newTiered(t *testing.T, backend *memoryBackend, opts ...cache.Option) (*cache.Tiered[string, int], *cache.Cache[string, int]) {
+	t.Helper()
+
+	l1 := newValues(t, append(opts, cache.WithTTL(time.Minute))...)
+
+	tiered, err := cache.NewTiered[string, int](l1, backend, time.Hour)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tiered, l1
+}
+
+func TestTieredPromotesL2HitToL1(t *testing.T) {
+	backend := newMemoryBackend()
+	backend.values["key"] = 1
+
+	tiered, l1 := newTiered(t, backend)
+
+	if value, ok := tiered.Get("key"); !ok || value != 1 {
+		t.Fatalf("expected the L2 value, got %d, %v", value, ok)
+	}
+
+	if value, ok := l1.Peek("key"); !ok || value != 1 {
+		t.Fatalf("expected the L2 hit to be promoted to L1, got %d, %v", value, ok)
+	}
+
+	// Повторное чтение обслуживается L1 без обращения к L2
+	if value, ok := tiered.Get("key"); !ok || value != 1 {
+		t.Fatalf("expected the L1 value, got %d, %v", value, ok)
+	}
+
+	if reads := backend.reads(); reads != 1 {
+		t.Fatalf("expected L2 to be read once, got %d", reads)
+	}
+
+	if stats := tiered.Stats(); stats != (cache.TieredStats{L1Hits: 1, L2Hits: 1}) {
+		t.Fatalf("expected one hit on each tier, got %+v", stats)
+	}
+}
+
+func TestTieredLoadsMissIntoBothTiers(t *testing.T) {
+	backend := newMemoryBackend()
+
+	loads := 0
+
+	tiered, l1 := newTiered(t, backend, cache.WithLoader(func(ctx context.Context, key string) (int, error) {
+		loads++
+
+		return 7, nil
+	}))
+
+	if value, err := tiered.GetContext(context.Background(), "key"); err != nil || value != 7 {
+		t.Fatalf("expected the loaded value, got %d, %v", value, err)
+	}
+
+	if value, ttl, ok := backend.get("key"); !ok || value != 7 || ttl != time.Hour {
+		t.Fatalf("expected the loaded value in L2 with the L2 ttl, got %d, %s, %v", value, ttl, ok)
+	}
+
+	if value, ok := l1.Peek("key"); !ok || value != 7 {
+		t.Fatalf("expected the loaded value in L1, got %d, %v", value, ok)
+	}
+
+	if stats := tiered.Stats(); loads != 1 || stats != (cache.TieredStats{Misses: 1}) {
+		t.Fatalf("expected one load and one miss, got %d loads and %+v", loads, stats)
+	}
+}
+
+func TestTieredMissWithoutLoader(t *testing.T) {
+	tiered, _ := newTiered(t, newMemoryBackend())
+
+	if _, err := tiered.GetContext(context.Background(), "key"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if stats := tiered.Stats(); stats != (cache.TieredStats{Misses: 1}) {
+		t.Fatalf("expected one miss, got %+v", stats)
+	}
+}
+
+func TestTieredL2ErrorsFallBackToLoader(t *testing.T) {
+	backend := newMemoryBackend()
+	backend.values["key"] = 1
+	backend.failWith(errors.New("redis is down"))
+
+	tiered, l1 := newTiered(t, backend, cache.WithLoader(func(ctx context.Context, key string) (int, error) {
+		return 2, nil
+	}))
+
+	// Ошибка чтения L2 обрабатывается как промах, а ошибка записи загруженного значения в L2 не мешает записи в L1
+	if value, ok := tiered.Get("key"); !ok || value != 2 {
+		t.Fatalf("expected the loaded value, got %d, %v", value, ok)
+	}
+
+	if value, ok := l1.Peek("key"); !ok || value != 2 {
+		t.Fatalf("expected the loaded value in L1, got %d, %v", value, ok)
+	}
+
+	if stats := tiered.Stats(); stats != (cache.TieredStats{Misses: 1, L2Errors: 2}) {
+		t.Fatalf("expected one miss and two L2 errors, got %+v", stats)
+	}
+}
+
+func TestTieredWritesL2First(t *testing.T) {
+	backend := newMemoryBackend()
+
+	tiered, l1 := newTiered(t, backend)
+
+	if err := tiered.Set("key", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, ttl, ok := backend.get("key"); !ok || value != 1 || ttl != time.Hour {
+		t.Fatalf("expected the value in L2 with the L2 ttl, got %d, %s, %v", value, ttl, ok)
+	}
+
+	backend.failWith(errors.New("redis is down"))
+
+	// Ошибка записи в L2 оставляет L1 без изменений
+	if err := tiered.Set("key", 2); err == nil {
+		t.Fatal("expected Set to return the L2 error")
+	}
+
+	if value, _ := l1.Peek("key"); value != 1 {
+		t.Fatalf("expected L1 to keep the previous value, got %d", value)
+	}
+
+	// Удаление убирает значение из L1 даже при ошибке L2
+	if err := tiered.Delete("key"); err == nil {
+		t.Fatal("expected Delete to return the L2 error")
+	}
+
+	if _, ok := l1.Peek("key"); ok {
+		t.Fatal("expected the value to be removed from L1")
+	}
+
+	if failures := tiered.Stats().L2Errors; failures != 2 {
+		t.Fatalf("expected two L2 errors, got %d", failures)
+	}
+}
+
+func TestNewTieredRejectsShortL2TTL(t *testing.T) {
+	l1 := newValues(t, cache.WithTTL(time.Hour))
+
+	if _, err := cache.NewTiered[string, int](l1, newMemoryBackend(), time.Minute); err == nil {
+		t.Fatal("expected an L2 ttl shorter than the L1 ttl to be rejected")
+	}
+}
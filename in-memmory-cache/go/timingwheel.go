@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"time"
+)
+
+// Механизм удаления просроченных значений, выбираемый опцией `WithExpirationEngine`
+type ExpirationEngine int
+
+const (
+	// Периодический просмотр всех значений сборщиком мусора
+	Scan ExpirationEngine = iota
+
+	// Иерархическое колесо таймеров: сборщик мусора обрабатывает только значения, срок которых истек
+	TimingWheel
+
+	// Выборочная проверка случайных значений, как в Redis: стоимость прохода не зависит от размера хранилища
+	Sampling
+)
+
+func (engine ExpirationEngine) String() string {
+	switch engine {
+	case Scan:
+		return "Scan"
+	case TimingWheel:
+		return "TimingWheel"
+	case Sampling:
+		return "Sampling"
+	default:
+		return fmt.Sprintf("ExpirationEngine(%d)", int(engine))
+	}
+}
+
+const (
+	// Количество уровней колеса и слотов на каждом уровне. Колесо из 4 уровней по 64 слота
+	// охватывает 64^4 тиков, значения с более долгим сроком жизни переносятся на верхний уровень
+	wheelLevels   = 4
+	wheelSlotBits = 6
+	wheelSlots    = 1 << wheelSlotBits
+	wheelSlotMask = wheelSlots - 1
+)
+
+/*
+ * Иерархическое колесо таймеров для удаления просроченных значений. Время разбито на тики заданной
+ * длительности, а таймер каждого значения хранится в слоте, соответствующем тику истечения. Нижний уровень
+ * хранит таймеры ближайших 64 тиков, каждый следующий уровень - в 64 раза более грубые интервалы. При
+ * переходе через границу интервала таймеры верхнего слота переносятся на нижние уровни.
+ *
+ * Планирование и отмена таймера выполняются за `O(1)`, а продвижение колеса затрагивает только слоты
+ * истекших тиков, поэтому стоимость очистки не зависит от общего количества значений. Методы
+ * вызываются под блокировкой сегмента на запись
+ */
+type timingWheel[K comparable] struct {
+	tick  time.Duration
+	start time.Time
+
+	// Номер последнего обработанного тика, отсчитываемого от `start`
+	current uint64
+
+	slots  [wheelLevels][wheelSlots]*list.List
+	timers map[K]*wheelTimer[K]
+}
+
+// Таймер истечения значения и его положение в колесе
+type wheelTimer[K comparable] struct {
+	key        K
+	expireTick uint64
+	level      int
+	slot       int
+	element    *list.Element
+}
+
+func newTimingWheel[K comparable](tick time.Duration, start time.Time) *timingWheel[K] {
+	wheel := &timingWheel[K]{
+		tick:   tick,
+		start:  start,
+		timers: make(map[K]*wheelTimer[K]),
+	}
+
+	for level := range wheel.slots {
+		for slot := range wheel.slots[level] {
+			wheel.slots[level][slot] = list.New()
+		}
+	}
+
+	return wheel
+}
+
+// Функция планирования истечения значения. Ранее запланированный таймер ключа отменяется
+func (wheel *timingWheel[K]) schedule(key K, expireAt time.Time) {
+	wheel.cancel(key)
+
+	// Округляем тик истечения вверх, чтобы значение не было удалено раньше срока
+	expireTick := uint64(0)
+
+	if elapsed := expireAt.Sub(wheel.start); elapsed > 0 {
+		expireTick = uint64((elapsed + wheel.tick - 1) / wheel.tick)
+	}
+
+	if expireTick <= wheel.current {
+		expireTick = wheel.current + 1
+	}
+
+	timer := &wheelTimer[K]{key: key, expireTick: expireTick}
+	wheel.timers[key] = timer
+
+	wheel.place(timer)
+}
+
+// Функция отмены таймера ключа
+func (wheel *timingWheel[K]) cancel(key K) {
+	timer, ok := wheel.timers[key]
+
+	if !ok {
+		return
+	}
+
+	wheel.slots[timer.level][timer.slot].Remove(timer.element)
+	delete(wheel.timers, key)
+}
+
+// Функция размещения таймера на самом нижнем уровне, охватывающем его срок
+func (wheel *timingWheel[K]) place(timer *wheelTimer[K]) {
+	delta := timer.expireTick - wheel.current
+	expireTick := timer.expireTick
+
+	level := 0
+
+	for level < wheelLevels-1 && delta >= 1<<(wheelSlotBits*(level+1)) {
+		level++
+	}
+
+	// Срок за пределами колеса - размещаем таймер в самом дальнем слоте верхнего уровня,
+	// при переносе с него таймер будет размещен повторно
+	if delta >= 1<<(wheelSlotBits*wheelLevels) {
+		expireTick = wheel.current + 1<<(wheelSlotBits*wheelLevels) - 1
+	}
+
+	timer.level = level
+	timer.slot = int(expireTick>>(wheelSlotBits*level)) & wheelSlotMask
+	timer.element = wheel.slots[level][timer.slot].PushBack(timer)
+}
+
+/*
+ * Функция продвижения колеса до момента `now`. Возвращает ключи, срок которых истек.
+ * Таймеры возвращенных ключей снимаются с учета
+ */
+func (wheel *timingWheel[K]) advance(now time.Time) []K {
+	target := uint64(now.Sub(wheel.start) / wheel.tick)
+
+	var expired []K
+
+	for wheel.current < target {
+		wheel.current++
+
+		// При переходе через границу интервала уровня переносим таймеры
+		// соответствующего слота этого уровня на нижние уровни
+		for level := 1; level < wheelLevels; level++ {
+			if wheel.current&(1<<(wheelSlotBits*level)-1) != 0 {
+				break
+			}
+
+			slot := int(wheel.current>>(wheelSlotBits*level)) & wheelSlotMask
+			wheel.cascade(wheel.slots[level][slot])
+		}
+
+		timers := wheel.slots[0][wheel.current&wheelSlotMask]
+
+		for element := timers.Front(); element != nil; element = element.Next() {
+			timer := element.Value.(*wheelTimer[K])
+
+			expired = append(expired, timer.key)
+			delete(wheel.timers, timer.key)
+		}
+
+		timers.Init()
+	}
+
+	return expired
+}
+
+// Функция переноса таймеров слота верхнего уровня на нижние уровни
+func (wheel *timingWheel[K]) cascade(timers *list.List) {
+	pending := make([]*wheelTimer[K], 0, timers.Len())
+
+	for element := timers.Front(); element != nil; element = element.Next() {
+		pending = append(pending, element.Value.(*wheelTimer[K]))
+	}
+
+	timers.Init()
+
+	for _, timer := range pending {
+		if timer.expireTick <= wheel.current {
+			timer.expireTick = wheel.current
+		}
+
+		wheel.place(timer)
+	}
+}
@@ -0,0 +1,135 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachetest"
+)
+
+// Функция создания кэша с колесом таймеров и шагом `tick`, время которого управляется тестом
+func newWheel(t *testing.T, tick time.Duration, opts ...cache.Option) (*cache.Cache[string, int], *cachetest.FakeClock) {
+	t.Helper()
+
+	clock := cachetest.NewFakeClock(time.Now())
+
+	values := newValues(t, append(opts, cache.WithClock(clock), cache.WithCleanupInterval(tick), cache.WithExpirationEngine(cache.TimingWheel))...)
+
+	return values, clock
+}
+
+// Функция проверки, что проход сборщика мусора удаляет ровно `expected` значений
+func expectExpired(t *testing.T, values *cache.Cache[string, int], expected int) {
+	t.Helper()
+
+	if removed := values.DeleteExpired(); removed != expected {
+		t.Fatalf("expected %d expired entries, got %d (keys %v)", expected, removed, values.Keys())
+	}
+}
+
+func TestTimingWheelCascadesAcrossLevels(t *testing.T) {
+	values, clock := newWheel(t, time.Second)
+
+	// Сроки попадают на каждый уровень колеса: 64 тика, 64^2, 64^3 и больше
+	ttls := []time.Duration{10 * time.Second, 100 * time.Second, 5000 * time.Second, 300000 * time.Second}
+
+	for i, ttl := range ttls {
+		values.SetWithTTL(string(rune('a'+i)), i, ttl)
+	}
+
+	elapsed := time.Duration(0)
+
+	for i, ttl := range ttls {
+		clock.Advance(ttl - time.Second - elapsed)
+
+		expectExpired(t, values, 0)
+
+		if _, ok := values.Peek(string(rune('a' + i))); !ok {
+			t.Fatalf("expected the entry with ttl %s to live until its expiry", ttl)
+		}
+
+		clock.Advance(2 * time.Second)
+
+		expectExpired(t, values, 1)
+
+		elapsed = ttl + time.Second
+	}
+
+	if values.Len() != 0 || values.Stats().Expired != uint64(len(ttls)) {
+		t.Fatalf("expected every entry to expire, got %d entries and %+v", values.Len(), values.Stats())
+	}
+}
+
+func TestTimingWheelExpiresBeyondTopLevel(t *testing.T) {
+	// Колесо с шагом 1ms охватывает 64^4 тиков - около 4.6 часов
+	values, clock := newWheel(t, time.Millisecond)
+
+	values.SetWithTTL("far", 1, 6*time.Hour)
+
+	clock.Advance(6*time.Hour - time.Second)
+
+	expectExpired(t, values, 0)
+
+	clock.Advance(2 * time.Second)
+
+	expectExpired(t, values, 1)
+}
+
+func TestTimingWheelReschedulesOnTouchAndSet(t *testing.T) {
+	values, clock := newWheel(t, time.Second)
+
+	values.SetWithTTL("touched", 1, 10*time.Second)
+
+	clock.Advance(8 * time.Second)
+
+	if !values.Touch("touched") {
+		t.Fatal("expected Touch to extend the entry")
+	}
+
+	// Прежний таймер срабатывает, но значение продлено до 18 секунды
+	clock.Advance(5 * time.Second)
+
+	expectExpired(t, values, 0)
+
+	clock.Advance(6 * time.Second)
+
+	expectExpired(t, values, 1)
+
+	// Перезапись с меньшим временем жизни и `Expire` переносят таймер с верхнего уровня на более ранний срок
+	values.SetWithTTL("rewritten", 2, 100*time.Second)
+	values.SetWithTTL("rewritten", 2, 5*time.Second)
+
+	values.SetWithTTL("expired", 3, 100*time.Second)
+	values.Expire("expired", 5*time.Second)
+
+	clock.Advance(6 * time.Second)
+
+	expectExpired(t, values, 2)
+}
+
+func TestTimingWheelDeleteCancelsTimer(t *testing.T) {
+	var reasons []cache.EvictionReason
+
+	values, clock := newWheel(t, time.Second, cache.WithOnEvicted(func(key string, value int, reason cache.EvictionReason) {
+		reasons = append(reasons, reason)
+	}))
+
+	// Таймеры обоих значений находятся в одном слоте
+	values.SetWithTTL("deleted", 1, 10*time.Second)
+	values.SetWithTTL("kept", 2, 10*time.Second)
+
+	values.Delete("deleted")
+
+	clock.Advance(11 * time.Second)
+
+	expectExpired(t, values, 1)
+
+	if len(reasons) != 2 || reasons[0] != cache.EvictedDeleted || reasons[1] != cache.EvictedExpired {
+		t.Fatalf("expected one explicit deletion and one expiry, got %v", reasons)
+	}
+
+	if expired := values.Stats().Expired; expired != 1 {
+		t.Fatalf("expected only the kept entry to expire, got %d", expired)
+	}
+}
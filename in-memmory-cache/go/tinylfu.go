@@ -0,0 +1,362 @@
+package cache
+
+import (
+	"container/list"
+	"hash/maphash"
+	"math/bits"
+	"sync/atomic"
+)
+
+/*
+ * Фильтр допуска TinyLFU. Перед вытеснением значения ради нового ключа сравниваем оценки частоты
+ * обращений к новому ключу и к кандидату на вытеснение: новый ключ допускается в основную часть
+ * хранилища только если к нему обращались чаще. Благодаря этому профили, запрошенные единожды (например
+ * при сканировании всех пользователей), не вытесняют действительно "горячие" значения.
+ *
+ * Частоты оцениваются приближенно с помощью count-min sketch, а перед ним стоит привратник
+ * (doorkeeper) - фильтр Блума, который отсекает ключи, встретившиеся впервые, чтобы они не
+ * занимали счетчики. Для учета изменения нагрузки во времени после заданного количества
+ * обращений все счетчики уменьшаются вдвое, а привратник очищается
+ */
+type tinyLFU[K comparable] struct {
+	seed       maphash.Seed
+	sketch     *countMinSketch
+	doorkeeper *bloomFilter
+
+	// Количество учтенных обращений и порог, после которого счетчики уменьшаются вдвое
+	samples    int
+	sampleSize int
+}
+
+func newTinyLFU[K comparable](capacity int) *tinyLFU[K] {
+	// Привратник очищается раз в период из `sampleSize` обращений, поэтому рассчитывается на все ключи
+	// периода. Иначе после заполнения он пропускает в sketch и однократные обращения
+	sampleSize := 10 * capacity
+
+	return &tinyLFU[K]{
+		seed:       maphash.MakeSeed(),
+		sketch:     newCountMinSketch(capacity),
+		doorkeeper: newBloomFilter(sampleSize),
+		sampleSize: sampleSize,
+	}
+}
+
+// Функция учета обращения к ключу
+func (filter *tinyLFU[K]) record(key K) {
+	hash := hashKey(filter.seed, key)
+
+	// Первое обращение к ключу фиксирует только привратник
+	if filter.doorkeeper.add(hash) {
+		filter.sketch.increment(hash)
+	}
+
+	filter.samples++
+
+	if filter.samples >= filter.sampleSize {
+		filter.sketch.halve()
+		filter.doorkeeper.reset()
+		filter.samples = 0
+	}
+}
+
+// Функция оценки частоты обращений к ключу
+func (filter *tinyLFU[K]) estimate(key K) int {
+	hash := hashKey(filter.seed, key)
+	frequency := int(filter.sketch.estimate(hash))
+
+	if filter.doorkeeper.contains(hash) {
+		frequency++
+	}
+
+	return frequency
+}
+
+// Функция принятия решения о допуске нового ключа вместо кандидата на вытеснение
+func (filter *tinyLFU[K]) admit(candidate, victim K) bool {
+	return filter.estimate(candidate) > filter.estimate(victim)
+}
+
+// Доля емкости сегмента, отводимая окну W-TinyLFU
+const windowRatio = 0.01
+
+/*
+ * Политика вытеснения W-TinyLFU. Новые ключи попадают в небольшое окно LRU (1% емкости) перед основной
+ * политикой и проходят фильтр допуска только при вытеснении из окна: ключ из окна сравнивается
+ * с кандидатом основной политики, и вытесняется тот, к которому обращались реже. Окно дает новым значениям
+ * время набрать обращения, поэтому даже при одних записях последние ключи остаются в хранилище, а однократное
+ * сканирование не вытесняет часто используемые значения основной политики. Ключи из окна, не допущенные
+ * фильтром, учитываются в `Stats.Rejected`
+ */
+type windowPolicy[K comparable] struct {
+	filter *tinyLFU[K]
+	main   EvictionPolicy[K]
+
+	// Окно в порядке использования и положение ключей окна. Ключ с пустым элементом относится к основной политике
+	window  *list.List
+	entries map[K]*list.Element
+	size    int
+
+	// Ключ окна, выбранный последним вызовом `Victim` вместо кандидата основной политики. Его удаление
+	// означает, что фильтр не допустил ключ
+	rejected  K
+	rejecting bool
+	rejects   *atomic.Uint64
+}
+
+func newWindowPolicy[K comparable](main EvictionPolicy[K], filter *tinyLFU[K], capacity int, rejects *atomic.Uint64) *windowPolicy[K] {
+	return &windowPolicy[K]{
+		filter:  filter,
+		main:    main,
+		window:  list.New(),
+		entries: make(map[K]*list.Element),
+		size:    max(int(float64(capacity)*windowRatio), 1),
+		rejects: rejects,
+	}
+}
+
+func (policy *windowPolicy[K]) OnGet(key K) {
+	policy.rejecting = false
+
+	if element := policy.entries[key]; element != nil {
+		policy.window.MoveToFront(element)
+
+		return
+	}
+
+	policy.main.OnGet(key)
+}
+
+func (policy *windowPolicy[K]) OnSet(key K) {
+	policy.rejecting = false
+
+	element, ok := policy.entries[key]
+
+	switch {
+	case element != nil:
+		policy.window.MoveToFront(element)
+	case ok:
+		policy.main.OnSet(key)
+	default:
+		policy.entries[key] = policy.window.PushFront(key)
+
+		// Давно не использованный ключ окна переходит в основную политику. Если места для него нет,
+		// `Victim` уже сравнил его с кандидатом основной политики и вытеснил проигравшего
+		for policy.window.Len() > policy.size {
+			graduate := policy.window.Remove(policy.window.Back()).(K)
+
+			policy.entries[graduate] = nil
+			policy.main.OnSet(graduate)
+		}
+	}
+}
+
+func (policy *windowPolicy[K]) OnDelete(key K) {
+	element, ok := policy.entries[key]
+
+	if !ok {
+		return
+	}
+
+	if policy.rejecting && policy.rejected == key {
+		policy.rejects.Add(1)
+	}
+
+	policy.rejecting = false
+
+	delete(policy.entries, key)
+
+	if element != nil {
+		policy.window.Remove(element)
+
+		return
+	}
+
+	policy.main.OnDelete(key)
+}
+
+/*
+ * Пока окно не заполнено, новый ключ не вытесняет ключи окна, и кандидат выбирается основной политикой.
+ * Иначе давно не использованный ключ окна сравнивается фильтром с кандидатом основной политики
+ */
+func (policy *windowPolicy[K]) Victim() (K, bool) {
+	policy.rejecting = false
+
+	victim, ok := peekVictim(policy.main)
+
+	// Основная политика пуста - вытесняется ключ окна
+	if !ok {
+		return policy.oldest()
+	}
+
+	if candidate, ok := policy.candidate(); ok && !policy.filter.admit(candidate, victim) {
+		policy.rejected, policy.rejecting = candidate, true
+
+		return candidate, true
+	}
+
+	return policy.main.Victim()
+}
+
+func (policy *windowPolicy[K]) peekVictim() (K, bool) {
+	victim, ok := peekVictim(policy.main)
+
+	if !ok {
+		return policy.oldest()
+	}
+
+	if candidate, ok := policy.candidate(); ok && !policy.filter.admit(candidate, victim) {
+		return candidate, true
+	}
+
+	return victim, true
+}
+
+// Функция получения ключа, который вытеснит из заполненного окна следующий новый ключ
+func (policy *windowPolicy[K]) candidate() (K, bool) {
+	if policy.window.Len() < policy.size {
+		var zero K
+
+		return zero, false
+	}
+
+	return policy.oldest()
+}
+
+// Функция получения давно не использованного ключа окна
+func (policy *windowPolicy[K]) oldest() (K, bool) {
+	back := policy.window.Back()
+
+	if back == nil {
+		var zero K
+
+		return zero, false
+	}
+
+	return back.Value.(K), true
+}
+
+// Количество строк count-min sketch (независимых хеш-функций)
+const sketchDepth = 4
+
+/*
+ * Вероятностная структура для приближенного подсчета частот (count-min sketch). Каждый ключ
+ * увеличивает по одному счетчику в каждой строке, а оценкой частоты служит минимум этих счетчиков.
+ * Счетчики насыщаются на значении 15, поскольку для сравнения частот большая точность не нужна
+ */
+type countMinSketch struct {
+	rows [sketchDepth][]uint8
+	mask uint64
+}
+
+func newCountMinSketch(capacity int) *countMinSketch {
+	width := nextPowerOfTwo(capacity)
+	sketch := &countMinSketch{mask: uint64(width - 1)}
+
+	for i := range sketch.rows {
+		sketch.rows[i] = make([]uint8, width)
+	}
+
+	return sketch
+}
+
+func (sketch *countMinSketch) increment(hash uint64) {
+	for i := range sketch.rows {
+		index := sketch.index(hash, i)
+
+		if sketch.rows[i][index] < 15 {
+			sketch.rows[i][index]++
+		}
+	}
+}
+
+func (sketch *countMinSketch) estimate(hash uint64) uint8 {
+	minimum := uint8(15)
+
+	for i := range sketch.rows {
+		minimum = min(minimum, sketch.rows[i][sketch.index(hash, i)])
+	}
+
+	return minimum
+}
+
+// Функция старения счетчиков: уменьшаем все счетчики вдвое
+func (sketch *countMinSketch) halve() {
+	for i := range sketch.rows {
+		for j := range sketch.rows[i] {
+			sketch.rows[i][j] >>= 1
+		}
+	}
+}
+
+// Индекс счетчика в строке. Для каждой строки используем свою часть хеша
+func (sketch *countMinSketch) index(hash uint64, row int) uint64 {
+	return bits.RotateLeft64(hash, row*16) * (uint64(row)*2 + 0x9E3779B97F4A7C15) >> 32 & sketch.mask
+}
+
+/*
+ * Фильтр Блума для привратника TinyLFU. Отвечает на вопрос, встречался ли ключ ранее,
+ * с возможными ложноположительными ответами
+ */
+type bloomFilter struct {
+	bits []uint64
+	mask uint64
+}
+
+func newBloomFilter(capacity int) *bloomFilter {
+	// Выделяем по 8 бит на ожидаемый ключ
+	size := nextPowerOfTwo(capacity * 8)
+
+	return &bloomFilter{
+		bits: make([]uint64, (size+63)/64),
+		mask: uint64(size - 1),
+	}
+}
+
+// Функция добавления ключа. Возвращает `true`, если ключ уже присутствовал в фильтре
+func (filter *bloomFilter) add(hash uint64) bool {
+	present := true
+
+	for _, position := range filter.positions(hash) {
+		word, bit := position/64, position%64
+
+		if filter.bits[word]&(1<<bit) == 0 {
+			present = false
+			filter.bits[word] |= 1 << bit
+		}
+	}
+
+	return present
+}
+
+func (filter *bloomFilter) contains(hash uint64) bool {
+	for _, position := range filter.positions(hash) {
+		if filter.bits[position/64]&(1<<(position%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (filter *bloomFilter) reset() {
+	clear(filter.bits)
+}
+
+// Позиции битов ключа, полученные методом двойного хеширования
+func (filter *bloomFilter) positions(hash uint64) [3]uint64 {
+	low, high := hash, hash>>32|hash<<32
+
+	return [3]uint64{
+		low & filter.mask,
+		(low + high) & filter.mask,
+		(low + 2*high) & filter.mask,
+	}
+}
+
+func nextPowerOfTwo(n int) int {
+	if n < 2 {
+		return 2
+	}
+
+	return 1 << bits.Len(uint(n-1))
+}
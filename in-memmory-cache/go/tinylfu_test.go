@@ -0,0 +1,100 @@
+package cache_test
+
+import (
+	"fmt"
+	"testing"
+
+	cache "golang-cache"
+)
+
+func TestTinyLFUAdmitsRecentKeysOnWrites(t *testing.T) {
+	values := newValues(t, cache.WithMaxEntries(3), cache.WithTinyLFU(true))
+
+	for i := range 10 {
+		values.Set(fmt.Sprint(i), i)
+	}
+
+	// Последний записанный ключ находится в окне и не вытесняется при собственной записи
+	if value, ok := values.Get("9"); !ok || value != 9 {
+		t.Fatalf("expected the last written key to be cached, got %d, %v (keys %v)", value, ok, values.Keys())
+	}
+
+	stats := values.Stats()
+
+	if values.Len() != 3 || stats.Evictions != 7 {
+		t.Fatalf("expected 3 entries and 7 evictions, got %d and %+v", values.Len(), stats)
+	}
+
+	// Ключи из окна с той же частотой, что и у кандидата основной политики, не допускаются
+	if stats.Rejected == 0 || stats.Rejected > stats.Evictions {
+		t.Fatalf("expected rejections to be counted among evictions, got %+v", stats)
+	}
+}
+
+func TestTinyLFUResistsScans(t *testing.T) {
+	for _, policy := range []cache.Policy{cache.LRU, cache.ARC, cache.SLRU, cache.TwoQueue} {
+		t.Run(policy.String(), func(t *testing.T) {
+			values := newValues(t, cache.WithMaxEntries(100), cache.WithPolicy(policy), cache.WithTinyLFU(true))
+
+			for range 5 {
+				for i := range 20 {
+					key := fmt.Sprint("hot-", i)
+
+					if _, ok := values.Get(key); !ok {
+						values.Set(key, i)
+					}
+				}
+			}
+
+			// Сканирование обращается к каждому ключу один раз, как ночная задача по всем пользователям,
+			// а обычная нагрузка продолжает читать часто используемые ключи
+			for i := range 1000 {
+				key := fmt.Sprint("scan-", i)
+
+				values.Get(key)
+				values.Set(key, i)
+
+				values.Get(fmt.Sprint("hot-", i%20))
+			}
+
+			// Частоты оцениваются приближенно, и ключ сканирования, счетчики которого совпали со счетчиками
+			// часто используемых ключей, изредка допускается. Поэтому проверяется, что сохранилось большинство
+			survived := 0
+
+			for i := range 20 {
+				if _, ok := values.Peek(fmt.Sprint("hot-", i)); ok {
+					survived++
+				}
+			}
+
+			if survived < 18 {
+				t.Fatalf("expected hot keys to survive the scan, only %d of 20 did", survived)
+			}
+
+			if stats := values.Stats(); values.Len() != 100 || stats.Rejected == 0 {
+				t.Fatalf("expected a full cache with rejected scan keys, got %d and %+v", values.Len(), stats)
+			}
+		})
+	}
+}
+
+func TestWithoutTinyLFUScanEvictsHotKeys(t *testing.T) {
+	values := newValues(t, cache.WithMaxEntries(100))
+
+	for i := range 50 {
+		values.Set(fmt.Sprint("hot-", i), i)
+		values.Get(fmt.Sprint("hot-", i))
+	}
+
+	for i := range 1000 {
+		values.Set(fmt.Sprint("scan-", i), i)
+	}
+
+	if _, ok := values.Peek("hot-0"); ok {
+		t.Fatal("expected the scan to evict hot keys under plain LRU")
+	}
+
+	if rejected := values.Stats().Rejected; rejected != 0 {
+		t.Fatalf("expected no rejections without TinyLFU, got %d", rejected)
+	}
+}
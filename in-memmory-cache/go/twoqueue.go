@@ -0,0 +1,143 @@
+package cache
+
+import "container/list"
+
+// Доли емкости политики 2Q: очередь новых значений A1in и очередь вытесненных из нее ключей A1out
+const (
+	twoQueueInRatio  = 0.25
+	twoQueueOutRatio = 0.5
+)
+
+// Очереди политики 2Q
+const (
+	// Новые значения (A1in), вытесняются в порядке добавления
+	twoQueueIn = iota
+
+	// Ключи, вытесненные из A1in, без значений (A1out)
+	twoQueueOut
+
+	// Значения, записанные повторно после вытеснения из A1in (Am), вытесняются по LRU
+	twoQueueMain
+)
+
+// Положение ключа в очередях политики 2Q
+type twoQueueEntry struct {
+	element *list.Element
+	queue   int
+}
+
+/*
+ * Политика вытеснения 2Q. Новое значение попадает в очередь A1in и при вытеснении из нее оставляет
+ * ключ в очереди A1out. В основную очередь Am значение попадает, только если его записывают снова,
+ * пока ключ помнит A1out, то есть к нему обращаются не один раз за короткое время. Однократное
+ * сканирование проходит через A1in и не затрагивает Am, а обращения к значениям A1in не учитываются.
+ *
+ * Как и ARC, политика считает емкостью наибольшее количество отслеживаемых значений
+ */
+type twoQueuePolicy[K comparable] struct {
+	queues   [3]*list.List
+	entries  map[K]*twoQueueEntry
+	capacity int
+
+	// Кандидат, выбранный последним вызовом `Victim`. Его удаление из A1in оставляет ключ в A1out
+	victim  K
+	pending bool
+}
+
+func newTwoQueuePolicy[K comparable]() *twoQueuePolicy[K] {
+	policy := &twoQueuePolicy[K]{entries: make(map[K]*twoQueueEntry)}
+
+	for i := range policy.queues {
+		policy.queues[i] = list.New()
+	}
+
+	return policy
+}
+
+func (policy *twoQueuePolicy[K]) OnGet(key K) {
+	policy.pending = false
+
+	if entry, ok := policy.entries[key]; ok && entry.queue == twoQueueMain {
+		policy.queues[twoQueueMain].MoveToFront(entry.element)
+	}
+}
+
+func (policy *twoQueuePolicy[K]) OnSet(key K) {
+	policy.pending = false
+
+	entry, ok := policy.entries[key]
+
+	switch {
+	case !ok:
+		policy.entries[key] = &twoQueueEntry{element: policy.queues[twoQueueIn].PushFront(key), queue: twoQueueIn}
+	case entry.queue == twoQueueOut:
+		policy.move(key, entry, twoQueueMain)
+	case entry.queue == twoQueueMain:
+		policy.queues[twoQueueMain].MoveToFront(entry.element)
+	}
+
+	policy.capacity = max(policy.capacity, policy.queues[twoQueueIn].Len()+policy.queues[twoQueueMain].Len())
+}
+
+func (policy *twoQueuePolicy[K]) OnDelete(key K) {
+	entry, ok := policy.entries[key]
+
+	if !ok {
+		return
+	}
+
+	evicted := policy.pending && policy.victim == key
+
+	policy.pending = false
+
+	if evicted && entry.queue == twoQueueIn {
+		policy.move(key, entry, twoQueueOut)
+
+		for out := policy.queues[twoQueueOut]; out.Len() > max(int(float64(policy.capacity)*twoQueueOutRatio), 1); {
+			delete(policy.entries, out.Remove(out.Back()).(K))
+		}
+
+		return
+	}
+
+	policy.queues[entry.queue].Remove(entry.element)
+	delete(policy.entries, key)
+}
+
+// Кандидат запоминается, поэтому его последующее удаление из A1in оставляет ключ в A1out
+func (policy *twoQueuePolicy[K]) Victim() (K, bool) {
+	victim, ok := policy.peekVictim()
+
+	if ok {
+		policy.victim, policy.pending = victim, true
+	}
+
+	return victim, ok
+}
+
+// Кандидат выбирается из A1in, пока она больше своей доли емкости, иначе из Am
+func (policy *twoQueuePolicy[K]) peekVictim() (K, bool) {
+	in, main := policy.queues[twoQueueIn], policy.queues[twoQueueMain]
+
+	element := in.Back()
+
+	if element == nil || (in.Len() <= max(int(float64(policy.capacity)*twoQueueInRatio), 1) && main.Len() > 0) {
+		element = main.Back()
+	}
+
+	if element == nil {
+		var zero K
+
+		return zero, false
+	}
+
+	return element.Value.(K), true
+}
+
+// Функция переноса ключа в начало очереди `queue`
+func (policy *twoQueuePolicy[K]) move(key K, entry *twoQueueEntry, queue int) {
+	policy.queues[entry.queue].Remove(entry.element)
+
+	entry.element = policy.queues[queue].PushFront(key)
+	entry.queue = queue
+}
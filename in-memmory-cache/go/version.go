@@ -0,0 +1,68 @@
+package cache
+
+import "context"
+
+/*
+ * Функция получения значения вместе с версией его записи. Версия передается в `CompareAndSwap`, чтобы
+ * обнаружить изменение значения другим потоком между чтением и записью. Чтение не продлевает время жизни
+ */
+func (cache *Cache[K, V]) GetWithVersion(key K) (V, uint64, bool) {
+	shard := cache.shardFor(key)
+
+	shard.mutex.RLock()
+
+	item, ok := shard.data[key]
+
+	if !ok || shard.clock.Now().After(item.expireAt) {
+		shard.mutex.RUnlock()
+
+		cache.recordRead(key, false)
+
+		var zero V
+
+		return zero, 0, false
+	}
+
+	value, version := item.value, item.version
+
+	shard.mutex.RUnlock()
+
+	cache.recordRead(key, true)
+
+	return cache.copyOut(value), version, true
+}
+
+/*
+ * Функция записи значения, только если его версия не изменилась с момента чтения через `GetWithVersion`
+ * (оптимистичная блокировка). Значение записывается с заново отсчитанным временем жизни и получает новую
+ * версию. Возвращает `ErrNotFound`, если значение отсутствует или просрочено, и `ErrVersionMismatch`,
+ * если значение было перезаписано другим потоком
+ */
+func (cache *Cache[K, V]) CompareAndSwap(key K, expectedVersion uint64, value V) error {
+	cache.awaitWrites()
+
+	stored := cache.copyIn(value)
+
+	var conflict error
+
+	_, err := cache.writeThrough(context.Background(), key, nil, func(shard *shard[K, V]) (V, bool, []evictedItem[K, V]) {
+		item, ok := shard.data[key]
+
+		switch {
+		case !ok || shard.clock.Now().After(item.expireAt):
+			conflict = ErrNotFound
+		case item.version != expectedVersion:
+			conflict = ErrVersionMismatch
+		default:
+			return value, true, shard.set(key, stored, item.lifetime())
+		}
+
+		return value, false, nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return conflict
+}
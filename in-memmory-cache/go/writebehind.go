@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+/*
+ * Очередь отложенной записи в хранилище (`WithWriteBehind`). Хранит последнее изменение каждого ключа,
+ * поэтому частые изменения одного значения записываются в хранилище один раз за сброс
+ */
+type writeBehind[K comparable, V any] struct {
+	mutex   sync.Mutex
+	pending map[K]pendingWrite[V]
+
+	// Количество ключей в очереди, при котором очередь сбрасывается досрочно
+	size  int
+	flush chan struct{}
+
+	// Сбросы очереди выполняются по очереди, чтобы более старое изменение
+	// ключа не было записано в хранилище после более нового
+	flushing sync.Mutex
+}
+
+// Изменение значения, ожидающее записи в хранилище
+type pendingWrite[V any] struct {
+	value   V
+	deleted bool
+}
+
+func newWriteBehind[K comparable, V any](size int) *writeBehind[K, V] {
+	return &writeBehind[K, V]{
+		pending: make(map[K]pendingWrite[V]),
+		size:    size,
+		flush:   make(chan struct{}, 1),
+	}
+}
+
+// Функция постановки изменения в очередь. Заменяет ранее поставленное изменение того же ключа
+func (queue *writeBehind[K, V]) enqueue(key K, write pendingWrite[V]) {
+	queue.mutex.Lock()
+
+	queue.pending[key] = write
+	full := len(queue.pending) >= queue.size
+
+	queue.mutex.Unlock()
+
+	// Очередь заполнена - будим горутину сброса, не дожидаясь интервала
+	if full {
+		select {
+		case queue.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+/*
+ * Функция немедленной записи накопленных изменений в хранилище (`WithWriteBehind`). Изменения, которые
+ * не удалось записать, возвращаются в очередь и будут повторены при следующем сбросе, если ключ не был
+ * изменен повторно. Возвращает объединенную ошибку хранилища. Без отложенной записи ничего не делает
+ */
+func (cache *Cache[K, V]) Flush() error {
+	queue := cache.writeBehind
+
+	if queue == nil {
+		return nil
+	}
+
+	queue.flushing.Lock()
+
+	defer queue.flushing.Unlock()
+
+	// Подменяем очередь пустой, чтобы запись в хранилище не блокировала новые изменения
+	queue.mutex.Lock()
+
+	pending := queue.pending
+	queue.pending = make(map[K]pendingWrite[V])
+
+	queue.mutex.Unlock()
+
+	ctx := context.Background()
+
+	var errs []error
+
+	for key, write := range pending {
+		var err error
+
+		if write.deleted {
+			err = cache.store.Delete(ctx, key)
+		} else {
+			err = cache.store.Save(ctx, key, write.value)
+		}
+
+		if err == nil {
+			continue
+		}
+
+		errs = append(errs, err)
+
+		queue.mutex.Lock()
+
+		if _, ok := queue.pending[key]; !ok {
+			queue.pending[key] = write
+		}
+
+		queue.mutex.Unlock()
+	}
+
+	return errors.Join(errs...)
+}
+
+// Функция фонового сброса очереди отложенной записи по интервалу либо при заполнении очереди
+func (cache *Cache[K, V]) flusher(ticker Ticker) {
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+		case <-cache.writeBehind.flush:
+		case <-cache.stop:
+			return
+		}
+
+		// Ошибки фонового сброса не теряются: изменения остаются в очереди до следующего сброса
+		_ = cache.Flush()
+	}
+}
@@ -0,0 +1,108 @@
+package cache_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	cache "golang-cache"
+	"golang-cache/cachetest"
+)
+
+// Функция создания кэша с отложенной записью в `store`, сбрасываемой раз в секунду либо при `size` ключах
+func newWriteBehindValues(t *testing.T, store *memoryStore, size int) (*cache.Cache[string, int], *cachetest.FakeClock) {
+	t.Helper()
+
+	clock := cachetest.NewFakeClock(time.Now())
+
+	values := newValues(t, cache.WithClock(clock), cache.WithStore[string, int](store), cache.WithWriteBehind(time.Second, size))
+
+	return values, clock
+}
+
+func TestWriteBehindFlushesOnInterval(t *testing.T) {
+	store := newMemoryStore()
+	values, clock := newWriteBehindValues(t, store, 100)
+
+	// Повторные изменения одного ключа объединяются в одно сохранение
+	values.Set("a", 1)
+	values.Set("a", 2)
+	values.Set("b", 3)
+	values.Delete("b")
+
+	if store.saved() != 0 {
+		t.Fatal("expected writes to wait for the flush interval")
+	}
+
+	clock.Advance(time.Second)
+
+	eventually(t, func() bool {
+		value, ok := store.get("a")
+
+		return ok && value == 2
+	})
+
+	if _, ok := store.get("b"); ok || store.saved() != 1 {
+		t.Fatalf("expected one coalesced save and a deleted key, got %d saves", store.saved())
+	}
+}
+
+func TestWriteBehindFlushesWhenQueueIsFull(t *testing.T) {
+	store := newMemoryStore()
+	values, _ := newWriteBehindValues(t, store, 3)
+
+	values.Set("a", 1)
+	values.Set("b", 2)
+	values.Set("c", 3)
+
+	// Часы не сдвигаются, поэтому очередь сбрасывается только из-за заполнения
+	eventually(t, func() bool {
+		return store.saved() == 3
+	})
+}
+
+func TestWriteBehindFlushesOnClose(t *testing.T) {
+	store := newMemoryStore()
+	values, _ := newWriteBehindValues(t, store, 100)
+
+	values.Set("a", 1)
+	values.Close()
+
+	if value, ok := store.get("a"); !ok || value != 1 {
+		t.Fatalf("expected Close to flush pending writes, got %d, %v", value, ok)
+	}
+}
+
+func TestWriteBehindRetriesFailedWrites(t *testing.T) {
+	store := newMemoryStore()
+	values, _ := newWriteBehindValues(t, store, 100)
+
+	failure := errors.New("database is down")
+	store.failWith(failure)
+
+	values.Set("a", 1)
+	values.Set("b", 1)
+
+	if err := values.Flush(); !errors.Is(err, failure) {
+		t.Fatalf("expected the store error from Flush, got %v", err)
+	}
+
+	// Ключ, измененный после неудачного сброса, записывается с новым значением, а не с возвращенным в очередь
+	values.Set("a", 2)
+
+	store.failWith(nil)
+
+	if err := values.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range map[string]int{"a": 2, "b": 1} {
+		if value, ok := store.get(key); !ok || value != expected {
+			t.Fatalf("expected %q = %d after the retry, got %d, %v", key, expected, value, ok)
+		}
+	}
+
+	if err := values.Flush(); err != nil || store.saved() != 2 {
+		t.Fatalf("expected an empty queue after the retry, got %v and %d saves", err, store.saved())
+	}
+}